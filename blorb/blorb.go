@@ -0,0 +1,208 @@
+// Package blorb reads Blorb files (.zblorb, .blb), the IFF container most
+// stories are released in. A Blorb holds the story file itself alongside the
+// pictures and sounds it uses and metadata about it, all found through the
+// resource index. See https://www.eblong.com/zarf/blorb/blorb.html
+package blorb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Usage is what a resource is for, as given in the resource index
+type Usage string
+
+const (
+	Picture    Usage = "Pict"
+	Sound      Usage = "Snd "
+	Data       Usage = "Data"
+	Executable Usage = "Exec"
+)
+
+// Resource is one indexed chunk. Type is the chunk's id, e.g. "ZCOD", "PNG ",
+// "JPEG", "OGGV", "MOD " or "AIFF". Data is the chunk's contents except for
+// AIFF sounds which, being IFF forms themselves, include the FORM header so
+// they can be handed straight to a decoder.
+type Resource struct {
+	Usage  Usage
+	Number uint32
+	Type   string
+	Data   []byte
+}
+
+type resourceKey struct {
+	usage  Usage
+	number uint32
+}
+
+// Blorb is a parsed Blorb file
+type Blorb struct {
+	resources    map[resourceKey]Resource
+	loops        map[uint32]uint32
+	Metadata     []byte // iFiction XML from the IFmd chunk, nil if there isn't one
+	Frontispiece uint32 // Picture number of the cover art from the Fspc chunk
+	HasCover     bool   // Frontispiece is set
+	Release      uint16 // Release number from the RelN chunk
+	HasRelease   bool   // Release is set
+}
+
+type chunk struct {
+	id   string
+	data []byte
+}
+
+// IsBlorb checks for the FORM IFRS header
+func IsBlorb(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "FORM" && string(data[8:12]) == "IFRS"
+}
+
+// Parse reads a Blorb file, every resource in the index must be a chunk
+// within the file
+func Parse(data []byte) (*Blorb, error) {
+	if !IsBlorb(data) {
+		return nil, errors.New("not a Blorb file")
+	}
+	formLength := int(binary.BigEndian.Uint32(data[4:8]))
+	end := min(8+formLength, len(data))
+
+	b := &Blorb{resources: make(map[resourceKey]Resource), loops: make(map[uint32]uint32)}
+	var index []byte
+	for offset := 12; offset < end; {
+		c, next, err := readChunk(data, offset)
+		if err != nil {
+			return nil, err
+		}
+		switch c.id {
+		case "RIdx":
+			index = c.data
+		case "IFmd":
+			b.Metadata = c.data
+		case "Fspc":
+			if len(c.data) >= 4 {
+				b.Frontispiece = binary.BigEndian.Uint32(c.data)
+				b.HasCover = true
+			}
+		case "Loop":
+			for entry := c.data; len(entry) >= 8; entry = entry[8:] {
+				b.loops[binary.BigEndian.Uint32(entry)] = binary.BigEndian.Uint32(entry[4:])
+			}
+		case "RelN":
+			if len(c.data) >= 2 {
+				b.Release = binary.BigEndian.Uint16(c.data)
+				b.HasRelease = true
+			}
+		}
+		offset = next
+	}
+
+	if index == nil {
+		return nil, errors.New("blorb has no resource index")
+	}
+	if len(index) < 4 {
+		return nil, errors.New("blorb resource index is truncated")
+	}
+	count := int(binary.BigEndian.Uint32(index))
+	if len(index) < 4+12*count {
+		return nil, errors.New("blorb resource index is truncated")
+	}
+	for ix := range count {
+		entry := index[4+12*ix:]
+		usage := Usage(entry[0:4])
+		number := binary.BigEndian.Uint32(entry[4:8])
+		start := int(binary.BigEndian.Uint32(entry[8:12]))
+
+		c, _, err := readChunk(data, start)
+		if err != nil {
+			return nil, fmt.Errorf("%s resource %d: %w", usage, number, err)
+		}
+		resource := Resource{Usage: usage, Number: number, Type: c.id, Data: c.data}
+		if c.id == "FORM" && len(c.data) >= 4 {
+			resource.Type = string(c.data[0:4])
+			resource.Data = data[start : start+8+len(c.data)]
+		}
+		b.resources[resourceKey{usage, number}] = resource
+	}
+	return b, nil
+}
+
+// readChunk reads the chunk starting at offset, returning where the next one
+// starts after any padding
+func readChunk(data []byte, offset int) (chunk, int, error) {
+	if offset < 0 || offset+8 > len(data) {
+		return chunk{}, 0, fmt.Errorf("chunk at %d is outside the file", offset)
+	}
+	length := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+	start := offset + 8
+	if length > len(data)-start {
+		return chunk{}, 0, fmt.Errorf("chunk at %d runs past the end of the file", offset)
+	}
+	return chunk{id: string(data[offset : offset+4]), data: data[start : start+length]}, start + length + length%2, nil
+}
+
+// Resource returns the resource with the given usage and number
+func (b *Blorb) Resource(usage Usage, number uint32) (Resource, bool) {
+	resource, ok := b.resources[resourceKey{usage, number}]
+	return resource, ok
+}
+
+// Picture returns picture number n
+func (b *Blorb) Picture(n uint32) (Resource, bool) {
+	return b.Resource(Picture, n)
+}
+
+// Sound returns sound number n
+func (b *Blorb) Sound(n uint32) (Resource, bool) {
+	return b.Resource(Sound, n)
+}
+
+// Loop returns how many times sound n plays by default from the Loop chunk,
+// which only V3 stories have. Zero means it repeats forever.
+func (b *Blorb) Loop(n uint32) (uint32, bool) {
+	loop, ok := b.loops[n]
+	return loop, ok
+}
+
+// Numbers returns the numbers of every resource with the given usage in order
+func (b *Blorb) Numbers(usage Usage) []uint32 {
+	var numbers []uint32
+	for key := range maps.Keys(b.resources) {
+		if key.usage == usage {
+			numbers = append(numbers, key.number)
+		}
+	}
+	slices.Sort(numbers)
+	return numbers
+}
+
+// Story returns a copy of the Z-code executable, which is always resource
+// Exec 0, as the machine writes to the story's memory
+func (b *Blorb) Story() ([]byte, error) {
+	executable, ok := b.Resource(Executable, 0)
+	switch {
+	case !ok:
+		return nil, errors.New("blorb has no executable")
+	case executable.Type != "ZCOD":
+		return nil, fmt.Errorf("blorb executable is %q rather than Z-code", executable.Type)
+	}
+	return slices.Clone(executable.Data), nil
+}
+
+// Unwrap returns the story file from data, which is either a Blorb or a bare
+// story file. The Blorb is nil for bare story files.
+func Unwrap(data []byte) ([]byte, *Blorb, error) {
+	if !IsBlorb(data) {
+		return data, nil, nil
+	}
+	b, err := Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	story, err := b.Story()
+	if err != nil {
+		return nil, nil, err
+	}
+	return story, b, nil
+}
@@ -0,0 +1,117 @@
+package blorb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"slices"
+	"testing"
+)
+
+func appendChunk(data []byte, id string, chunk []byte) []byte {
+	data = append(data, id...)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(chunk)))
+	data = append(data, chunk...)
+	if len(chunk)%2 == 1 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+type testResource struct {
+	usage  Usage
+	number uint32
+	id     string
+	data   []byte
+}
+
+// buildBlorb lays out the resource index first, as Inform's blorbing tools
+// do, followed by the resources and then the other chunks
+func buildBlorb(resources []testResource, others ...[]byte) []byte {
+	indexLength := 4 + 12*len(resources)
+	offset := 12 + 8 + indexLength
+	index := binary.BigEndian.AppendUint32(nil, uint32(len(resources)))
+	var body []byte
+	for _, r := range resources {
+		index = append(index, r.usage...)
+		index = binary.BigEndian.AppendUint32(index, r.number)
+		index = binary.BigEndian.AppendUint32(index, uint32(offset+len(body)))
+		body = appendChunk(body, r.id, r.data)
+	}
+
+	form := appendChunk([]byte("IFRS"), "RIdx", index)
+	form = append(form, body...)
+	for _, other := range others {
+		form = append(form, other...)
+	}
+	return appendChunk(nil, "FORM", form)
+}
+
+func TestUnwrapBlorb(t *testing.T) {
+	story, err := os.ReadFile("../advent.z3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aiff := append([]byte("AIFF"), appendChunk(nil, "COMM", make([]byte, 18))...)
+	data := buildBlorb([]testResource{
+		{Executable, 0, "ZCOD", story},
+		{Picture, 1, "PNG ", []byte{0x89, 'P', 'N', 'G', 1}},
+		{Picture, 3, "JPEG", []byte{0xff, 0xd8}},
+		{Sound, 3, "FORM", aiff},
+	},
+		appendChunk(nil, "IFmd", []byte("<ifindex/>")),
+		appendChunk(nil, "Fspc", []byte{0, 0, 0, 1}),
+		appendChunk(nil, "RelN", []byte{0, 7}),
+		appendChunk(nil, "Loop", []byte{0, 0, 0, 3, 0, 0, 0, 0}),
+	)
+
+	unwrapped, b, err := Unwrap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, story) {
+		t.Errorf("expected the ZCOD chunk to be the story")
+	}
+
+	if numbers := b.Numbers(Picture); !slices.Equal(numbers, []uint32{1, 3}) {
+		t.Errorf("expected pictures 1 and 3, got %v", numbers)
+	}
+	if picture, ok := b.Picture(3); !ok || picture.Type != "JPEG" || !bytes.Equal(picture.Data, []byte{0xff, 0xd8}) {
+		t.Errorf("expected picture 3 to be a JPEG, got %+v", picture)
+	}
+	sound, ok := b.Sound(3)
+	if !ok || sound.Type != "AIFF" || string(sound.Data[0:4]) != "FORM" || len(sound.Data) != 8+len(aiff) {
+		t.Errorf("expected sound 3 to be a whole AIFF form, got %q", sound.Data)
+	}
+	if loop, ok := b.Loop(3); !ok || loop != 0 {
+		t.Errorf("expected sound 3 to loop forever, got %d %t", loop, ok)
+	}
+	if _, ok := b.Sound(1); ok {
+		t.Errorf("expected no sound 1")
+	}
+
+	if string(b.Metadata) != "<ifindex/>" || !b.HasCover || b.Frontispiece != 1 || !b.HasRelease || b.Release != 7 {
+		t.Errorf("unexpected metadata %q, frontispiece %d, release %d", b.Metadata, b.Frontispiece, b.Release)
+	}
+}
+
+func TestUnwrapBareStory(t *testing.T) {
+	story := []byte{3, 0, 0, 0}
+	unwrapped, b, err := Unwrap(story)
+	if err != nil || b != nil || !bytes.Equal(unwrapped, story) {
+		t.Errorf("expected a bare story to be returned as it is, got %v %v %v", unwrapped, b, err)
+	}
+}
+
+func TestBlorbWithoutStory(t *testing.T) {
+	data := buildBlorb([]testResource{{Picture, 1, "PNG ", []byte{1}}})
+	if _, _, err := Unwrap(data); err == nil {
+		t.Errorf("expected a Blorb without an executable to fail")
+	}
+
+	data = buildBlorb([]testResource{{Executable, 0, "ZCOD", []byte{5}}})
+	binary.BigEndian.PutUint32(data[12+8+4+8:], 0x1000) // Point the index past the end
+	if _, _, err := Unwrap(data); err == nil {
+		t.Errorf("expected an index pointing outside the file to fail")
+	}
+}
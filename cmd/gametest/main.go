@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/davetcode/goz/blorb"
 	"github.com/davetcode/goz/zmachine"
 )
 
@@ -59,7 +61,9 @@ func runAllGames(storiesDir, outputDir string) {
 		if strings.HasSuffix(name, ".z1") || strings.HasSuffix(name, ".z2") ||
 			strings.HasSuffix(name, ".z3") || strings.HasSuffix(name, ".z4") ||
 			strings.HasSuffix(name, ".z5") || strings.HasSuffix(name, ".z6") ||
-			strings.HasSuffix(name, ".z7") || strings.HasSuffix(name, ".z8") {
+			strings.HasSuffix(name, ".z7") || strings.HasSuffix(name, ".z8") ||
+			strings.HasSuffix(name, ".zblorb") || strings.HasSuffix(name, ".zlb") ||
+			strings.HasSuffix(name, ".blb") {
 			games = append(games, filepath.Join(storiesDir, name))
 		}
 	}
@@ -173,12 +177,18 @@ func runGameTest(gamePath string) (result TestResult) {
 	}()
 
 	// Load the game file
-	storyBytes, err := os.ReadFile(gamePath)
+	fileBytes, err := os.ReadFile(gamePath)
 	if err != nil {
 		result.Success = false
 		result.ErrorMessage = fmt.Sprintf("Failed to read file: %v", err)
 		return
 	}
+	storyBytes, resources, err := blorb.Unwrap(fileBytes)
+	if err != nil {
+		result.Success = false
+		result.ErrorMessage = fmt.Sprintf("Failed to read Blorb: %v", err)
+		return
+	}
 
 	// Basic validation - check minimum size for header
 	if len(storyBytes) < 64 {
@@ -196,6 +206,7 @@ func runGameTest(gamePath string) (result TestResult) {
 
 	// Load the Z-machine
 	z := zmachine.LoadRom(storyBytes, inputChannel, saveRestoreChannel, outputChannel)
+	z.SetResources(resources)
 
 	// Commands to try - these are common adventure game commands that should
 	// exercise various parts of the interpreter
@@ -396,7 +407,9 @@ func runGameTest(gamePath string) (result TestResult) {
 	// Collect output until we hit input request or timeout
 	var screenOutput []string
 	done := make(chan bool)
-	timeout := time.After(30 * time.Second) // Longer timeout for multiple commands
+	// Longer timeout for multiple commands, cancelling also stops the interpreter whichever way we leave
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
 	go func() {
 		defer func() {
@@ -407,7 +420,7 @@ func runGameTest(gamePath string) (result TestResult) {
 				done <- true
 			}
 		}()
-		z.Run()
+		z.RunContext(ctx) // nolint:errcheck
 		done <- true
 	}()
 
@@ -431,17 +444,15 @@ func runGameTest(gamePath string) (result TestResult) {
 					// We've sent all commands, stop collecting
 					collectOutput = false
 				}
-			case zmachine.StateChangeRequest:
-				if v == zmachine.WaitForCharacter {
-					// Game is waiting for character input - send next command
-					if commandIndex < len(commands) {
-						lastCommand = commands[commandIndex]
-						inputChannel <- zmachine.InputResponse{Text: commands[commandIndex], TerminatingKey: 13}
-						commandIndex++
-					} else {
-						// We've sent all commands, stop collecting
-						collectOutput = false
-					}
+			case zmachine.CharacterRequest:
+				// Game is waiting for character input - send next command
+				if commandIndex < len(commands) {
+					lastCommand = commands[commandIndex]
+					inputChannel <- zmachine.InputResponse{Text: commands[commandIndex], TerminatingKey: 13}
+					commandIndex++
+				} else {
+					// We've sent all commands, stop collecting
+					collectOutput = false
 				}
 			case zmachine.Save:
 				// For testing, always respond with failure (not saving)
@@ -449,6 +460,13 @@ func runGameTest(gamePath string) (result TestResult) {
 			case zmachine.Restore:
 				// For testing, always respond with failure (no save file)
 				saveRestoreChannel <- zmachine.RestoreResponse{Success: false, Result: 0}
+			case zmachine.OpenTranscript:
+				// For testing, no transcript
+				saveRestoreChannel <- zmachine.TranscriptResponse{}
+			case zmachine.OpenCommandRecording:
+				saveRestoreChannel <- zmachine.CommandRecordingResponse{}
+			case zmachine.OpenCommandScript:
+				saveRestoreChannel <- zmachine.CommandScriptResponse{}
 			case zmachine.Quit:
 				collectOutput = false
 			case zmachine.Restart:
@@ -458,7 +476,7 @@ func runGameTest(gamePath string) (result TestResult) {
 				result.ErrorMessage = fmt.Sprintf("After command %d %q: %s", commandIndex, lastCommand, string(v))
 				return
 			}
-		case <-timeout:
+		case <-ctx.Done():
 			result.Success = false
 			result.ErrorMessage = fmt.Sprintf("Timeout after command %d %q", commandIndex, lastCommand)
 			return
@@ -0,0 +1,62 @@
+// zdap is a Debug Adapter Protocol server for debugging stories from an
+// editor. It talks over stdio by default, which is how editors normally
+// launch debug adapters, or listens on a TCP address for editors which
+// connect to a running server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/davetcode/goz/dap"
+)
+
+func main() {
+	listen := flag.String("listen", "", "TCP address to listen on, e.g. 127.0.0.1:4711, instead of using stdio")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *listen == "" {
+		if err := dap.Serve(ctx, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Debug session failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to listen on %s: %v\n", *listen, err)
+		os.Exit(1)
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close() // nolint:errcheck
+	}()
+	log.Printf("Listening for debug sessions on %s", listener.Addr())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to accept connection: %v", err)
+			continue
+		}
+
+		go func() {
+			defer conn.Close() // nolint:errcheck
+			if err := dap.Serve(ctx, conn, conn); err != nil {
+				log.Printf("Debug session from %s failed: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
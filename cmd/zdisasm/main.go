@@ -0,0 +1,296 @@
+// zdisasm prints a txd style listing of the routines in a story file without
+// running it. Routines are found by following calls from the start of the
+// story and then by trying to decode a routine at every packed address which
+// isn't inside one already found.
+// Given Inform debug information the routines it lists are decoded too and
+// routines and variables are named.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/davetcode/goz/blorb"
+	"github.com/davetcode/goz/debuginfo"
+	"github.com/davetcode/goz/zcore"
+	"github.com/davetcode/goz/zmachine"
+	"github.com/davetcode/goz/zstring"
+)
+
+type routine struct {
+	address      uint32 // Address of the locals count, the code follows the locals
+	locals       []uint16
+	instructions []zmachine.Instruction
+	end          uint32
+}
+
+type disassembler struct {
+	core      zcore.Core
+	alphabets *zstring.Alphabets
+	routines  map[uint32]*routine
+	info      *debuginfo.Info // nil without -debuginfo
+}
+
+func main() {
+	romFilePath := flag.String("rom", "", "The path of a z-machine rom")
+	routineAddress := flag.String("routine", "", "Only list the routine at this byte address (hex) or with this name")
+	debugInfoPath := flag.String("debuginfo", "", "Inform debug information file (gameinfo.dbg) to name routines and variables")
+	flag.Parse()
+
+	if *romFilePath == "" {
+		fmt.Fprintln(os.Stderr, "Usage: zdisasm -rom story.z5 [-routine 4e37] [-debuginfo gameinfo.dbg]")
+		os.Exit(2)
+	}
+
+	romFileBytes, err := os.ReadFile(*romFilePath)
+	if err == nil {
+		romFileBytes, _, err = blorb.Unwrap(romFileBytes)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read story file: %v\n", err)
+		os.Exit(1)
+	}
+
+	d := disassembler{
+		core:     zcore.LoadCore(romFileBytes),
+		routines: make(map[uint32]*routine),
+	}
+	d.alphabets = zstring.LoadAlphabets(&d.core)
+
+	if *debugInfoPath != "" {
+		d.info, err = debuginfo.Load(*debugInfoPath, &d.core)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load debug information: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *routineAddress != "" {
+		address, err := strconv.ParseUint(*routineAddress, 16, 32)
+		if d.info != nil {
+			if named := d.info.RoutineNamed(*routineAddress); named != nil {
+				address, err = uint64(named.Address), nil
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid routine address %q: %v\n", *routineAddress, err)
+			os.Exit(2)
+		}
+		r, err := d.decodeRoutine(uint32(address))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to decode routine: %v\n", err)
+			os.Exit(1)
+		}
+		d.print(r, d.mainRoutine())
+		return
+	}
+
+	mainRoutine := d.mainRoutine()
+	d.walk(mainRoutine)
+	if d.info != nil {
+		for _, r := range d.info.Routines() {
+			d.walk(r.Address)
+		}
+	}
+	d.sweep()
+
+	addresses := make([]uint32, 0, len(d.routines))
+	for address := range d.routines {
+		addresses = append(addresses, address)
+	}
+	slices.Sort(addresses)
+	for _, address := range addresses {
+		d.print(d.routines[address], mainRoutine)
+	}
+}
+
+// mainRoutine is where the story starts. Outside V6 the initial PC is the
+// first instruction so the routine header is assumed to be the byte before.
+func (d *disassembler) mainRoutine() uint32 {
+	if d.core.Version == 6 {
+		return zmachine.UnpackAddress(&d.core, uint32(d.core.FirstInstruction), false)
+	}
+	return uint32(d.core.FirstInstruction) - 1
+}
+
+// walk decodes the routine at address and every routine it calls
+func (d *disassembler) walk(address uint32) {
+	pending := []uint32{address}
+	for len(pending) > 0 {
+		address, pending = pending[len(pending)-1], pending[:len(pending)-1]
+		if _, ok := d.routines[address]; ok {
+			continue
+		}
+		r, err := d.decodeRoutine(address)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping routine at %04x: %v\n", address, err)
+			continue
+		}
+		d.routines[address] = r
+		for _, instruction := range r.instructions {
+			if instruction.RoutineAddress != 0 {
+				pending = append(pending, instruction.RoutineAddress)
+			}
+		}
+	}
+}
+
+// sweep looks for routines which are never called directly, e.g. those only
+// referenced from object properties. Like txd it tries a routine at every
+// packed address in the gaps between those already found, a candidate must
+// end before the next known routine starts. After the last one only routines
+// straight after each other are taken, as that is where the strings start and
+// they often decode as nonsense. Addresses which don't decode are remembered
+// so each is only tried once.
+func (d *disassembler) sweep() {
+	alignment := zmachine.UnpackAddress(&d.core, 1, false) - zmachine.UnpackAddress(&d.core, 0, false)
+	rejected := make(map[uint32]bool)
+
+	for found := true; found; {
+		found = false
+		starts := make([]uint32, 0, len(d.routines))
+		for address := range d.routines {
+			starts = append(starts, address)
+		}
+		slices.Sort(starts)
+
+		for i, start := range starts {
+			limit := d.core.MemoryLength()
+			if i+1 < len(starts) {
+				limit = starts[i+1]
+			}
+			for address := (d.routines[start].end + alignment - 1) / alignment * alignment; address < limit; address += alignment {
+				if rejected[address] {
+					continue
+				}
+				candidate, err := d.decodeRoutine(address)
+				if err != nil || candidate.end > limit {
+					rejected[address] = true
+					if i+1 == len(starts) {
+						break
+					}
+					continue
+				}
+				d.routines[address] = candidate
+				found = true
+				for _, instruction := range candidate.instructions {
+					if instruction.RoutineAddress != 0 {
+						d.walk(instruction.RoutineAddress)
+					}
+				}
+				// Carry on looking after the routine just found
+				address = (candidate.end+alignment-1)/alignment*alignment - alignment
+			}
+		}
+	}
+}
+
+// decodeRoutine decodes instructions until one which can't fall through to
+// the next is reached past the furthest branch seen, the same as txd
+func (d *disassembler) decodeRoutine(address uint32) (*routine, error) {
+	if address == 0 || address >= d.core.MemoryLength() {
+		return nil, fmt.Errorf("address out of range")
+	}
+
+	localCount := d.core.ReadZByte(address)
+	if localCount > 15 {
+		return nil, fmt.Errorf("%d locals", localCount)
+	}
+
+	r := &routine{address: address, locals: make([]uint16, localCount)}
+	pc := address + 1
+	if d.core.Version < 5 {
+		if pc+2*uint32(localCount) > d.core.MemoryLength() {
+			return nil, fmt.Errorf("locals run past the end of memory")
+		}
+		for i := range r.locals {
+			r.locals[i] = d.core.ReadHalfWord(pc)
+			pc += 2
+		}
+	}
+
+	furthest := pc
+	for {
+		instruction, err := zmachine.DecodeInstruction(&d.core, d.alphabets, pc)
+		if err != nil {
+			return nil, err
+		}
+		if instruction.Mnemonic == "unknown" {
+			return nil, fmt.Errorf("unknown opcode at %04x", pc)
+		}
+		r.instructions = append(r.instructions, instruction)
+		pc += instruction.Length
+
+		if instruction.Branches && instruction.BranchTarget > 1 {
+			furthest = max(furthest, instruction.BranchTarget)
+		}
+		if instruction.JumpTarget != 0 {
+			furthest = max(furthest, instruction.JumpTarget)
+		}
+
+		if endsRoutine(instruction.Mnemonic) && pc > furthest {
+			r.end = pc
+			return r, nil
+		}
+	}
+}
+
+// endsRoutine is true for instructions after which execution never carries on
+// to the next instruction
+func endsRoutine(mnemonic string) bool {
+	switch mnemonic {
+	case "rtrue", "rfalse", "ret", "ret_popped", "print_ret", "jump", "quit", "restart", "throw":
+		return true
+	}
+	return false
+}
+
+func (d *disassembler) print(r *routine, mainRoutine uint32) {
+	name := "Routine"
+	if r.address == mainRoutine {
+		name = "Main routine"
+	}
+	if d.info != nil {
+		if known := d.info.RoutineAt(r.address); known != nil && known.Address == r.address {
+			name = fmt.Sprintf("%s %s (%s)", name, known.Name, known.Location)
+		}
+	}
+	fmt.Printf("%s %04x, %d locals", name, r.address, len(r.locals))
+	if d.core.Version < 5 && len(r.locals) > 0 {
+		fmt.Print(" (")
+		for i, local := range r.locals {
+			if i > 0 {
+				fmt.Print(", ")
+			}
+			fmt.Printf("%04x", local)
+		}
+		fmt.Print(")")
+	}
+	fmt.Print("\n\n")
+
+	for _, instruction := range r.instructions {
+		if d.info != nil {
+			instruction.Annotate(d.info)
+			if location, ok := d.info.Location(instruction.Address); ok && d.startsLine(instruction.Address) {
+				fmt.Printf("        ; %s\n", location)
+			}
+		}
+		fmt.Printf("%6x:  %s\n", instruction.Address, instruction)
+	}
+	fmt.Println()
+}
+
+// startsLine is true if address is the first instruction of a source line
+func (d *disassembler) startsLine(address uint32) bool {
+	if known := d.info.RoutineAt(address); known != nil {
+		for _, point := range known.Lines {
+			if point.Address == address {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,333 @@
+// zinfo prints what's in a story file's header and tables, in the spirit of
+// infodump, either as text or as JSON for other tools.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/davetcode/goz/blorb"
+	"github.com/davetcode/goz/dictionary"
+	"github.com/davetcode/goz/zcore"
+	"github.com/davetcode/goz/zobject"
+	"github.com/davetcode/goz/zstring"
+)
+
+type header struct {
+	Version               uint8    `json:"version"`
+	Flags1                uint8    `json:"flags1"`
+	Release               uint16   `json:"release"`
+	Serial                string   `json:"serial"`
+	HighMemory            uint16   `json:"high_memory"`
+	InitialPC             uint16   `json:"initial_pc"`
+	Dictionary            uint16   `json:"dictionary"`
+	ObjectTable           uint16   `json:"object_table"`
+	Globals               uint16   `json:"globals"`
+	StaticMemory          uint16   `json:"static_memory"`
+	Flags2                uint16   `json:"flags2"`
+	Abbreviations         uint16   `json:"abbreviations"`
+	FileLength            uint32   `json:"file_length"`
+	Checksum              uint16   `json:"checksum"`
+	InterpreterNumber     uint8    `json:"interpreter_number"`
+	InterpreterVersion    uint8    `json:"interpreter_version"`
+	ScreenHeightLines     uint8    `json:"screen_height_lines"`
+	ScreenWidthChars      uint8    `json:"screen_width_chars"`
+	ScreenWidthUnits      uint16   `json:"screen_width_units"`
+	ScreenHeightUnits     uint16   `json:"screen_height_units"`
+	FontWidth             uint8    `json:"font_width"`
+	FontHeight            uint8    `json:"font_height"`
+	RoutinesOffset        uint16   `json:"routines_offset"`
+	StringsOffset         uint16   `json:"strings_offset"`
+	DefaultBackground     uint8    `json:"default_background"`
+	DefaultForeground     uint8    `json:"default_foreground"`
+	TerminatingCharacters uint16   `json:"terminating_characters"`
+	Stream3Width          uint16   `json:"stream3_width"`
+	StandardRevision      string   `json:"standard_revision"`
+	AlphabetTable         uint16   `json:"alphabet_table"`
+	ExtensionTable        uint16   `json:"extension_table"`
+	Extension             []uint16 `json:"extension,omitempty"`
+}
+
+type objectSummary struct {
+	Count int      `json:"count"`
+	Roots []string `json:"roots,omitempty"` // Objects with no parent, usually rooms and the like
+}
+
+type dictionarySummary struct {
+	Separators  string `json:"separators"`
+	EntryLength uint8  `json:"entry_length"`
+	Entries     int    `json:"entries"`
+	Sorted      bool   `json:"sorted"`
+}
+
+// blorbSummary lists what a Blorb holds besides the story
+type blorbSummary struct {
+	Pictures     []uint32 `json:"pictures,omitempty"`
+	Sounds       []uint32 `json:"sounds,omitempty"`
+	Frontispiece *uint32  `json:"frontispiece,omitempty"`
+	Release      *uint16  `json:"release,omitempty"`
+	Metadata     string   `json:"metadata,omitempty"` // iFiction XML
+}
+
+type storyInfo struct {
+	Header                header            `json:"header"`
+	Abbreviations         []string          `json:"abbreviations,omitempty"`
+	Alphabets             [3]string         `json:"alphabets"`
+	TerminatingCharacters []uint8           `json:"terminating_characters,omitempty"`
+	Objects               objectSummary     `json:"objects"`
+	Dictionary            dictionarySummary `json:"dictionary"`
+	Blorb                 *blorbSummary     `json:"blorb,omitempty"`
+}
+
+func main() {
+	romFilePath := flag.String("rom", "", "The path of a z-machine rom")
+	jsonOutput := flag.Bool("json", false, "Write the information as JSON")
+	flag.Parse()
+
+	if *romFilePath == "" {
+		fmt.Fprintln(os.Stderr, "Usage: zinfo -rom story.z5|story.zblorb [-json]")
+		os.Exit(2)
+	}
+
+	romFileBytes, err := os.ReadFile(*romFilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read story file: %v\n", err)
+		os.Exit(1)
+	}
+	storyFile, resources, err := blorb.Unwrap(romFileBytes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read Blorb: %v\n", err)
+		os.Exit(1)
+	}
+
+	info := inspect(storyFile)
+	if resources != nil {
+		info.Blorb = summariseBlorb(resources)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(info); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printInfo(*romFilePath, info)
+}
+
+// inspect gathers everything about the story. LoadCore fills in the
+// interpreter's details in the header so those are taken from the file as it
+// was before loading.
+func inspect(storyFile []uint8) storyInfo {
+	original := slices.Clone(storyFile[:0x40])
+	core := zcore.LoadCore(storyFile)
+	alphabets := zstring.LoadAlphabets(&core)
+
+	info := storyInfo{
+		Header: header{
+			Version:               core.Version,
+			Flags1:                original[0x01],
+			Release:               core.ReleaseNumber,
+			Serial:                core.SerialCode(),
+			HighMemory:            core.PagedMemoryBase,
+			InitialPC:             core.FirstInstruction,
+			Dictionary:            core.DictionaryBase,
+			ObjectTable:           core.ObjectTableBase,
+			Globals:               core.GlobalVariableBase,
+			StaticMemory:          core.StaticMemoryBase,
+			Flags2:                core.Flags2(),
+			Abbreviations:         core.AbbreviationTableBase,
+			FileLength:            core.FileLength(),
+			Checksum:              core.FileChecksum,
+			InterpreterNumber:     original[0x1e],
+			InterpreterVersion:    original[0x1f],
+			ScreenHeightLines:     original[0x20],
+			ScreenWidthChars:      original[0x21],
+			ScreenWidthUnits:      uint16(original[0x22])<<8 | uint16(original[0x23]),
+			ScreenHeightUnits:     uint16(original[0x24])<<8 | uint16(original[0x25]),
+			FontWidth:             original[0x26],
+			FontHeight:            original[0x27],
+			RoutinesOffset:        core.RoutinesOffset,
+			StringsOffset:         core.StringOffset,
+			DefaultBackground:     core.DefaultBackgroundColorNumber,
+			DefaultForeground:     core.DefaultForegroundColorNumber,
+			TerminatingCharacters: core.TerminatingCharTableBase,
+			Stream3Width:          core.OutputStream3Width,
+			StandardRevision:      fmt.Sprintf("%d.%d", original[0x32], original[0x33]),
+			AlphabetTable:         core.AlternativeCharSetBaseAddress,
+			ExtensionTable:        core.ExtensionTableBaseAddress,
+			Extension:             core.ExtensionTable(),
+		},
+		Alphabets: alphabets.Rows(),
+	}
+
+	// V1 has no abbreviations and V2 only the first 32
+	abbreviationSets := uint8(3)
+	switch core.Version {
+	case 1:
+		abbreviationSets = 0
+	case 2:
+		abbreviationSets = 1
+	}
+	for z := uint8(1); z <= abbreviationSets; z++ {
+		for x := range uint8(32) {
+			info.Abbreviations = append(info.Abbreviations, zstring.FindAbbreviation(&core, alphabets, z, x))
+		}
+	}
+
+	if core.Version >= 5 && core.TerminatingCharTableBase != 0 {
+		for address := uint32(core.TerminatingCharTableBase); address < core.MemoryLength(); address++ {
+			zchr := core.ReadZByte(address)
+			if zchr == 0 {
+				break
+			}
+			info.TerminatingCharacters = append(info.TerminatingCharacters, zchr)
+		}
+	}
+
+	info.Objects = summariseObjects(&core, alphabets)
+	info.Dictionary = summariseDictionary(&core, alphabets)
+	return info
+}
+
+func summariseBlorb(b *blorb.Blorb) *blorbSummary {
+	summary := &blorbSummary{
+		Pictures: b.Numbers(blorb.Picture),
+		Sounds:   b.Numbers(blorb.Sound),
+		Metadata: string(b.Metadata),
+	}
+	if b.HasCover {
+		summary.Frontispiece = &b.Frontispiece
+	}
+	if b.HasRelease {
+		summary.Release = &b.Release
+	}
+	return summary
+}
+
+func summariseObjects(core *zcore.Core, alphabets *zstring.Alphabets) objectSummary {
+	summary := objectSummary{Count: zobject.Count(core)}
+	for id := uint16(1); int(id) <= summary.Count; id++ {
+		obj := zobject.GetObject(id, core, alphabets)
+		if obj.Parent == 0 {
+			summary.Roots = append(summary.Roots, fmt.Sprintf("%d %s", obj.Id, obj.Name))
+		}
+	}
+	return summary
+}
+
+func summariseDictionary(core *zcore.Core, alphabets *zstring.Alphabets) dictionarySummary {
+	d := dictionary.ParseDictionary(uint32(core.DictionaryBase), core, alphabets)
+	return dictionarySummary{
+		Separators:  string(d.Header.InputCodes),
+		EntryLength: d.Header.EntryLength,
+		Entries:     len(d.Entries()),
+		Sorted:      d.Header.Count >= 0,
+	}
+}
+
+func printInfo(romFilePath string, info storyInfo) {
+	h := info.Header
+	fmt.Printf("Story file is %s\n\n", romFilePath)
+	fmt.Print("    *** Header ***\n\n")
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"Z-code version", fmt.Sprint(h.Version)},
+		{"Flags 1", fmt.Sprintf("%08b", h.Flags1)},
+		{"Release number", fmt.Sprint(h.Release)},
+		{"Serial number", h.Serial},
+		{"Size of resident memory", fmt.Sprintf("%04x", h.HighMemory)},
+		{"Start PC", fmt.Sprintf("%04x", h.InitialPC)},
+		{"Dictionary address", fmt.Sprintf("%04x", h.Dictionary)},
+		{"Object table address", fmt.Sprintf("%04x", h.ObjectTable)},
+		{"Global variables address", fmt.Sprintf("%04x", h.Globals)},
+		{"Size of dynamic memory", fmt.Sprintf("%04x", h.StaticMemory)},
+		{"Flags 2", fmt.Sprintf("%016b", h.Flags2)},
+		{"Abbreviations address", fmt.Sprintf("%04x", h.Abbreviations)},
+		{"File size", fmt.Sprintf("%05x", h.FileLength)},
+		{"Checksum", fmt.Sprintf("%04x", h.Checksum)},
+		{"Interpreter number", fmt.Sprint(h.InterpreterNumber)},
+		{"Interpreter version", fmt.Sprint(h.InterpreterVersion)},
+		{"Screen size (chars)", fmt.Sprintf("%dx%d", h.ScreenWidthChars, h.ScreenHeightLines)},
+		{"Screen size (units)", fmt.Sprintf("%dx%d", h.ScreenWidthUnits, h.ScreenHeightUnits)},
+		{"Font size (units)", fmt.Sprintf("%dx%d", h.FontWidth, h.FontHeight)},
+		{"Routines offset", fmt.Sprintf("%04x", h.RoutinesOffset)},
+		{"Strings offset", fmt.Sprintf("%04x", h.StringsOffset)},
+		{"Default colours", fmt.Sprintf("background %d, foreground %d", h.DefaultBackground, h.DefaultForeground)},
+		{"Terminating keys address", fmt.Sprintf("%04x", h.TerminatingCharacters)},
+		{"Output stream 3 width", fmt.Sprint(h.Stream3Width)},
+		{"Standard revision", h.StandardRevision},
+		{"Alphabet table address", fmt.Sprintf("%04x", h.AlphabetTable)},
+		{"Header extension address", fmt.Sprintf("%04x", h.ExtensionTable)},
+	}
+	for _, field := range fields {
+		fmt.Printf("%-26s %s\n", field.name+":", field.value)
+	}
+
+	if len(h.Extension) > 0 {
+		fmt.Print("\n    *** Header extension ***\n\n")
+		names := []string{"Mouse x", "Mouse y", "Unicode table address", "Flags 3", "True default foreground", "True default background"}
+		for ix, word := range h.Extension {
+			name := fmt.Sprintf("Word %d", ix+1)
+			if ix < len(names) {
+				name = names[ix]
+			}
+			fmt.Printf("%-26s %04x\n", name+":", word)
+		}
+	}
+
+	if len(info.Abbreviations) > 0 {
+		fmt.Print("\n    *** Abbreviations ***\n\n")
+		for ix, abbreviation := range info.Abbreviations {
+			fmt.Printf("[%2d] \"%s\"\n", ix, abbreviation)
+		}
+	}
+
+	fmt.Print("\n    *** Alphabets ***\n\n")
+	for ix, row := range info.Alphabets {
+		fmt.Printf("A%d: %s\n", ix, strings.ReplaceAll(row, "\n", "^"))
+	}
+
+	if len(info.TerminatingCharacters) > 0 {
+		fmt.Print("\n    *** Terminating characters ***\n\n")
+		for _, zchr := range info.TerminatingCharacters {
+			fmt.Printf("%d\n", zchr)
+		}
+	}
+
+	fmt.Print("\n    *** Objects ***\n\n")
+	fmt.Printf("%d objects, %d with no parent:\n", info.Objects.Count, len(info.Objects.Roots))
+	for _, root := range info.Objects.Roots {
+		fmt.Printf("  %s\n", root)
+	}
+
+	d := info.Dictionary
+	fmt.Print("\n    *** Dictionary ***\n\n")
+	fmt.Printf("%-26s %q\n", "Word separators:", d.Separators)
+	fmt.Printf("%-26s %d\n", "Entry length:", d.EntryLength)
+	fmt.Printf("%-26s %d\n", "Number of entries:", d.Entries)
+	fmt.Printf("%-26s %t\n", "Sorted:", d.Sorted)
+
+	if b := info.Blorb; b != nil {
+		fmt.Print("\n    *** Blorb ***\n\n")
+		fmt.Printf("%-26s %v\n", "Pictures:", b.Pictures)
+		fmt.Printf("%-26s %v\n", "Sounds:", b.Sounds)
+		if b.Frontispiece != nil {
+			fmt.Printf("%-26s %d\n", "Frontispiece:", *b.Frontispiece)
+		}
+		if b.Release != nil {
+			fmt.Printf("%-26s %d\n", "Release number:", *b.Release)
+		}
+		if b.Metadata != "" {
+			fmt.Printf("%-26s %d bytes of iFiction\n", "Metadata:", len(b.Metadata))
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// zobjects exports a story's object tree as JSON or as a Graphviz graph,
+// either as the story starts or as it was in a save file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/davetcode/goz/blorb"
+	"github.com/davetcode/goz/zmachine"
+	"github.com/davetcode/goz/zobject"
+)
+
+func main() {
+	romFilePath := flag.String("rom", "", "The path of a z-machine rom")
+	savePath := flag.String("save", "", "Save file to restore before exporting (Quetzal or goz format)")
+	format := flag.String("format", "json", "Output format, json or dot")
+	flag.Parse()
+
+	if *romFilePath == "" || (*format != "json" && *format != "dot") {
+		fmt.Fprintln(os.Stderr, "Usage: zobjects -rom story.z5 [-save game.sav] [-format json|dot]")
+		os.Exit(2)
+	}
+
+	romFileBytes, err := os.ReadFile(*romFilePath)
+	if err == nil {
+		romFileBytes, _, err = blorb.Unwrap(romFileBytes)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read story file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The machine is never run, it's only used to restore the save
+	z := zmachine.LoadRom(romFileBytes, nil, nil, nil)
+	if *savePath != "" {
+		saveBytes, err := os.ReadFile(*savePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read save file: %v\n", err)
+			os.Exit(1)
+		}
+		if !z.ImportSaveState(saveBytes) {
+			fmt.Fprintln(os.Stderr, "Save file doesn't belong to this story or is corrupt")
+			os.Exit(1)
+		}
+	}
+
+	objects := zobject.Export(&z.Core, z.Alphabets)
+	if *format == "dot" {
+		err = zobject.WriteDOT(os.Stdout, objects)
+	} else {
+		err = zobject.WriteJSON(os.Stdout, objects)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write objects: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,938 @@
+// Package dap serves the Debug Adapter Protocol so that editors such as VS
+// Code can run a story and debug it with breakpoints, stepping and views of
+// the call stack, locals and globals. The story's text goes to the editor's
+// debug console and lines typed in its REPL are the player's input.
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/davetcode/goz/blorb"
+	"github.com/davetcode/goz/debugger"
+	"github.com/davetcode/goz/debuginfo"
+	"github.com/davetcode/goz/dumbterminal"
+	"github.com/davetcode/goz/zmachine"
+)
+
+// threadId is the machine's only thread
+const threadId = 1
+
+// maxDisassembledInstructions is the most a disassemble request gets back,
+// far more than any client shows at once
+const maxDisassembledInstructions = 4096
+
+// Variables references: globals are 1, then each frame has two, one for its
+// locals and one for its routine stack. Frame ids are the frame's index in
+// CallFrames plus one as DAP treats a zero id as no frame.
+const globalsReference = 1
+
+// frameReferences returns the variables references for the frame at index
+// frame in CallFrames
+func frameReferences(frame int) (locals int, stack int) {
+	return 2 + 2*frame, 3 + 2*frame
+}
+
+// Session is a single client's debugging session, it runs at most one story
+type Session struct {
+	messages messageWriter
+	ctx      context.Context // Cancelled to stop the story
+	cancel   context.CancelFunc
+
+	z          *zmachine.ZMachine
+	debugger   *debugger.Debugger
+	frontend   *dumbterminal.Frontend
+	info       *debuginfo.Info // nil without debug information
+	sourceDir  string          // Where source paths in the debug information are relative to
+	input      chan string     // Lines typed in the REPL, queued for the story
+	entry      bool            // The first pause is stopOnEntry rather than a pause request
+	running    chan struct{}
+	resume     chan struct{} // Carries on from a stop, see onStop
+	mu         sync.Mutex    // Guards stopped and finished, set by the machine's goroutine
+	stopped    bool
+	finished   bool // The story has stopped running, nothing reads input any more
+	breakpoint struct {
+		nextId      int
+		instruction []uint32
+		function    []uint32
+		source      []uint32
+	}
+	watchpoints []int // Debugger ids of the data breakpoints
+}
+
+// Serve runs a session reading requests from in and writing responses and
+// events to out until the client disconnects or in ends
+func Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Session{
+		messages: messageWriter{w: out},
+		ctx:      ctx,
+		cancel:   cancel,
+		resume:   make(chan struct{}),
+	}
+	defer s.stopStory()
+
+	reader := bufio.NewReader(in)
+	for {
+		body, err := readMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		result, err := s.handle(req)
+		if err := s.messages.respond(req, result, err); err != nil {
+			return err
+		}
+
+		switch {
+		case req.Command == "disconnect":
+			return nil
+		case req.Command == "launch" && err == nil:
+			// Ready for breakpoints now that there's a story to put them in
+			s.messages.event("initialized", nil) // nolint:errcheck
+		}
+	}
+}
+
+// arguments decodes a request's arguments
+func arguments[T any](req request) (T, error) {
+	var args T
+	if len(req.Arguments) == 0 {
+		return args, nil
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return args, fmt.Errorf("invalid arguments to %s: %w", req.Command, err)
+	}
+	return args, nil
+}
+
+func (s *Session) handle(req request) (any, error) {
+	if s.z == nil {
+		switch req.Command {
+		case "initialize", "launch", "disconnect", "terminate":
+		default:
+			return nil, fmt.Errorf("%s needs a story to be launched first", req.Command)
+		}
+	}
+
+	switch req.Command {
+	case "initialize":
+		return capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsFunctionBreakpoints:      true,
+			SupportsInstructionBreakpoints:   true,
+			SupportsDataBreakpoints:          true,
+			SupportsDisassembleRequest:       true,
+			SupportsReadMemoryRequest:        true,
+			SupportsSetVariable:              true,
+			SupportsTerminateRequest:         true,
+		}, nil
+
+	case "launch":
+		args, err := arguments[launchArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.launch(args)
+
+	case "configurationDone":
+		s.startStory()
+		return nil, nil
+
+	case "disconnect", "terminate":
+		s.stopStory()
+		return nil, nil
+
+	case "threads":
+		return threadsBody{Threads: []thread{{Id: threadId, Name: "main"}}}, nil
+
+	case "continue":
+		return map[string]bool{"allThreadsContinued": true}, s.carryOn(s.debugger.Continue)
+
+	case "next":
+		return nil, s.carryOn(s.debugger.StepOver)
+
+	case "stepIn":
+		return nil, s.carryOn(s.debugger.Step)
+
+	case "stepOut":
+		return nil, s.carryOn(s.debugger.StepOut)
+
+	case "pause":
+		s.debugger.Pause()
+		return nil, nil
+
+	case "setBreakpoints":
+		args, err := arguments[setBreakpointsArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.setSourceBreakpoints(args), nil
+
+	case "setFunctionBreakpoints":
+		args, err := arguments[setFunctionBreakpointsArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.setFunctionBreakpoints(args), nil
+
+	case "setInstructionBreakpoints":
+		args, err := arguments[setInstructionBreakpointsArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.setInstructionBreakpoints(args), nil
+
+	case "dataBreakpointInfo":
+		args, err := arguments[dataBreakpointInfoArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.dataBreakpointInfo(args), nil
+
+	case "setDataBreakpoints":
+		args, err := arguments[setDataBreakpointsArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.setDataBreakpoints(args), nil
+
+	case "evaluate":
+		args, err := arguments[evaluateArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.evaluate(args)
+	}
+
+	// Everything else looks at the machine's state so needs it to be paused
+	if !s.isStopped() {
+		return nil, fmt.Errorf("%s needs the story to be paused", req.Command)
+	}
+
+	switch req.Command {
+	case "stackTrace":
+		args, err := arguments[stackTraceArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.stackTrace(args), nil
+
+	case "scopes":
+		args, err := arguments[scopesArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.scopes(args)
+
+	case "variables":
+		args, err := arguments[variablesArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.variables(args)
+
+	case "setVariable":
+		args, err := arguments[setVariableArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.setVariable(args)
+
+	case "readMemory":
+		args, err := arguments[readMemoryArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.readMemory(args)
+
+	case "disassemble":
+		args, err := arguments[disassembleArguments](req)
+		if err != nil {
+			return nil, err
+		}
+		return s.disassemble(args)
+	}
+
+	return nil, fmt.Errorf("unsupported request %s", req.Command)
+}
+
+// outputWriter sends whatever is written to it to the client's debug console
+type outputWriter struct {
+	messages *messageWriter
+	category string
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	if err := w.messages.event("output", outputBody{Category: w.category, Output: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *Session) launch(args launchArguments) error {
+	if s.z != nil {
+		return errors.New("a story has already been launched")
+	}
+
+	data, err := os.ReadFile(args.Program)
+	if err != nil {
+		return fmt.Errorf("unable to read story file: %w", err)
+	}
+	romFileBytes, resources, err := blorb.Unwrap(data)
+	if err != nil {
+		return fmt.Errorf("unable to read story file: %w", err)
+	}
+
+	// The story reads its input from lines typed in the REPL, a goroutine
+	// feeds them through so that typing ahead doesn't block the session
+	inputReader, inputWriter := io.Pipe()
+	s.input = make(chan string, 64)
+	go func() {
+		defer inputWriter.Close() // nolint:errcheck
+		for line := range s.input {
+			if _, err := io.WriteString(inputWriter, line+"\n"); err != nil {
+				return
+			}
+		}
+	}()
+
+	s.frontend = dumbterminal.New(args.Program, inputReader,
+		outputWriter{messages: &s.messages, category: "stdout"},
+		outputWriter{messages: &s.messages, category: "stderr"})
+	s.z = zmachine.LoadRomWithFrontend(romFileBytes, s.frontend)
+	s.z.SetResources(resources)
+
+	if args.Commands != "" {
+		script, err := os.Open(args.Commands)
+		if err != nil {
+			return fmt.Errorf("unable to read command file: %w", err)
+		}
+		s.z.SetCommandScript(script)
+	}
+
+	if err := s.loadDebugInfo(args); err != nil {
+		return err
+	}
+
+	s.debugger = debugger.New(s.z, s.onStop)
+	s.debugger.SetWatchLogger(func(hit debugger.WatchHit) {
+		s.messages.event("output", outputBody{Category: "console", Output: hit.String() + "\n"}) // nolint:errcheck
+	})
+	if args.StopOnEntry {
+		s.entry = true
+		s.debugger.Pause()
+	}
+	return nil
+}
+
+// loadDebugInfo reads the debug information named in the launch arguments or,
+// failing that, gameinfo.dbg next to the story if it's there and for the
+// same story
+func (s *Session) loadDebugInfo(args launchArguments) error {
+	path := args.DebugInfo
+	if path == "" {
+		path = filepath.Join(filepath.Dir(args.Program), "gameinfo.dbg")
+	}
+	info, err := debuginfo.Load(path, &s.z.Core)
+	switch {
+	case err != nil && args.DebugInfo != "":
+		return fmt.Errorf("unable to read debug information: %w", err)
+	case err != nil:
+		return nil
+	}
+	s.info = info
+	s.sourceDir = filepath.Dir(path)
+	s.z.SetSymbols(info)
+	return nil
+}
+
+// sourceOf is where a location's file is, relative paths are taken to be
+// from the debug information's directory as that's where Inform was run
+func (s *Session) sourceOf(location debuginfo.Location) *source {
+	path := location.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.sourceDir, path)
+	}
+	return &source{Name: filepath.Base(location.File), Path: path}
+}
+
+// startStory runs the story on its own goroutine once the client has set
+// its breakpoints
+func (s *Session) startStory() {
+	if s.running != nil {
+		return
+	}
+	s.running = make(chan struct{})
+
+	go func() {
+		defer close(s.running)
+		exitCode := 0
+		err := s.frontend.Run(s.ctx, s.z)
+		s.mu.Lock()
+		s.finished = true
+		s.mu.Unlock()
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, debugger.ErrQuit) {
+			exitCode = 1
+		}
+		s.messages.event("exited", exitedBody{ExitCode: exitCode}) // nolint:errcheck
+		s.messages.event("terminated", nil)                        // nolint:errcheck
+	}()
+}
+
+// stopStory stops the story, if it's running, and waits for it to finish
+func (s *Session) stopStory() {
+	s.cancel()
+	if s.input != nil {
+		close(s.input)
+		s.input = nil
+	}
+	if s.running != nil {
+		<-s.running
+	}
+}
+
+// onStop tells the client the story has paused and waits until it's told to
+// carry on, it runs on the machine's goroutine
+func (s *Session) onStop(stop debugger.Stop) error {
+	body := stoppedBody{Reason: "step", ThreadId: threadId, AllThreadsStopped: true}
+	switch stop.Reason {
+	case debugger.StopBreakpoint:
+		body.Reason = "breakpoint"
+	case debugger.StopPause:
+		body.Reason = "pause"
+		if s.entry {
+			body.Reason = "entry"
+			s.entry = false
+		}
+	case debugger.StopWatch:
+		body.Reason = "data breakpoint"
+		hits := make([]string, len(stop.Hits))
+		for ix, hit := range stop.Hits {
+			hits[ix] = hit.String()
+		}
+		body.Description = strings.Join(hits, "\n")
+		body.Text = body.Description
+	}
+
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	if err := s.messages.event("stopped", body); err != nil {
+		return err
+	}
+
+	select {
+	case <-s.resume:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func (s *Session) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+func (s *Session) hasFinished() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.finished
+}
+
+// carryOn tells the debugger how to carry on and then lets the paused
+// machine go
+func (s *Session) carryOn(how func()) error {
+	s.mu.Lock()
+	if !s.stopped {
+		s.mu.Unlock()
+		return errors.New("the story isn't paused")
+	}
+	s.stopped = false
+	s.mu.Unlock()
+
+	how()
+	s.resume <- struct{}{}
+	return nil
+}
+
+// parseReference reads an address sent as a memory or instruction
+// reference, which this server always sends as 0x-prefixed hex
+func parseReference(reference string) (uint32, error) {
+	address, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(reference), "0x"), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", reference)
+	}
+	return uint32(address), nil
+}
+
+func reference(address uint32) string {
+	return fmt.Sprintf("0x%x", address)
+}
+
+func (s *Session) nextBreakpointId() int {
+	s.breakpoint.nextId++
+	return s.breakpoint.nextId
+}
+
+// syncBreakpoints gives the debugger every kind of breakpoint, each kind is
+// replaced as a whole by the client so the debugger's are rebuilt each time
+func (s *Session) syncBreakpoints() {
+	s.debugger.ClearBreakpoints()
+	for _, addresses := range [][]uint32{s.breakpoint.instruction, s.breakpoint.function, s.breakpoint.source} {
+		for _, address := range addresses {
+			s.debugger.AddBreakpoint(address)
+		}
+	}
+}
+
+// setSourceBreakpoints breaks on the first instruction of each sequence
+// point on a line, there can be several where a line has more than one
+// statement or is inside a loop's condition
+func (s *Session) setSourceBreakpoints(args setBreakpointsArguments) breakpointsBody {
+	s.breakpoint.source = nil
+	body := breakpointsBody{Breakpoints: make([]breakpoint, len(args.Breakpoints))}
+	for ix, requested := range args.Breakpoints {
+		bp := breakpoint{Id: s.nextBreakpointId(), Line: requested.Line}
+		file := args.Source.Path
+		if file == "" {
+			file = args.Source.Name
+		}
+		switch addresses := s.lineAddresses(file, requested.Line); {
+		case s.info == nil:
+			bp.Message = "Line breakpoints need Inform debug information, which isn't loaded"
+		case len(addresses) == 0:
+			bp.Message = fmt.Sprintf("No code on line %d", requested.Line)
+		default:
+			s.breakpoint.source = append(s.breakpoint.source, addresses...)
+			bp.Verified = true
+			bp.Source = &args.Source
+			bp.InstructionReference = reference(addresses[0])
+		}
+		body.Breakpoints[ix] = bp
+	}
+	s.syncBreakpoints()
+	return body
+}
+
+func (s *Session) lineAddresses(file string, line int) []uint32 {
+	if s.info == nil {
+		return nil
+	}
+	return s.info.Addresses(file, line)
+}
+
+// setFunctionBreakpoints takes routines by name, given debug information, or
+// by their address in hex, optionally written R1234 as the disassembler does
+func (s *Session) setFunctionBreakpoints(args setFunctionBreakpointsArguments) breakpointsBody {
+	s.breakpoint.function = nil
+	body := breakpointsBody{Breakpoints: make([]breakpoint, len(args.Breakpoints))}
+	for ix, requested := range args.Breakpoints {
+		bp := breakpoint{Id: s.nextBreakpointId()}
+		routine, err := parseReference(strings.TrimPrefix(strings.TrimPrefix(requested.Name, "R"), "r"))
+		if s.info != nil {
+			if named := s.info.RoutineNamed(requested.Name); named != nil {
+				routine, err = named.Address, nil
+			}
+		}
+		if err != nil || routine >= s.z.Core.MemoryLength() {
+			bp.Message = fmt.Sprintf("No routine %q", requested.Name)
+		} else {
+			address := debugger.RoutineEntry(&s.z.Core, routine)
+			s.breakpoint.function = append(s.breakpoint.function, address)
+			bp.Verified = true
+			bp.InstructionReference = reference(address)
+		}
+		body.Breakpoints[ix] = bp
+	}
+	s.syncBreakpoints()
+	return body
+}
+
+func (s *Session) setInstructionBreakpoints(args setInstructionBreakpointsArguments) breakpointsBody {
+	s.breakpoint.instruction = nil
+	body := breakpointsBody{Breakpoints: make([]breakpoint, len(args.Breakpoints))}
+	for ix, requested := range args.Breakpoints {
+		bp := breakpoint{Id: s.nextBreakpointId()}
+		address, err := parseReference(requested.InstructionReference)
+		address += uint32(requested.Offset)
+		if err != nil || address >= s.z.Core.MemoryLength() {
+			bp.Message = fmt.Sprintf("No instruction at %s%+d", requested.InstructionReference, requested.Offset)
+		} else {
+			s.breakpoint.instruction = append(s.breakpoint.instruction, address)
+			bp.Verified = true
+			bp.InstructionReference = reference(address)
+		}
+		body.Breakpoints[ix] = bp
+	}
+	s.syncBreakpoints()
+	return body
+}
+
+// dataBreakpointInfo allows globals to be watched, locals come and go with
+// their routines so aren't worth watching
+func (s *Session) dataBreakpointInfo(args dataBreakpointInfoArguments) dataBreakpointInfoBody {
+	if _, ok := s.parseGlobal(args.Name); !ok || args.VariablesReference != globalsReference {
+		return dataBreakpointInfoBody{Description: "Only globals can be watched"}
+	}
+	return dataBreakpointInfoBody{DataId: &args.Name, Description: "Writes to global " + args.Name, AccessTypes: []string{"write"}}
+}
+
+func (s *Session) setDataBreakpoints(args setDataBreakpointsArguments) breakpointsBody {
+	for _, id := range s.watchpoints {
+		s.debugger.Unwatch(id)
+	}
+	s.watchpoints = nil
+
+	body := breakpointsBody{Breakpoints: make([]breakpoint, len(args.Breakpoints))}
+	for ix, requested := range args.Breakpoints {
+		bp := breakpoint{Id: s.nextBreakpointId()}
+		if n, ok := s.parseGlobal(requested.DataId); ok {
+			s.watchpoints = append(s.watchpoints, s.debugger.WatchGlobal(n, false))
+			bp.Verified = true
+		} else {
+			bp.Message = fmt.Sprintf("Can't watch %q", requested.DataId)
+		}
+		body.Breakpoints[ix] = bp
+	}
+	return body
+}
+
+func (s *Session) routineName(frame zmachine.FrameInfo) string {
+	if s.info != nil {
+		if routine := s.info.RoutineAt(frame.PC); routine != nil {
+			return routine.Name
+		}
+	}
+	if frame.RoutineAddress == 0 {
+		return "main"
+	}
+	return fmt.Sprintf("R%04x", frame.RoutineAddress)
+}
+
+func (s *Session) stackTrace(args stackTraceArguments) stackTraceBody {
+	frames := s.z.CallFrames()
+	body := stackTraceBody{StackFrames: []stackFrame{}, TotalFrames: len(frames)}
+	for depth := max(args.StartFrame, 0); depth < len(frames); depth++ {
+		if args.Levels > 0 && len(body.StackFrames) == args.Levels {
+			break
+		}
+		ix := len(frames) - 1 - depth
+		frame := stackFrame{
+			Id:                          ix + 1,
+			Name:                        s.routineName(frames[ix]),
+			InstructionPointerReference: reference(frames[ix].PC),
+		}
+		if s.info != nil {
+			if location, ok := s.info.Location(frames[ix].PC); ok {
+				frame.Source = s.sourceOf(location)
+				frame.Line = location.Line
+				frame.Column = location.Column
+			}
+		}
+		body.StackFrames = append(body.StackFrames, frame)
+	}
+	return body
+}
+
+func (s *Session) scopes(args scopesArguments) (scopesBody, error) {
+	frames := s.z.CallFrames()
+	ix := args.FrameId - 1
+	if ix < 0 || ix >= len(frames) {
+		return scopesBody{}, fmt.Errorf("no frame %d", args.FrameId)
+	}
+	locals, stack := frameReferences(ix)
+	return scopesBody{Scopes: []scope{
+		{Name: "Locals", PresentationHint: "locals", VariablesReference: locals, NamedVariables: len(frames[ix].Locals)},
+		{Name: "Stack", VariablesReference: stack, NamedVariables: len(frames[ix].Stack)},
+		{Name: "Globals", VariablesReference: globalsReference, NamedVariables: 240},
+	}}, nil
+}
+
+// formatValue shows a word as signed decimal, the way most stories treat
+// numbers, and hex
+func formatValue(value uint16) string {
+	return fmt.Sprintf("%d (0x%04x)", int16(value), value)
+}
+
+func (s *Session) variables(args variablesArguments) (variablesBody, error) {
+	body := variablesBody{Variables: []variable{}}
+	if args.VariablesReference == globalsReference {
+		for n := range 240 {
+			body.Variables = append(body.Variables, variable{
+				Name:            s.globalName(uint8(n)),
+				Value:           formatValue(s.z.Global(uint8(n))),
+				MemoryReference: reference(uint32(s.z.Core.GlobalVariableBase) + 2*uint32(n)),
+			})
+		}
+		return body, nil
+	}
+
+	frames := s.z.CallFrames()
+	ix := (args.VariablesReference - 2) / 2
+	if args.VariablesReference < 2 || ix >= len(frames) {
+		return body, fmt.Errorf("no variables %d", args.VariablesReference)
+	}
+	if locals, _ := frameReferences(ix); args.VariablesReference == locals {
+		for n, value := range frames[ix].Locals {
+			body.Variables = append(body.Variables, variable{Name: s.localName(frames[ix], n), Value: formatValue(value)})
+		}
+	} else {
+		// Top of the stack first, as it's the one sp reads
+		stack := frames[ix].Stack
+		for n := len(stack) - 1; n >= 0; n-- {
+			body.Variables = append(body.Variables, variable{Name: fmt.Sprintf("[%d]", n), Value: formatValue(stack[n])})
+		}
+	}
+	return body, nil
+}
+
+// parseGlobal reads a global's name, G followed by its number in hex
+func parseGlobal(name string) (uint8, bool) {
+	if !strings.HasPrefix(name, "G") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(name[1:], 16, 8)
+	if err != nil || n > 239 {
+		return 0, false
+	}
+	return uint8(n), true
+}
+
+// parseLocal reads a local's name, L followed by its number in hex
+func parseLocal(name string) (int, bool) {
+	if !strings.HasPrefix(name, "L") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(name[1:], 16, 4)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// globalName is the global's name from the debug information if it has one
+func (s *Session) globalName(n uint8) string {
+	if s.info != nil && s.info.GlobalName(n) != "" {
+		return s.info.GlobalName(n)
+	}
+	return fmt.Sprintf("G%02x", n)
+}
+
+func (s *Session) localName(frame zmachine.FrameInfo, n int) string {
+	if s.info != nil && s.info.LocalName(frame.PC, uint8(n)) != "" {
+		return s.info.LocalName(frame.PC, uint8(n))
+	}
+	return fmt.Sprintf("L%02x", n)
+}
+
+// parseGlobal is parseGlobal also taking the names from debug information
+func (s *Session) parseGlobal(name string) (uint8, bool) {
+	for n := range 240 {
+		if s.info != nil && s.info.GlobalName(uint8(n)) == name {
+			return uint8(n), true
+		}
+	}
+	return parseGlobal(name)
+}
+
+// parseLocal is parseLocal also taking the names from debug information
+func (s *Session) parseLocal(frame zmachine.FrameInfo, name string) (int, bool) {
+	for n := range frame.Locals {
+		if s.info != nil && s.info.LocalName(frame.PC, uint8(n)) == name {
+			return n, true
+		}
+	}
+	return parseLocal(name)
+}
+
+// parseValue accepts decimal, negative or not, or 0x-prefixed hex
+func parseValue(value string) (uint16, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 0, 32)
+	if err != nil || n < -32768 || n > 65535 {
+		return 0, fmt.Errorf("%q isn't a number which fits in a word", value)
+	}
+	return uint16(n), nil
+}
+
+func (s *Session) setVariable(args setVariableArguments) (setVariableBody, error) {
+	value, err := parseValue(args.Value)
+	if err != nil {
+		return setVariableBody{}, err
+	}
+
+	if args.VariablesReference == globalsReference {
+		n, ok := s.parseGlobal(args.Name)
+		if !ok {
+			return setVariableBody{}, fmt.Errorf("no global %s", args.Name)
+		}
+		s.z.SetGlobal(n, value)
+		return setVariableBody{Value: formatValue(value)}, nil
+	}
+
+	frames := s.z.CallFrames()
+	ix := (args.VariablesReference - 2) / 2
+	locals, _ := frameReferences(ix)
+	if args.VariablesReference != locals || ix < 0 || ix >= len(frames) {
+		return setVariableBody{}, fmt.Errorf("%s can't be set", args.Name)
+	}
+	n, ok := s.parseLocal(frames[ix], args.Name)
+	if !ok || !s.z.SetLocal(ix, n, value) {
+		return setVariableBody{}, fmt.Errorf("%s can't be set", args.Name)
+	}
+	return setVariableBody{Value: formatValue(value)}, nil
+}
+
+// evaluate sends whatever is typed in the REPL to the story as input, other
+// expressions are a variable name: Gnn, Lnn, sp or a name from the debug
+// information
+func (s *Session) evaluate(args evaluateArguments) (evaluateBody, error) {
+	if args.Context == "repl" {
+		if s.input == nil || s.hasFinished() {
+			return evaluateBody{}, errors.New("the story has finished")
+		}
+		// Nothing may be reading the input, e.g. the story is about to quit
+		select {
+		case s.input <- args.Expression:
+			return evaluateBody{}, nil
+		default:
+			return evaluateBody{}, errors.New("too much input is already waiting for the story")
+		}
+	}
+
+	if !s.isStopped() {
+		return evaluateBody{}, errors.New("the story needs to be paused")
+	}
+
+	frames := s.z.CallFrames()
+	ix := len(frames) - 1
+	if args.FrameId != nil {
+		ix = *args.FrameId - 1
+	}
+	if ix < 0 || ix >= len(frames) {
+		return evaluateBody{}, errors.New("no such frame")
+	}
+
+	expression := strings.TrimSpace(args.Expression)
+	if n, ok := s.parseLocal(frames[ix], expression); ok && n < len(frames[ix].Locals) {
+		return evaluateBody{Result: formatValue(frames[ix].Locals[n])}, nil
+	}
+	if n, ok := s.parseGlobal(expression); ok {
+		return evaluateBody{Result: formatValue(s.z.Global(n))}, nil
+	}
+	if stack := frames[ix].Stack; expression == "sp" && len(stack) > 0 {
+		return evaluateBody{Result: formatValue(stack[len(stack)-1])}, nil
+	}
+	return evaluateBody{}, fmt.Errorf("can't evaluate %q", args.Expression)
+}
+
+func (s *Session) readMemory(args readMemoryArguments) (readMemoryBody, error) {
+	address, err := parseReference(args.MemoryReference)
+	if err != nil {
+		return readMemoryBody{}, err
+	}
+	start := int64(address) + int64(args.Offset)
+	length := int64(s.z.Core.MemoryLength())
+	if start < 0 || start >= length {
+		return readMemoryBody{Address: reference(uint32(max(start, 0))), UnreadableBytes: args.Count}, nil
+	}
+	end := min(start+int64(max(args.Count, 0)), length)
+	data := s.z.Core.ReadSlice(uint32(start), uint32(end))
+	return readMemoryBody{
+		Address:         reference(uint32(start)),
+		UnreadableBytes: args.Count - len(data),
+		Data:            base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// disassemble decodes forwards from the reference. Instructions have no
+// fixed length so ones before the reference can't be found, those asked for
+// are returned as invalid.
+func (s *Session) disassemble(args disassembleArguments) (disassembleBody, error) {
+	address, err := parseReference(args.MemoryReference)
+	if err != nil {
+		return disassembleBody{}, err
+	}
+	address += uint32(args.Offset)
+	if args.InstructionCount < 0 {
+		return disassembleBody{}, fmt.Errorf("can't disassemble %d instructions", args.InstructionCount)
+	}
+	args.InstructionCount = min(args.InstructionCount, maxDisassembledInstructions)
+
+	body := disassembleBody{Instructions: make([]disassembledInstruction, 0, args.InstructionCount)}
+	for ix := args.InstructionOffset; ix < 0 && len(body.Instructions) < args.InstructionCount; ix++ {
+		body.Instructions = append(body.Instructions, disassembledInstruction{
+			Address:          reference(uint32(max(int64(address)+int64(ix), 0))),
+			Instruction:      "??",
+			PresentationHint: "invalid",
+		})
+	}
+
+	skip := max(args.InstructionOffset, 0)
+	for len(body.Instructions) < args.InstructionCount {
+		next := disassembledInstruction{Address: reference(address), Instruction: "??", PresentationHint: "invalid"}
+		length := uint32(1)
+		if address < s.z.Core.MemoryLength() {
+			if instruction, err := zmachine.DecodeInstruction(&s.z.Core, s.z.Alphabets, address); err == nil {
+				if s.info != nil {
+					instruction.Annotate(s.info)
+				}
+				next = disassembledInstruction{
+					Address:          reference(address),
+					InstructionBytes: hex.EncodeToString(s.z.Core.ReadSlice(address, address+instruction.Length)),
+					Instruction:      instruction.String(),
+				}
+				s.annotateInstruction(&next, address)
+				length = instruction.Length
+			}
+		}
+
+		if skip > 0 {
+			skip--
+		} else {
+			body.Instructions = append(body.Instructions, next)
+		}
+		address += length
+	}
+	return body, nil
+}
+
+// annotateInstruction names the routine an instruction is in and where in the
+// source it came from
+func (s *Session) annotateInstruction(instruction *disassembledInstruction, address uint32) {
+	if s.info == nil {
+		return
+	}
+	if routine := s.info.RoutineAt(address); routine != nil {
+		instruction.Symbol = routine.Name
+	}
+	if location, ok := s.info.Location(address); ok {
+		instruction.Location = s.sourceOf(location)
+		instruction.Line = location.Line
+	}
+}
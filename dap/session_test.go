@@ -0,0 +1,217 @@
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient plays the part of an editor, every message from the server is
+// read in the background so the server never blocks writing
+type testClient struct {
+	t        *testing.T
+	w        io.Writer
+	seq      int
+	messages chan map[string]any
+	pending  []map[string]any
+	output   strings.Builder
+}
+
+func newTestClient(t *testing.T) *testClient {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, serverReader, serverWriter) }()
+	t.Cleanup(func() {
+		cancel()
+		clientWriter.Close() // nolint:errcheck
+		if err := <-served; err != nil {
+			t.Errorf("unexpected error from Serve %v", err)
+		}
+		serverWriter.Close() // nolint:errcheck
+	})
+
+	c := &testClient{t: t, w: clientWriter, messages: make(chan map[string]any, 1000)}
+	go func() {
+		reader := bufio.NewReader(clientReader)
+		for {
+			body, err := readMessage(reader)
+			if err != nil {
+				close(c.messages)
+				return
+			}
+			var message map[string]any
+			json.Unmarshal(body, &message) // nolint:errcheck
+			c.messages <- message
+		}
+	}()
+	return c
+}
+
+func (c *testClient) send(command string, arguments any) {
+	c.seq++
+	body, _ := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": arguments})
+	fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// expect waits for a message matching, story output on the way is kept
+func (c *testClient) expect(description string, matches func(map[string]any) bool) map[string]any {
+	c.t.Helper()
+	for ix, message := range c.pending {
+		if matches(message) {
+			c.pending = append(c.pending[:ix], c.pending[ix+1:]...)
+			return message
+		}
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case message, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("server closed the connection waiting for %s", description)
+			}
+			if message["event"] == "output" {
+				c.output.WriteString(message["body"].(map[string]any)["output"].(string))
+			}
+			if matches(message) {
+				return message
+			}
+			c.pending = append(c.pending, message)
+		case <-timeout:
+			c.t.Fatalf("timed out waiting for %s", description)
+		}
+	}
+}
+
+// request sends a request and returns the body of its successful response
+func (c *testClient) request(command string, arguments any) map[string]any {
+	c.t.Helper()
+	c.send(command, arguments)
+	seq := float64(c.seq)
+	response := c.expect("response to "+command, func(m map[string]any) bool {
+		return m["type"] == "response" && m["request_seq"] == seq
+	})
+	if response["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, response["message"])
+	}
+	body, _ := response["body"].(map[string]any)
+	return body
+}
+
+func (c *testClient) event(name string) map[string]any {
+	c.t.Helper()
+	event := c.expect(name+" event", func(m map[string]any) bool { return m["event"] == name })
+	body, _ := event["body"].(map[string]any)
+	return body
+}
+
+func (c *testClient) stopped(reason string) {
+	c.t.Helper()
+	if body := c.event("stopped"); body["reason"] != reason {
+		c.t.Fatalf("expected to stop for %s, got %v", reason, body)
+	}
+}
+
+// topFrame returns the id and name of the innermost frame and where it is
+func (c *testClient) topFrame() (float64, string, string) {
+	c.t.Helper()
+	frames := c.request("stackTrace", map[string]any{"threadId": threadId})["stackFrames"].([]any)
+	top := frames[0].(map[string]any)
+	return top["id"].(float64), top["name"].(string), top["instructionPointerReference"].(string)
+}
+
+func TestDebugSession(t *testing.T) {
+	c := newTestClient(t)
+
+	if capabilities := c.request("initialize", map[string]any{"adapterID": "goz"}); capabilities["supportsInstructionBreakpoints"] != true {
+		t.Errorf("expected instruction breakpoints to be supported, got %v", capabilities)
+	}
+	c.request("launch", map[string]any{"program": "../advent.z3", "stopOnEntry": true})
+	c.event("initialized")
+
+	breakpoints := c.request("setInstructionBreakpoints", map[string]any{
+		"breakpoints": []any{map[string]any{"instructionReference": "0x4509"}},
+	})["breakpoints"].([]any)
+	if breakpoints[0].(map[string]any)["verified"] != true {
+		t.Errorf("expected breakpoint to be verified, got %v", breakpoints)
+	}
+	c.request("configurationDone", nil)
+	c.stopped("entry")
+
+	c.request("continue", map[string]any{"threadId": threadId})
+	c.stopped("breakpoint")
+	if _, name, pc := c.topFrame(); name != "main" || pc != "0x4509" {
+		t.Errorf("expected to stop in main at 0x4509, got %s at %s", name, pc)
+	}
+
+	disassembly := c.request("disassemble", map[string]any{"memoryReference": "0x4509", "instructionCount": 2})["instructions"].([]any)
+	if instruction := disassembly[0].(map[string]any)["instruction"]; instruction != "call R6be6 -> sp" {
+		t.Errorf("expected call R6be6 -> sp at 0x4509, got %v", instruction)
+	}
+
+	c.send("disassemble", map[string]any{"memoryReference": "0x4509", "instructionCount": -1})
+	seq := float64(c.seq)
+	if response := c.expect("response to disassemble", func(m map[string]any) bool {
+		return m["type"] == "response" && m["request_seq"] == seq
+	}); response["success"] != false {
+		t.Errorf("expected a negative instruction count to fail, got %v", response)
+	}
+	if huge := c.request("disassemble", map[string]any{"memoryReference": "0x4509", "instructionCount": 1 << 40})["instructions"].([]any); len(huge) != maxDisassembledInstructions {
+		t.Errorf("expected at most %d instructions, got %d", maxDisassembledInstructions, len(huge))
+	}
+
+	c.request("stepIn", map[string]any{"threadId": threadId})
+	c.stopped("step")
+	frameId, name, pc := c.topFrame()
+	if name != "R6be6" || pc != "0x6be9" {
+		t.Errorf("expected to step into R6be6 at 0x6be9, got %s at %s", name, pc)
+	}
+
+	scopes := c.request("scopes", map[string]any{"frameId": frameId})["scopes"].([]any)
+	locals := c.request("variables", map[string]any{"variablesReference": scopes[0].(map[string]any)["variablesReference"]})["variables"].([]any)
+	if len(locals) != 1 || locals[0].(map[string]any)["name"] != "L00" {
+		t.Errorf("expected R6be6 to have one local, got %v", locals)
+	}
+
+	if result := c.request("evaluate", map[string]any{"expression": "G00", "context": "watch"})["result"]; result != "219 (0x00db)" {
+		t.Errorf("expected G00 to be 219, got %v", result)
+	}
+	c.request("setVariable", map[string]any{"variablesReference": globalsReference, "name": "G01", "value": "0x10"})
+	if result := c.request("evaluate", map[string]any{"expression": "G01", "context": "hover"})["result"]; result != "16 (0x0010)" {
+		t.Errorf("expected G01 to have been set to 16, got %v", result)
+	}
+
+	c.request("setInstructionBreakpoints", map[string]any{"breakpoints": []any{}})
+	c.request("continue", map[string]any{"threadId": threadId})
+	for _, line := range []string{"no", "quit", "y"} {
+		c.request("evaluate", map[string]any{"expression": line, "context": "repl"})
+	}
+	c.event("exited")
+	c.event("terminated")
+
+	// Nothing reads input once the story has quit, the REPL mustn't block
+	for range 100 {
+		c.send("evaluate", map[string]any{"expression": "look", "context": "repl"})
+		seq := float64(c.seq)
+		if response := c.expect("response to evaluate", func(m map[string]any) bool {
+			return m["type"] == "response" && m["request_seq"] == seq
+		}); response["success"] != false {
+			t.Fatalf("expected input after the story quit to fail, got %v", response)
+		}
+	}
+	c.request("disconnect", nil)
+
+	for _, expected := range []string{"Welcome to Adventure!", "You are standing at the end of a road", "Are you sure you want to quit?"} {
+		if !strings.Contains(c.output.String(), expected) {
+			t.Errorf("missing %q in story output %q", expected, c.output.String())
+		}
+	}
+}
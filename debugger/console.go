@@ -0,0 +1,458 @@
+package debugger
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/davetcode/goz/debuginfo"
+	"github.com/davetcode/goz/zmachine"
+	"github.com/davetcode/goz/zobject"
+)
+
+const consoleHelp = `Commands, addresses and values are hex and object numbers decimal. With
+debug information routines and globals can be given by name:
+  break addr          pause before the instruction at addr (b)
+  break file:line     pause before each instruction starting the source line
+  rbreak addr         pause on entry to the routine starting at addr
+  delete addr         remove the breakpoint at addr
+  breakpoints         list breakpoints
+  watch memory addr [length]  pause after any of length bytes from addr are written
+  watch global n              pause after global n is written
+  watch attribute obj attr    pause after an attribute of obj is set or cleared
+  watch parent obj            pause after obj is moved
+  log ...             the same as watch but only report the change
+  unwatch id          remove a watchpoint or log
+  watchpoints         list watchpoints and logs
+  step                run one instruction, following calls (s)
+  next                run one instruction, running calls to completion (n)
+  finish              run until the current routine returns
+  continue            run until the next breakpoint or watchpoint (c)
+  bt                  show the call stack with each frame's locals and stack
+  global n [value]    show or set global n, numbered from 0 (g)
+  object n            show object n with its attributes and properties (o)
+  x addr [length]     show length bytes of memory from addr, default 16
+  quit                stop the story (q)
+`
+
+// Console is a command line debugger, it prompts for commands each time the
+// machine pauses until one of them carries on running
+type Console struct {
+	*Debugger
+	readLine func() (string, error)
+	out      io.Writer
+	info     *debuginfo.Info // nil without debug information
+}
+
+// NewConsole attaches a command line debugger to z which pauses before the
+// first instruction. readLine returns the next command typed.
+func NewConsole(z *zmachine.ZMachine, readLine func() (string, error), out io.Writer) *Console {
+	c := &Console{readLine: readLine, out: out}
+	c.Debugger = New(z, c.prompt)
+	c.SetWatchLogger(func(hit WatchHit) { fmt.Fprintln(c.out, hit) })
+	c.Pause()
+	return c
+}
+
+// SetDebugInfo lets routines, globals and source lines be given by name and
+// names them when showing frames and instructions
+func (c *Console) SetDebugInfo(info *debuginfo.Info) {
+	c.info = info
+}
+
+// prompt runs commands until one resumes the machine
+func (c *Console) prompt(stop Stop) error {
+	if stop.Reason == StopBreakpoint {
+		fmt.Fprintf(c.out, "Breakpoint at %06x\n", stop.PC)
+	}
+	for _, hit := range stop.Hits {
+		fmt.Fprintln(c.out, hit)
+	}
+	c.showInstruction(stop.PC)
+
+	for {
+		fmt.Fprint(c.out, "(debug) ")
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		resume, err := c.command(fields[0], fields[1:])
+		if err != nil {
+			return err
+		}
+		if resume {
+			return nil
+		}
+	}
+}
+
+// command runs a single command, returning true if the machine should carry
+// on running
+func (c *Console) command(name string, args []string) (bool, error) {
+	z := c.Machine()
+	switch name {
+	case "step", "s":
+		c.Step()
+		return true, nil
+
+	case "next", "n":
+		c.StepOver()
+		return true, nil
+
+	case "finish":
+		c.StepOut()
+		return true, nil
+
+	case "continue", "c":
+		c.Continue()
+		return true, nil
+
+	case "quit", "q":
+		return false, ErrQuit
+
+	case "break", "b":
+		if c.info != nil && len(args) > 0 && strings.Contains(args[0], ":") {
+			c.breakOnLine(args[0])
+			break
+		}
+		fallthrough
+
+	case "rbreak", "delete":
+		address, ok := c.routineArgument(args, 0)
+		if !ok {
+			break
+		}
+		switch name {
+		case "rbreak":
+			fmt.Fprintf(c.out, "Breakpoint at %06x\n", c.AddRoutineBreakpoint(address))
+		case "delete":
+			if !c.RemoveBreakpoint(address) {
+				fmt.Fprintf(c.out, "No breakpoint at %06x\n", address)
+			}
+		default:
+			c.AddBreakpoint(address)
+			fmt.Fprintf(c.out, "Breakpoint at %06x\n", address)
+		}
+
+	case "breakpoints":
+		breakpoints := c.Breakpoints()
+		slices.Sort(breakpoints)
+		for _, address := range breakpoints {
+			fmt.Fprintf(c.out, "%06x\n", address)
+		}
+
+	case "watch", "log":
+		c.watch(args, name == "log")
+
+	case "unwatch":
+		if len(args) == 0 {
+			fmt.Fprintf(c.out, "Missing watchpoint id\n")
+			break
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil || !c.Unwatch(id) {
+			fmt.Fprintf(c.out, "No watchpoint %s\n", args[0])
+		}
+
+	case "watchpoints":
+		watchpoints := c.Watchpoints()
+		for _, id := range slices.Sorted(maps.Keys(watchpoints)) {
+			w := watchpoints[id]
+			action := "watch"
+			if w.LogOnly {
+				action = "log"
+			}
+			fmt.Fprintf(c.out, "%d: %s %s\n", id, action, w)
+		}
+
+	case "bt", "backtrace":
+		frames := z.CallFrames()
+		for ix := len(frames) - 1; ix >= 0; ix-- {
+			c.showFrame(len(frames)-1-ix, frames[ix])
+		}
+
+	case "global", "g":
+		n, ok := c.globalArgument(args, 0)
+		if !ok {
+			break
+		}
+		if n > 239 {
+			fmt.Fprintf(c.out, "There are only 240 globals\n")
+			break
+		}
+		if len(args) > 1 {
+			value, ok := c.hexArgument(args, 1, "value")
+			if !ok {
+				break
+			}
+			z.SetGlobal(uint8(n), uint16(value))
+		}
+		fmt.Fprintf(c.out, "%s = %04x\n", c.globalName(uint8(n)), z.Global(uint8(n)))
+
+	case "object", "o":
+		if id, ok := c.objectArgument(args, 0); ok {
+			c.showObject(id)
+		}
+
+	case "x", "memory":
+		address, ok := c.hexArgument(args, 0, "address")
+		if !ok {
+			break
+		}
+		length := uint32(16)
+		if len(args) > 1 {
+			if length, ok = c.hexArgument(args, 1, "length"); !ok {
+				break
+			}
+		}
+		end := min(address+length, z.Core.MemoryLength())
+		if address >= end {
+			fmt.Fprintf(c.out, "Address %x is outside memory\n", address)
+			break
+		}
+		for row := address; row < end; row += 16 {
+			fmt.Fprintf(c.out, "%06x  % x\n", row, z.Core.ReadSlice(row, min(row+16, end)))
+		}
+
+	case "help", "h", "?":
+		fmt.Fprint(c.out, consoleHelp)
+
+	default:
+		fmt.Fprintf(c.out, "Unknown command %q, try help\n", name)
+	}
+
+	return false, nil
+}
+
+// watch adds a watchpoint from the arguments to the watch and log commands
+func (c *Console) watch(args []string, logOnly bool) {
+	if len(args) == 0 {
+		fmt.Fprintf(c.out, "Watch memory, global, attribute or parent?\n")
+		return
+	}
+
+	var id int
+	switch args[0] {
+	case "memory":
+		address, ok := c.hexArgument(args, 1, "address")
+		if !ok {
+			return
+		}
+		length := uint32(1)
+		if len(args) > 2 {
+			if length, ok = c.hexArgument(args, 2, "length"); !ok {
+				return
+			}
+		}
+		id = c.WatchMemory(address, length, logOnly)
+	case "global":
+		n, ok := c.hexArgument(args, 1, "global number")
+		if !ok {
+			return
+		}
+		if n > 239 {
+			fmt.Fprintf(c.out, "There are only 240 globals\n")
+			return
+		}
+		id = c.WatchGlobal(uint8(n), logOnly)
+	case "attribute":
+		object, ok := c.objectArgument(args, 1)
+		if !ok {
+			return
+		}
+		if len(args) < 3 {
+			fmt.Fprintf(c.out, "Missing attribute\n")
+			return
+		}
+		attribute, err := strconv.ParseUint(args[2], 10, 16)
+		if err != nil {
+			fmt.Fprintf(c.out, "Invalid attribute %q\n", args[2])
+			return
+		}
+		id = c.WatchAttribute(object, uint16(attribute), logOnly)
+	case "parent":
+		object, ok := c.objectArgument(args, 1)
+		if !ok {
+			return
+		}
+		id = c.WatchParent(object, logOnly)
+	default:
+		fmt.Fprintf(c.out, "Can't watch %q\n", args[0])
+		return
+	}
+	fmt.Fprintf(c.out, "Watchpoint %d, %s\n", id, c.Watchpoints()[id])
+}
+
+// objectArgument parses args[ix] as an object number, telling the player
+// what was wrong if it isn't one
+func (c *Console) objectArgument(args []string, ix int) (uint16, bool) {
+	if ix >= len(args) {
+		fmt.Fprintf(c.out, "Missing object number\n")
+		return 0, false
+	}
+	id, err := strconv.ParseUint(args[ix], 10, 16)
+	if err != nil || id == 0 || int(id) > zobject.Count(&c.Machine().Core) {
+		fmt.Fprintf(c.out, "No object %s\n", args[ix])
+		return 0, false
+	}
+	return uint16(id), true
+}
+
+// routineArgument is hexArgument for addresses which can also be given as
+// the name of a routine
+func (c *Console) routineArgument(args []string, ix int) (uint32, bool) {
+	if c.info != nil && ix < len(args) {
+		if routine := c.info.RoutineNamed(args[ix]); routine != nil {
+			return routine.Address, true
+		}
+	}
+	return c.hexArgument(args, ix, "address")
+}
+
+// globalArgument is hexArgument for globals which can also be given by name
+func (c *Console) globalArgument(args []string, ix int) (uint32, bool) {
+	if c.info != nil && ix < len(args) {
+		for n := range 240 {
+			if c.info.GlobalName(uint8(n)) == args[ix] {
+				return uint32(n), true
+			}
+		}
+	}
+	return c.hexArgument(args, ix, "global number")
+}
+
+func (c *Console) globalName(n uint8) string {
+	if c.info != nil {
+		if name := c.info.GlobalName(n); name != "" {
+			return name
+		}
+	}
+	return fmt.Sprintf("G%02x", n)
+}
+
+// breakOnLine adds a breakpoint on every instruction starting file:line
+func (c *Console) breakOnLine(arg string) {
+	separator := strings.LastIndex(arg, ":")
+	line, err := strconv.Atoi(arg[separator+1:])
+	if err != nil {
+		fmt.Fprintf(c.out, "Invalid line %q\n", arg[separator+1:])
+		return
+	}
+	addresses := c.info.Addresses(arg[:separator], line)
+	if len(addresses) == 0 {
+		fmt.Fprintf(c.out, "No code on %s\n", arg)
+		return
+	}
+	for _, address := range addresses {
+		c.AddBreakpoint(address)
+		fmt.Fprintf(c.out, "Breakpoint at %06x\n", address)
+	}
+}
+
+// hexArgument parses args[ix] as hex, telling the player what was wrong if
+// it's missing or not a number
+func (c *Console) hexArgument(args []string, ix int, name string) (uint32, bool) {
+	if ix >= len(args) {
+		fmt.Fprintf(c.out, "Missing %s\n", name)
+		return 0, false
+	}
+	value, err := strconv.ParseUint(strings.TrimPrefix(args[ix], "0x"), 16, 32)
+	if err != nil {
+		fmt.Fprintf(c.out, "Invalid %s %q\n", name, args[ix])
+		return 0, false
+	}
+	return uint32(value), true
+}
+
+func (c *Console) showInstruction(pc uint32) {
+	z := c.Machine()
+	instruction, err := zmachine.DecodeInstruction(&z.Core, z.Alphabets, pc)
+	if err != nil {
+		fmt.Fprintf(c.out, "%06x: %v\n", pc, err)
+		return
+	}
+	if c.info != nil {
+		instruction.Annotate(c.info)
+		if description := c.info.Describe(pc); description != "" {
+			fmt.Fprintf(c.out, "%s\n", description)
+		}
+	}
+	fmt.Fprintf(c.out, "%06x: %s\n", pc, instruction)
+}
+
+func (c *Console) showFrame(number int, frame zmachine.FrameInfo) {
+	routine := "main"
+	if frame.RoutineAddress != 0 {
+		routine = fmt.Sprintf("R%04x", frame.RoutineAddress)
+	}
+	if c.info != nil {
+		if description := c.info.Describe(frame.PC); description != "" {
+			routine = description
+		}
+	}
+	fmt.Fprintf(c.out, "#%d %s at %06x (%s)\n", number, routine, frame.PC, frame.Kind)
+
+	if len(frame.Locals) > 0 {
+		locals := make([]string, len(frame.Locals))
+		for ix, local := range frame.Locals {
+			name := fmt.Sprintf("L%02x", ix)
+			if c.info != nil && c.info.LocalName(frame.PC, uint8(ix)) != "" {
+				name = c.info.LocalName(frame.PC, uint8(ix))
+			}
+			locals[ix] = fmt.Sprintf("%s=%04x", name, local)
+		}
+		fmt.Fprintf(c.out, "    locals %s\n", strings.Join(locals, " "))
+	}
+
+	if len(frame.Stack) > 0 {
+		stack := make([]string, len(frame.Stack))
+		for ix, value := range frame.Stack {
+			stack[ix] = fmt.Sprintf("%04x", value)
+		}
+		fmt.Fprintf(c.out, "    stack  %s\n", strings.Join(stack, " "))
+	}
+}
+
+func (c *Console) showObject(id uint16) {
+	z := c.Machine()
+	attributeCount := uint16(32)
+	if z.Core.Version >= 4 {
+		attributeCount = 48
+	}
+
+	obj := zobject.GetObject(id, &z.Core, z.Alphabets)
+	fmt.Fprintf(c.out, "%d %q parent %d sibling %d child %d\n", obj.Id, obj.Name, obj.Parent, obj.Sibling, obj.Child)
+
+	var attributes []string
+	for attribute := range attributeCount {
+		if obj.TestAttribute(attribute) {
+			name := strconv.Itoa(int(attribute))
+			if c.info != nil && c.info.AttributeName(attribute) != "" {
+				name = c.info.AttributeName(attribute)
+			}
+			attributes = append(attributes, name)
+		}
+	}
+	fmt.Fprintf(c.out, "    attributes %s\n", strings.Join(attributes, " "))
+
+	propertyId, err := obj.GetNextProperty(0, &z.Core)
+	for err == nil && propertyId != 0 {
+		property := obj.GetProperty(propertyId, &z.Core)
+		name := strconv.Itoa(int(property.Id))
+		if c.info != nil && c.info.PropertyName(uint16(property.Id)) != "" {
+			name = c.info.PropertyName(uint16(property.Id))
+		}
+		fmt.Fprintf(c.out, "    property %s: %s\n", name, hex.EncodeToString(property.Data))
+		propertyId, err = obj.GetNextProperty(propertyId, &z.Core)
+	}
+}
@@ -0,0 +1,204 @@
+// Package debugger pauses a running story at breakpoints or after stepping
+// so that its call stack, variables, objects and memory can be inspected.
+// The Debugger does the pausing, whatever it reports stops to decides what
+// to do while paused, see Console for a command line one.
+package debugger
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/davetcode/goz/zcore"
+	"github.com/davetcode/goz/zmachine"
+)
+
+// ErrQuit is returned from RunContext when the player quits from the debugger
+var ErrQuit = errors.New("quit from the debugger")
+
+// StopReason says why the debugger paused the machine
+type StopReason string
+
+const (
+	StopBreakpoint StopReason = "breakpoint"
+	StopStep       StopReason = "step"
+	StopPause      StopReason = "pause"
+	StopWatch      StopReason = "watchpoint"
+)
+
+// Stop is passed to the stop handler each time the machine pauses
+type Stop struct {
+	Reason StopReason
+	PC     uint32     // The next instruction to run
+	Hits   []WatchHit // Why a watchpoint stopped the machine
+}
+
+type mode int
+
+const (
+	running  mode = iota
+	stepInto mode = iota // Stop at the next instruction
+	stepOver mode = iota // Stop at the next instruction no deeper than depth
+	stepOut  mode = iota // Stop at the next instruction shallower than depth
+	paused   mode = iota // Stop at the next instruction, reported as a pause
+)
+
+// Debugger is safe to use from other goroutines while the machine runs, for
+// example to set breakpoints or pause it
+type Debugger struct {
+	z      *zmachine.ZMachine
+	onStop func(Stop) error
+
+	mu          sync.Mutex // Guards everything below
+	breakpoints map[uint32]bool
+	mode        mode
+	depth       int // Call depth when the current step started
+	watchpoints map[int]Watchpoint
+	nextWatchId int
+	pendingHits []WatchHit // Hits in the instruction just run, reported before the next
+	logHit      func(WatchHit)
+}
+
+// New attaches a debugger to z. onStop is called each time the machine
+// pauses and the machine carries on once it returns, it decides how to carry
+// on by calling Step, StepOver, StepOut or Continue first. Returning an error
+// stops the machine.
+func New(z *zmachine.ZMachine, onStop func(Stop) error) *Debugger {
+	d := &Debugger{
+		z:           z,
+		onStop:      onStop,
+		breakpoints: make(map[uint32]bool),
+		watchpoints: make(map[int]Watchpoint),
+	}
+	z.SetDebugHook(d.beforeInstruction)
+	z.SetMemoryHook(d.memoryWritten)
+	z.SetObjectHook(d.objectChanged)
+	return d
+}
+
+// Machine returns the machine being debugged
+func (d *Debugger) Machine() *zmachine.ZMachine {
+	return d.z
+}
+
+// AddBreakpoint pauses before the instruction at address
+func (d *Debugger) AddBreakpoint(address uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints[address] = true
+}
+
+// AddRoutineBreakpoint pauses before the first instruction of the routine
+// starting at address, returning the address of that instruction
+func (d *Debugger) AddRoutineBreakpoint(routine uint32) uint32 {
+	address := RoutineEntry(&d.z.Core, routine)
+	d.AddBreakpoint(address)
+	return address
+}
+
+// RoutineEntry returns the address of the first instruction of the routine
+// starting at routine
+func RoutineEntry(core *zcore.Core, routine uint32) uint32 {
+	address := routine + 1
+	if core.Version < 5 {
+		// Initial values for the locals come before the code
+		address += 2 * uint32(core.ReadZByte(routine))
+	}
+	return address
+}
+
+// RemoveBreakpoint returns false if there wasn't a breakpoint at address
+func (d *Debugger) RemoveBreakpoint(address uint32) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.breakpoints[address] {
+		return false
+	}
+	delete(d.breakpoints, address)
+	return true
+}
+
+// Breakpoints returns the address of every breakpoint in no particular order
+func (d *Debugger) Breakpoints() []uint32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	addresses := make([]uint32, 0, len(d.breakpoints))
+	for address := range d.breakpoints {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// ClearBreakpoints removes every breakpoint
+func (d *Debugger) ClearBreakpoints() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	clear(d.breakpoints)
+}
+
+// Step pauses again before the next instruction, wherever it is
+func (d *Debugger) Step() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mode = stepInto
+}
+
+// StepOver pauses again before the next instruction in the current routine,
+// or its caller if it returns, running any calls in between
+func (d *Debugger) StepOver() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mode = stepOver
+	d.depth = d.z.CallDepth()
+}
+
+// StepOut pauses again once the current routine has returned
+func (d *Debugger) StepOut() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mode = stepOut
+	d.depth = d.z.CallDepth()
+}
+
+// Continue runs until the next breakpoint or watchpoint
+func (d *Debugger) Continue() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mode = running
+}
+
+// Pause stops before the next instruction, use it before running the story
+// to stop at the first one or while it's running to interrupt it
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mode = paused
+}
+
+func (d *Debugger) beforeInstruction() error {
+	pc := d.z.PC()
+	depth := d.z.CallDepth()
+
+	d.mu.Lock()
+	var reason StopReason
+	hits := d.pendingHits
+	d.pendingHits = nil
+	switch {
+	case d.mode == paused:
+		reason = StopPause
+	case len(hits) > 0:
+		reason = StopWatch
+	case d.breakpoints[pc]:
+		reason = StopBreakpoint
+	case d.mode == stepInto,
+		d.mode == stepOver && depth <= d.depth,
+		d.mode == stepOut && depth < d.depth:
+		reason = StopStep
+	default:
+		d.mu.Unlock()
+		return nil
+	}
+
+	d.mode = running
+	d.mu.Unlock()
+	return d.onStop(Stop{Reason: reason, PC: pc, Hits: hits})
+}
@@ -0,0 +1,134 @@
+package debugger
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/davetcode/goz/dumbterminal"
+	"github.com/davetcode/goz/zmachine"
+)
+
+func TestBreakpointStepIntoAndOut(t *testing.T) {
+	romFileBytes, err := os.ReadFile("../advent.z3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut strings.Builder
+	frontend := dumbterminal.New("../advent.z3", strings.NewReader("no\nquit\ny\n"), &out, &errOut)
+	z := zmachine.LoadRomWithFrontend(romFileBytes, frontend)
+
+	var stops []Stop
+	var d *Debugger
+	d = New(z, func(stop Stop) error {
+		stops = append(stops, stop)
+		switch len(stops) {
+		case 1:
+			if depth := len(z.CallFrames()); depth != 1 {
+				t.Errorf("expected to stop in the main routine, call stack is %d deep", depth)
+			}
+			d.Step()
+		case 2:
+			frames := z.CallFrames()
+			if len(frames) != 2 || frames[1].RoutineAddress != 0x6be6 || len(frames[1].Locals) != 1 {
+				t.Errorf("expected to be in routine 6be6 with one local, got %+v", frames)
+			}
+			d.StepOut()
+		default:
+			return ErrQuit
+		}
+		return nil
+	})
+	d.AddBreakpoint(0x4509) // call R6be6 -> sp in the main routine
+
+	if err := frontend.Run(context.Background(), z); !errors.Is(err, ErrQuit) {
+		t.Fatalf("expected the debugger to quit, got %v", err)
+	}
+
+	expected := []Stop{
+		{Reason: StopBreakpoint, PC: 0x4509},
+		{Reason: StopStep, PC: 0x6be9},
+		{Reason: StopStep, PC: 0x450e},
+	}
+	if len(stops) != len(expected) {
+		t.Fatalf("expected stops %+v, got %+v", expected, stops)
+	}
+	for ix := range expected {
+		if stops[ix].Reason != expected[ix].Reason || stops[ix].PC != expected[ix].PC {
+			t.Errorf("stop %d: expected %+v, got %+v", ix, expected[ix], stops[ix])
+		}
+	}
+}
+
+func TestRoutineBreakpointSkipsInitialLocals(t *testing.T) {
+	romFileBytes, err := os.ReadFile("../advent.z3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z := zmachine.LoadRomWithFrontend(romFileBytes, nil)
+	d := New(z, func(Stop) error { return nil })
+	if address := d.AddRoutineBreakpoint(0x6be6); address != 0x6be9 {
+		t.Errorf("expected the first instruction of 6be6 at 6be9, got %x", address)
+	}
+}
+
+func TestGlobalWatchpointPausesAfterTheWrite(t *testing.T) {
+	romFileBytes, err := os.ReadFile("../advent.z3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut strings.Builder
+	frontend := dumbterminal.New("../advent.z3", strings.NewReader("no\nquit\ny\n"), &out, &errOut)
+	z := zmachine.LoadRomWithFrontend(romFileBytes, frontend)
+
+	var stop Stop
+	d := New(z, func(s Stop) error {
+		stop = s
+		return ErrQuit
+	})
+	d.WatchGlobal(0, false)
+
+	if err := frontend.Run(context.Background(), z); !errors.Is(err, ErrQuit) {
+		t.Fatalf("expected the debugger to quit, got %v", err)
+	}
+
+	// The main routine starts with store #10 #db
+	if stop.Reason != StopWatch || stop.PC != 0x44e6 || len(stop.Hits) != 1 {
+		t.Fatalf("expected to stop after the first instruction for the watchpoint, got %+v", stop)
+	}
+	hit := stop.Hits[0]
+	if hit.PC != 0x44e3 || hit.Old != 0 || hit.New != 0xdb || hit.Size != 2 {
+		t.Errorf("expected store at 44e3 to change G00 from 0 to db, got %+v", hit)
+	}
+}
+
+func TestObjectWatchpointsLogChanges(t *testing.T) {
+	romFileBytes, err := os.ReadFile("../zork1.z1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z := zmachine.LoadRomWithFrontend(romFileBytes, nil)
+	d := New(z, func(Stop) error { return nil })
+	var hits []string
+	d.SetWatchLogger(func(hit WatchHit) { hits = append(hits, hit.String()) })
+	d.WatchParent(252, true)
+	d.WatchParent(199, true)
+
+	z.MoveObject(252, 4) // The player from West of House to the forest
+	z.RemoveObject(252)
+	z.MoveObject(199, 35) // Mailbox is already in West of House, the parent doesn't change
+
+	expected := []string{
+		"Watchpoint 1, object 252 parent: 35 -> 4 at 000000",
+		"Watchpoint 1, object 252 parent: 4 -> 0 at 000000",
+	}
+	if strings.Join(hits, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected hits\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(hits, "\n"))
+	}
+}
@@ -0,0 +1,174 @@
+package debugger
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+// WatchKind says what a watchpoint is watching
+type WatchKind string
+
+const (
+	WatchMemory    WatchKind = "memory"    // Writes to a range of memory
+	WatchGlobal    WatchKind = "global"    // Writes to a global variable
+	WatchAttribute WatchKind = "attribute" // An object attribute being set or cleared
+	WatchParent    WatchKind = "parent"    // An object moving to a new parent
+)
+
+// Watchpoint pauses the machine, or logs, when something is changed
+type Watchpoint struct {
+	Kind      WatchKind
+	Address   uint32 // First byte watched, for memory and globals
+	Length    uint32 // Bytes watched from Address, for memory and globals
+	Global    uint8  // Numbered from 0
+	Object    uint16
+	Attribute uint16
+	LogOnly   bool // Report hits to the logger without pausing
+}
+
+func (w Watchpoint) String() string {
+	switch w.Kind {
+	case WatchMemory:
+		return fmt.Sprintf("memory %06x-%06x", w.Address, w.Address+w.Length-1)
+	case WatchGlobal:
+		return fmt.Sprintf("global G%02x", w.Global)
+	case WatchAttribute:
+		return fmt.Sprintf("object %d attribute %d", w.Object, w.Attribute)
+	default:
+		return fmt.Sprintf("object %d parent", w.Object)
+	}
+}
+
+// WatchHit is a change seen by a watchpoint
+type WatchHit struct {
+	Id         int // The watchpoint's id, as returned from Watch
+	Watchpoint Watchpoint
+	PC         uint32 // The instruction which made the change
+	Address    uint32 // Where the write was, for memory and globals
+	Size       int    // Bytes written, for memory and globals
+	Old        uint32
+	New        uint32
+}
+
+func (h WatchHit) String() string {
+	switch h.Watchpoint.Kind {
+	case WatchMemory:
+		digits := 2 * h.Size
+		return fmt.Sprintf("Watchpoint %d, %s: %06x changed %0*x -> %0*x at %06x", h.Id, h.Watchpoint, h.Address, digits, h.Old, digits, h.New, h.PC)
+	case WatchParent:
+		return fmt.Sprintf("Watchpoint %d, %s: %d -> %d at %06x", h.Id, h.Watchpoint, h.Old, h.New, h.PC)
+	default:
+		return fmt.Sprintf("Watchpoint %d, %s: %04x -> %04x at %06x", h.Id, h.Watchpoint, h.Old, h.New, h.PC)
+	}
+}
+
+// WatchMemory watches writes to length bytes from address
+func (d *Debugger) WatchMemory(address uint32, length uint32, logOnly bool) int {
+	return d.Watch(Watchpoint{Kind: WatchMemory, Address: address, Length: max(length, 1), LogOnly: logOnly})
+}
+
+// WatchGlobal watches writes to global n, numbered from 0, however they're made
+func (d *Debugger) WatchGlobal(n uint8, logOnly bool) int {
+	address := uint32(d.z.Core.GlobalVariableBase) + 2*uint32(n)
+	return d.Watch(Watchpoint{Kind: WatchGlobal, Address: address, Length: 2, Global: n, LogOnly: logOnly})
+}
+
+// WatchAttribute watches an attribute of an object being set or cleared
+func (d *Debugger) WatchAttribute(object uint16, attribute uint16, logOnly bool) int {
+	return d.Watch(Watchpoint{Kind: WatchAttribute, Object: object, Attribute: attribute, LogOnly: logOnly})
+}
+
+// WatchParent watches an object being moved to a new parent
+func (d *Debugger) WatchParent(object uint16, logOnly bool) int {
+	return d.Watch(Watchpoint{Kind: WatchParent, Object: object, LogOnly: logOnly})
+}
+
+// Watch adds a watchpoint, returning its id for Unwatch
+func (d *Debugger) Watch(w Watchpoint) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextWatchId++
+	d.watchpoints[d.nextWatchId] = w
+	return d.nextWatchId
+}
+
+// Unwatch returns false if there was no watchpoint with that id
+func (d *Debugger) Unwatch(id int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.watchpoints[id]; !ok {
+		return false
+	}
+	delete(d.watchpoints, id)
+	return true
+}
+
+// Watchpoints returns every watchpoint by id
+func (d *Debugger) Watchpoints() map[int]Watchpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return maps.Clone(d.watchpoints)
+}
+
+// SetWatchLogger sends hits on LogOnly watchpoints to logger as they happen
+func (d *Debugger) SetWatchLogger(logger func(WatchHit)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.logHit = logger
+}
+
+// record keeps hits on watchpoints which pause until the next instruction,
+// returning those which are only logged. Called with the debugger locked.
+func (d *Debugger) record(hit WatchHit, logged []WatchHit) []WatchHit {
+	if hit.Watchpoint.LogOnly {
+		return append(logged, hit)
+	}
+	d.pendingHits = append(d.pendingHits, hit)
+	return logged
+}
+
+// log sends hits to the logger, once the debugger is unlocked so that the
+// logger can use it
+func (d *Debugger) log(logged []WatchHit) {
+	d.mu.Lock()
+	logger := d.logHit
+	d.mu.Unlock()
+	if logger == nil {
+		return
+	}
+	for _, hit := range logged {
+		logger(hit)
+	}
+}
+
+func (d *Debugger) memoryWritten(address uint32, size int, old uint32, new uint32) {
+	var logged []WatchHit
+	d.mu.Lock()
+	for _, id := range slices.Sorted(maps.Keys(d.watchpoints)) {
+		w := d.watchpoints[id]
+		if (w.Kind == WatchMemory || w.Kind == WatchGlobal) && address < w.Address+w.Length && address+uint32(size) > w.Address {
+			logged = d.record(WatchHit{Id: id, Watchpoint: w, PC: d.z.InstructionPC(), Address: address, Size: size, Old: old, New: new}, logged)
+		}
+	}
+	d.mu.Unlock()
+	d.log(logged)
+}
+
+func (d *Debugger) objectChanged(change zmachine.ObjectChange) {
+	var logged []WatchHit
+	d.mu.Lock()
+	for _, id := range slices.Sorted(maps.Keys(d.watchpoints)) {
+		w := d.watchpoints[id]
+		if w.Object != change.Object {
+			continue
+		}
+		if (w.Kind == WatchParent && change.Parent) || (w.Kind == WatchAttribute && !change.Parent && w.Attribute == change.Attribute) {
+			logged = d.record(WatchHit{Id: id, Watchpoint: w, PC: d.z.InstructionPC(), Old: uint32(change.Old), New: uint32(change.New)}, logged)
+		}
+	}
+	d.mu.Unlock()
+	d.log(logged)
+}
@@ -0,0 +1,200 @@
+package debuginfo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Record types in the binary format, see the Inform Technical Manual
+const (
+	eofRecord        = 0
+	fileRecord       = 1
+	classRecord      = 2
+	objectRecord     = 3
+	globalRecord     = 4
+	attributeRecord  = 5
+	propertyRecord   = 6
+	fakeActionRecord = 7
+	actionRecord     = 8
+	headerRecord     = 9
+	lineRefRecord    = 10
+	routineRecord    = 11
+	arrayRecord      = 12
+	mapRecord        = 13
+	routineEndRecord = 14
+)
+
+// binaryReader reads the big endian words, 3 byte addresses and null
+// terminated strings of the binary format. Reading past the end sets err.
+type binaryReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *binaryReader) bytes(n int) []byte {
+	if r.pos+n > len(r.data) {
+		r.err = errors.New("debug information file is truncated")
+		r.pos = len(r.data)
+		return make([]byte, n)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *binaryReader) byte() uint8 {
+	return r.bytes(1)[0]
+}
+
+func (r *binaryReader) word() uint16 {
+	b := r.bytes(2)
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+func (r *binaryReader) address() uint32 {
+	b := r.bytes(3)
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+func (r *binaryReader) string() string {
+	start := r.pos
+	for r.pos < len(r.data) && r.data[r.pos] != 0 {
+		r.pos++
+	}
+	s := string(r.data[start:r.pos])
+	r.bytes(1)
+	return s
+}
+
+// binaryLine is a source position, the file is resolved once every file
+// record has been read
+type binaryLine struct {
+	file   uint8
+	line   uint16
+	column uint8
+}
+
+func (r *binaryReader) line() binaryLine {
+	return binaryLine{file: r.byte(), line: r.word(), column: r.byte()}
+}
+
+// parseBinary reads the format written by Inform 6 up to 6.32. Routine
+// addresses are relative to the code area, whose address is in the map
+// record, and sequence points are relative to their routine.
+func parseBinary(data []byte) (*Info, error) {
+	r := &binaryReader{data: data}
+	r.word() // Magic number, already checked
+	if version := r.word(); version != 0 {
+		return nil, fmt.Errorf("unsupported debug information version %d", version)
+	}
+	r.word() // Inform version
+
+	info := newInfo()
+	files := make(map[uint8]string)
+	routines := make(map[uint16]*Routine)
+	routineLines := make(map[*Routine]binaryLine)
+	sequenceLines := make(map[*Routine][]binaryLine)
+	areas := make(map[string]uint32)
+
+	for done := false; !done && r.err == nil; {
+		switch record := r.byte(); record {
+		case eofRecord:
+			done = true
+		case fileRecord:
+			n := r.byte()
+			r.string() // Name as it was included
+			files[n] = r.string()
+		case classRecord:
+			r.string()
+			r.line()
+			r.line()
+		case objectRecord:
+			n := r.word()
+			info.objects[n] = r.string()
+			r.line()
+			r.line()
+		case globalRecord:
+			n := r.byte()
+			info.globals[n] = r.string()
+		case attributeRecord:
+			n := r.word()
+			info.attributes[n] = r.string()
+		case propertyRecord:
+			n := r.word()
+			info.properties[n] = r.string()
+		case fakeActionRecord, actionRecord:
+			n := r.word()
+			info.actions[n] = r.string()
+		case headerRecord:
+			info.header = r.bytes(64)
+		case lineRefRecord:
+			routine := routines[r.word()]
+			count := int(r.word())
+			for range count {
+				line := r.line()
+				offset := uint32(r.word())
+				if routine != nil {
+					routine.Lines = append(routine.Lines, SequencePoint{Address: offset})
+					sequenceLines[routine] = append(sequenceLines[routine], line)
+				}
+			}
+		case routineRecord:
+			n := r.word()
+			routine := &Routine{}
+			routineLines[routine] = r.line()
+			routine.Address = r.address()
+			routine.Name = r.string()
+			for local := r.string(); local != ""; local = r.string() {
+				routine.Locals = append(routine.Locals, local)
+			}
+			routines[n] = routine
+			info.routines = append(info.routines, routine)
+		case arrayRecord:
+			address := uint32(r.word())
+			info.arrays[address] = r.string()
+		case mapRecord:
+			for name := r.string(); name != "" && r.err == nil; name = r.string() {
+				areas[name] = r.address()
+			}
+		case routineEndRecord:
+			routine := routines[r.word()]
+			r.line()
+			end := r.address()
+			if routine != nil {
+				routine.End = end
+			}
+		default:
+			return nil, fmt.Errorf("unknown debug information record %d at offset %d", record, r.pos-1)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	resolve := func(line binaryLine) Location {
+		return Location{File: files[line.file], Line: int(line.line), Column: int(line.column)}
+	}
+	codeArea := areas["code area"]
+	for _, routine := range info.routines {
+		routine.Address += codeArea
+		if routine.End != 0 {
+			routine.End += codeArea
+		}
+		routine.Location = resolve(routineLines[routine])
+		for ix, line := range sequenceLines[routine] {
+			routine.Lines[ix].Address += routine.Address
+			routine.Lines[ix].Location = resolve(line)
+		}
+	}
+
+	// Arrays are numbered from the start of array space
+	if arraySpace, ok := areas["array space"]; ok {
+		arrays := make(map[uint32]string, len(info.arrays))
+		for address, name := range info.arrays {
+			arrays[address+arraySpace] = name
+		}
+		info.arrays = arrays
+	}
+	return info, nil
+}
@@ -0,0 +1,234 @@
+// Package debuginfo loads the debug information files Inform 6 writes when
+// compiling with -k, in either the original binary format or the XML format
+// used from 6.33, to give names to routines, variables, objects and source
+// lines. Info can be given to a machine with SetSymbols.
+package debuginfo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/davetcode/goz/zcore"
+)
+
+// Location is a place in the source
+type Location struct {
+	File   string // As the compiler found it, which may be a full path
+	Line   int
+	Column int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d", filepath.Base(l.File), l.Line)
+}
+
+// SequencePoint is the first instruction compiled from a source line
+type SequencePoint struct {
+	Address  uint32
+	Location Location
+}
+
+type Routine struct {
+	Name     string
+	Address  uint32 // Start of the routine, where the number of locals is
+	End      uint32 // First address after the routine
+	Locals   []string
+	Location Location        // Where the routine is defined
+	Lines    []SequencePoint // In address order
+}
+
+type Info struct {
+	routines   []*Routine // In address order
+	globals    map[uint8]string
+	objects    map[uint16]string
+	attributes map[uint16]string
+	properties map[uint16]string
+	actions    map[uint16]string
+	arrays     map[uint32]string
+	header     []uint8 // The story file's header when compiled, to check it's for the same story
+}
+
+func newInfo() *Info {
+	return &Info{
+		globals:    make(map[uint8]string),
+		objects:    make(map[uint16]string),
+		attributes: make(map[uint16]string),
+		properties: make(map[uint16]string),
+		actions:    make(map[uint16]string),
+		arrays:     make(map[uint32]string),
+	}
+}
+
+// Load reads a debug information file, see Parse
+func Load(path string, core *zcore.Core) (*Info, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, core)
+}
+
+// Parse reads a binary or XML debug information file for the story in core,
+// returning an error if it was written for a different story
+func Parse(data []byte, core *zcore.Core) (*Info, error) {
+	var info *Info
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte{0xde, 0xbf}):
+		info, err = parseBinary(data)
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")):
+		info, err = parseXML(data, core)
+	default:
+		return nil, errors.New("not an Inform debug information file")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.matches(core) {
+		return nil, errors.New("debug information is for a different story file")
+	}
+
+	slices.SortFunc(info.routines, func(a, b *Routine) int { return int(a.Address) - int(b.Address) })
+	for ix, routine := range info.routines {
+		if routine.End == 0 && ix+1 < len(info.routines) {
+			routine.End = info.routines[ix+1].Address
+		}
+		slices.SortFunc(routine.Lines, func(a, b SequencePoint) int { return int(a.Address) - int(b.Address) })
+	}
+	return info, nil
+}
+
+// matches checks the release, serial and checksum, the only parts of the
+// header which identify a story and which the interpreter doesn't change
+func (i *Info) matches(core *zcore.Core) bool {
+	if core == nil || len(i.header) < 0x1e || core.MemoryLength() < 0x1e {
+		return true
+	}
+	for _, field := range [][2]uint32{{0x02, 0x04}, {0x12, 0x18}, {0x1c, 0x1e}} {
+		if !bytes.Equal(i.header[field[0]:field[1]], core.ReadSlice(field[0], field[1])) {
+			return false
+		}
+	}
+	return true
+}
+
+// Routines returns every routine in address order
+func (i *Info) Routines() []*Routine {
+	return i.routines
+}
+
+// RoutineAt returns the routine containing pc, nil if there isn't one
+func (i *Info) RoutineAt(pc uint32) *Routine {
+	ix := sort.Search(len(i.routines), func(ix int) bool { return i.routines[ix].Address > pc }) - 1
+	if ix < 0 {
+		return nil
+	}
+	if routine := i.routines[ix]; routine.End == 0 || pc < routine.End {
+		return routine
+	}
+	return nil
+}
+
+// RoutineNamed returns the routine with the given name, nil if there isn't one
+func (i *Info) RoutineNamed(name string) *Routine {
+	for _, routine := range i.routines {
+		if routine.Name == name {
+			return routine
+		}
+	}
+	return nil
+}
+
+// Location returns the source line which the instruction at pc was compiled from
+func (i *Info) Location(pc uint32) (Location, bool) {
+	routine := i.RoutineAt(pc)
+	if routine == nil {
+		return Location{}, false
+	}
+	ix := sort.Search(len(routine.Lines), func(ix int) bool { return routine.Lines[ix].Address > pc }) - 1
+	if ix < 0 {
+		return Location{}, false
+	}
+	return routine.Lines[ix].Location, true
+}
+
+// Addresses returns the first instruction of each sequence point on a line.
+// file matches if it's the same path as the compiler saw or has the same
+// name, as editors give full paths.
+func (i *Info) Addresses(file string, line int) []uint32 {
+	var addresses []uint32
+	for _, routine := range i.routines {
+		for _, point := range routine.Lines {
+			if point.Location.Line == line && sameFile(point.Location.File, file) {
+				addresses = append(addresses, point.Address)
+			}
+		}
+	}
+	return addresses
+}
+
+func sameFile(compiled string, given string) bool {
+	return compiled == given || filepath.Base(filepath.ToSlash(compiled)) == filepath.Base(filepath.ToSlash(given))
+}
+
+// Describe names the routine containing pc along with the source line, e.g.
+// "LookSub (verbs.h:812)", empty if pc isn't in a known routine
+func (i *Info) Describe(pc uint32) string {
+	routine := i.RoutineAt(pc)
+	if routine == nil {
+		return ""
+	}
+	if location, ok := i.Location(pc); ok {
+		return fmt.Sprintf("%s (%s)", routine.Name, location)
+	}
+	return routine.Name
+}
+
+// RoutineName returns the name of the routine starting at address
+func (i *Info) RoutineName(address uint32) string {
+	if routine := i.RoutineAt(address); routine != nil && routine.Address == address {
+		return routine.Name
+	}
+	return ""
+}
+
+// GlobalName returns the name of global n, numbered from 0
+func (i *Info) GlobalName(n uint8) string {
+	return i.globals[n]
+}
+
+// LocalName returns the name of local n, numbered from 0, of the routine
+// containing pc
+func (i *Info) LocalName(pc uint32, n uint8) string {
+	if routine := i.RoutineAt(pc); routine != nil && int(n) < len(routine.Locals) {
+		return routine.Locals[n]
+	}
+	return ""
+}
+
+func (i *Info) ObjectName(n uint16) string {
+	return i.objects[n]
+}
+
+func (i *Info) AttributeName(n uint16) string {
+	return i.attributes[n]
+}
+
+func (i *Info) PropertyName(n uint16) string {
+	return i.properties[n]
+}
+
+func (i *Info) ActionName(n uint16) string {
+	return i.actions[n]
+}
+
+// ArrayName returns the name of the array starting at address
+func (i *Info) ArrayName(address uint32) string {
+	return i.arrays[address]
+}
@@ -0,0 +1,172 @@
+package debuginfo
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/davetcode/goz/zcore"
+)
+
+func loadAdvent(t *testing.T) zcore.Core {
+	t.Helper()
+	story, err := os.ReadFile("../advent.z3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zcore.LoadCore(story)
+}
+
+// binaryDebugInfo writes the records Inform would for a story with LookSub
+// at 0x5a00 in a code area starting at 0x5000
+func binaryDebugInfo(header []byte) []byte {
+	var b []byte
+	str := func(s string) { b = append(append(b, s...), 0) }
+	word := func(w uint16) { b = append(b, byte(w>>8), byte(w)) }
+	address := func(a uint32) { b = append(b, byte(a>>16), byte(a>>8), byte(a)) }
+	line := func(file uint8, line uint16) { b = append(b, file); word(line); b = append(b, 0) }
+
+	word(0xdebf)
+	word(0)
+	word(1631)
+	b = append(b, fileRecord, 0)
+	str("verbs")
+	str("lib/verbs.h")
+	b = append(b, globalRecord, 0x12)
+	str("score")
+	b = append(b, objectRecord)
+	word(27)
+	str("Lamp")
+	line(0, 10)
+	line(0, 20)
+	b = append(b, attributeRecord)
+	word(5)
+	str("light")
+	b = append(b, propertyRecord)
+	word(7)
+	str("description")
+	b = append(b, actionRecord)
+	word(1)
+	str("Look")
+	b = append(b, headerRecord)
+	b = append(b, header...)
+	b = append(b, routineRecord)
+	word(3)
+	line(0, 808)
+	address(0xa00)
+	str("LookSub")
+	str("visible")
+	str("i")
+	str("")
+	b = append(b, lineRefRecord)
+	word(3)
+	word(2)
+	line(0, 810)
+	word(3)
+	line(0, 812)
+	word(0x3c)
+	b = append(b, routineEndRecord)
+	word(3)
+	line(0, 830)
+	address(0xa80)
+	b = append(b, arrayRecord)
+	word(0x10)
+	str("buffer")
+	b = append(b, mapRecord)
+	str("code area")
+	address(0x5000)
+	str("array space")
+	address(0x2000)
+	str("")
+	b = append(b, eofRecord)
+	return b
+}
+
+func TestBinaryDebugInfo(t *testing.T) {
+	core := loadAdvent(t)
+	info, err := Parse(binaryDebugInfo(core.ReadSlice(0, 64)), &core)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if description := info.Describe(0x5a3c); description != "LookSub (verbs.h:812)" {
+		t.Errorf("expected LookSub (verbs.h:812) at 0x5a3c, got %q", description)
+	}
+	if description := info.Describe(0x5a80); description != "" {
+		t.Errorf("expected nothing after the end of LookSub, got %q", description)
+	}
+	if name := info.GlobalName(0x12); name != "score" {
+		t.Errorf("expected global 0x12 to be score, got %q", name)
+	}
+	if name := info.LocalName(0x5a10, 1); name != "i" {
+		t.Errorf("expected the second local of LookSub to be i, got %q", name)
+	}
+	if name := info.RoutineName(0x5a00); name != "LookSub" {
+		t.Errorf("expected LookSub at 0x5a00, got %q", name)
+	}
+	for _, check := range []struct{ got, expected string }{
+		{info.ObjectName(27), "Lamp"},
+		{info.AttributeName(5), "light"},
+		{info.PropertyName(7), "description"},
+		{info.ActionName(1), "Look"},
+		{info.ArrayName(0x2010), "buffer"},
+	} {
+		if check.got != check.expected {
+			t.Errorf("expected %q, got %q", check.expected, check.got)
+		}
+	}
+	if addresses := info.Addresses("/home/me/lib/verbs.h", 810); !slices.Equal(addresses, []uint32{0x5a03}) {
+		t.Errorf("expected line 810 to start at 0x5a03, got %x", addresses)
+	}
+}
+
+func TestXMLDebugInfo(t *testing.T) {
+	core := loadAdvent(t)
+	global := uint32(core.GlobalVariableBase) + 2*0x12
+	xml := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<inform-story-file version="1.0" content-creator="Inform" content-creator-version="6.36">
+<story-file-prefix>%s</story-file-prefix>
+<source index="0"><given-path>verbs.h</given-path><resolved-path>lib/verbs.h</resolved-path><language>Inform 6</language></source>
+<global-variable><identifier>score</identifier><address>%d</address></global-variable>
+<object><identifier>Lamp</identifier><value>27</value></object>
+<routine>
+  <identifier>LookSub</identifier>
+  <value>11520</value>
+  <address>23040</address>
+  <byte-count>128</byte-count>
+  <source-code-location><file-index>0</file-index><line>808</line><character>1</character></source-code-location>
+  <local-variable><identifier>visible</identifier><index>1</index></local-variable>
+  <local-variable><identifier>i</identifier><index>2</index></local-variable>
+  <sequence-point><address>23043</address><source-code-location><file-index>0</file-index><line>810</line><character>5</character></source-code-location></sequence-point>
+  <sequence-point><address>23100</address><source-code-location><file-index>0</file-index><line>812</line><character>5</character></source-code-location></sequence-point>
+</routine>
+</inform-story-file>`, base64.StdEncoding.EncodeToString(core.ReadSlice(0, 64)), global)
+
+	info, err := Parse([]byte(xml), &core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if description := info.Describe(0x5a3c); description != "LookSub (verbs.h:812)" {
+		t.Errorf("expected LookSub (verbs.h:812) at 0x5a3c, got %q", description)
+	}
+	if name := info.GlobalName(0x12); name != "score" {
+		t.Errorf("expected global 0x12 to be score, got %q", name)
+	}
+	if name := info.LocalName(0x5a3c, 0); name != "visible" {
+		t.Errorf("expected the first local of LookSub to be visible, got %q", name)
+	}
+	if routine := info.RoutineNamed("LookSub"); routine == nil || routine.End != 0x5a80 {
+		t.Errorf("expected LookSub to end at 0x5a80, got %+v", routine)
+	}
+}
+
+func TestDebugInfoForAnotherStory(t *testing.T) {
+	core := loadAdvent(t)
+	header := slices.Clone(core.ReadSlice(0, 64))
+	header[0x12]++ // Serial number
+	if _, err := Parse(binaryDebugInfo(header), &core); err == nil {
+		t.Errorf("expected debug information for a different serial number to be rejected")
+	}
+}
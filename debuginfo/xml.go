@@ -0,0 +1,153 @@
+package debuginfo
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"github.com/davetcode/goz/zcore"
+)
+
+// The XML format written by Inform 6.33 onwards. Unlike the binary format
+// every address is absolute and numbers are decimal.
+
+type xmlStory struct {
+	Prefix     string       `xml:"story-file-prefix"`
+	Sources    []xmlSource  `xml:"source"`
+	Globals    []xmlSymbol  `xml:"global-variable"`
+	Objects    []xmlSymbol  `xml:"object"`
+	Attributes []xmlSymbol  `xml:"attribute"`
+	Properties []xmlSymbol  `xml:"property"`
+	Actions    []xmlSymbol  `xml:"action"`
+	Fake       []xmlSymbol  `xml:"fake-action"`
+	Arrays     []xmlSymbol  `xml:"array"`
+	Routines   []xmlRoutine `xml:"routine"`
+}
+
+type xmlSource struct {
+	Index        int    `xml:"index,attr"`
+	GivenPath    string `xml:"given-path"`
+	ResolvedPath string `xml:"resolved-path"`
+}
+
+type xmlSymbol struct {
+	Identifier string `xml:"identifier"`
+	Value      string `xml:"value"`
+	Address    string `xml:"address"`
+}
+
+type xmlLocation struct {
+	FileIndex int `xml:"file-index"`
+	Line      int `xml:"line"`
+	Character int `xml:"character"`
+}
+
+type xmlRoutine struct {
+	Identifier string        `xml:"identifier"`
+	Address    string        `xml:"address"`
+	ByteCount  string        `xml:"byte-count"`
+	Locations  []xmlLocation `xml:"source-code-location"` // Start and end of the definition
+	Locals     []struct {
+		Identifier string `xml:"identifier"`
+		Index      string `xml:"index"`
+	} `xml:"local-variable"`
+	SequencePoints []struct {
+		Address  string      `xml:"address"`
+		Location xmlLocation `xml:"source-code-location"`
+	} `xml:"sequence-point"`
+}
+
+func xmlNumber(s string) uint32 {
+	n, _ := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+	return uint32(n)
+}
+
+// parseXML reads the XML format. Globals are given by address so the story's
+// header, from the file or failing that from core, is needed to number them.
+func parseXML(data []byte, core *zcore.Core) (*Info, error) {
+	var story xmlStory
+	if err := xml.Unmarshal(data, &story); err != nil {
+		return nil, err
+	}
+
+	info := newInfo()
+	if prefix, err := base64.StdEncoding.DecodeString(strings.TrimSpace(story.Prefix)); err == nil && len(prefix) > 0 {
+		info.header = prefix
+	}
+
+	files := make(map[int]string)
+	for _, source := range story.Sources {
+		if source.ResolvedPath != "" {
+			files[source.Index] = strings.TrimSpace(source.ResolvedPath)
+		} else {
+			files[source.Index] = strings.TrimSpace(source.GivenPath)
+		}
+	}
+	resolve := func(location xmlLocation) Location {
+		return Location{File: files[location.FileIndex], Line: location.Line, Column: location.Character}
+	}
+
+	var globalBase uint32
+	switch {
+	case len(info.header) >= 0x0e:
+		globalBase = uint32(binary.BigEndian.Uint16(info.header[0x0c:0x0e]))
+	case core != nil:
+		globalBase = uint32(core.GlobalVariableBase)
+	}
+	for _, global := range story.Globals {
+		if address := xmlNumber(global.Address); address >= globalBase && address < globalBase+240*2 {
+			info.globals[uint8((address-globalBase)/2)] = strings.TrimSpace(global.Identifier)
+		}
+	}
+
+	for _, symbols := range []struct {
+		from []xmlSymbol
+		to   map[uint16]string
+	}{
+		{story.Objects, info.objects},
+		{story.Attributes, info.attributes},
+		{story.Properties, info.properties},
+		{story.Actions, info.actions},
+		{story.Fake, info.actions},
+	} {
+		for _, symbol := range symbols.from {
+			symbols.to[uint16(xmlNumber(symbol.Value))] = strings.TrimSpace(symbol.Identifier)
+		}
+	}
+	for _, array := range story.Arrays {
+		info.arrays[xmlNumber(array.Value)] = strings.TrimSpace(array.Identifier)
+	}
+
+	for _, r := range story.Routines {
+		routine := &Routine{
+			Name:    strings.TrimSpace(r.Identifier),
+			Address: xmlNumber(r.Address),
+		}
+		if count := xmlNumber(r.ByteCount); count > 0 {
+			routine.End = routine.Address + count
+		}
+		if len(r.Locations) > 0 {
+			routine.Location = resolve(r.Locations[0])
+		}
+		for ix, local := range r.Locals {
+			index := int(xmlNumber(local.Index))
+			if index == 0 {
+				index = ix + 1
+			}
+			if index > 15 {
+				continue
+			}
+			for len(routine.Locals) < index {
+				routine.Locals = append(routine.Locals, "")
+			}
+			routine.Locals[index-1] = strings.TrimSpace(local.Identifier)
+		}
+		for _, point := range r.SequencePoints {
+			routine.Lines = append(routine.Lines, SequencePoint{Address: xmlNumber(point.Address), Location: resolve(point.Location)})
+		}
+		info.routines = append(info.routines, routine)
+	}
+	return info, nil
+}
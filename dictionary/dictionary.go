@@ -1,29 +1,29 @@
 package dictionary
 
 import (
-	"bytes"
-
 	"github.com/davetcode/goz/zcore"
 	"github.com/davetcode/goz/zstring"
 )
 
 type Header struct {
-	n          uint8
-	InputCodes []uint8
-	length     uint8
-	count      int16
+	InputCodes  []uint8
+	EntryLength uint8
+	Count       int16 // Negative for user dictionaries which aren't sorted
 }
 
 type Entry struct {
-	address     uint16
-	encodedWord []uint8
-	decodedWord string
-	data        []uint8
+	Address uint16
+	Encoded []uint8 // The word as a Z-string, 4 bytes before V4 and 6 after
+	Word    string
+	Data    []uint8 // The bytes after the word, their meaning is up to the story
 }
 
 type Dictionary struct {
 	Header  Header
+	end     uint32 // Address after the last entry
+	writes  uint64 // Writes made to the dictionary's memory when it was parsed, see Cache
 	entries []Entry
+	index   map[string]uint16 // Encoded word to entry address
 }
 
 func ParseDictionary(baseAddress uint32, core *zcore.Core, alphabets *zstring.Alphabets) *Dictionary {
@@ -31,45 +31,83 @@ func ParseDictionary(baseAddress uint32, core *zcore.Core, alphabets *zstring.Al
 	numInputCodes := core.ReadZByte(dictionaryPtr)
 
 	header := Header{
-		n:          numInputCodes,
-		InputCodes: core.ReadSlice(dictionaryPtr+1, dictionaryPtr+uint32(numInputCodes)+1),
-		length:     core.ReadZByte((dictionaryPtr + 1 + uint32(numInputCodes))),
-		count:      int16(core.ReadHalfWord(dictionaryPtr + 2 + uint32(numInputCodes))),
+		InputCodes:  core.ReadSlice(dictionaryPtr+1, dictionaryPtr+uint32(numInputCodes)+1),
+		EntryLength: core.ReadZByte((dictionaryPtr + 1 + uint32(numInputCodes))),
+		Count:       int16(core.ReadHalfWord(dictionaryPtr + 2 + uint32(numInputCodes))),
+	}
+
+	// A negative count marks a user dictionary which isn't sorted, the
+	// index makes lookups the same either way
+	entryCount := int(header.Count)
+	if entryCount < 0 {
+		entryCount = -entryCount
 	}
 
 	entryPtr := dictionaryPtr + 4 + uint32(numInputCodes)
-	var entries = make([]Entry, header.count)
+	var entries = make([]Entry, entryCount)
+	index := make(map[string]uint16, entryCount)
 
 	encodedWordLength := 4
 	if core.Version > 3 {
 		encodedWordLength = 6
 	}
 
-	for ix := 0; ix < int(header.count); ix++ {
+	for ix := range entryCount {
 		encodedWord := core.ReadSlice(entryPtr, entryPtr+uint32(encodedWordLength))
 		decodedWord, _ := zstring.Decode(entryPtr, entryPtr+uint32(encodedWordLength), core, alphabets, false)
 		entries[ix] = Entry{
-			address:     uint16(entryPtr),
-			encodedWord: encodedWord,
-			decodedWord: decodedWord,
-			data:        core.ReadSlice(entryPtr+uint32(encodedWordLength), entryPtr+uint32(header.length)),
+			Address: uint16(entryPtr),
+			Encoded: encodedWord,
+			Word:    decodedWord,
+			Data:    core.ReadSlice(entryPtr+uint32(encodedWordLength), entryPtr+uint32(header.EntryLength)),
 		}
 
-		entryPtr += uint32(header.length)
+		// The first of any duplicates wins, the same as a linear search
+		if _, ok := index[string(encodedWord)]; !ok {
+			index[string(encodedWord)] = uint16(entryPtr)
+		}
+
+		entryPtr += uint32(header.EntryLength)
 	}
 
 	return &Dictionary{
 		Header:  header,
+		end:     entryPtr,
+		writes:  core.Writes(baseAddress, entryPtr),
 		entries: entries,
+		index:   index,
 	}
 }
 
+// Entries returns every word in the order they appear in the story file
+func (d *Dictionary) Entries() []Entry {
+	return d.entries
+}
+
+// Find returns the address of the entry for an encoded word, 0 if the word
+// isn't in the dictionary
 func (d *Dictionary) Find(zstr []uint8) uint16 {
-	for _, entry := range d.entries {
-		if bytes.Equal(entry.encodedWord, zstr) {
-			return entry.address
-		}
+	return d.index[string(zstr)]
+}
+
+// Cache keeps dictionaries parsed by address so that stories which tokenise
+// against their own dictionaries don't pay to parse them on every call
+type Cache struct {
+	dictionaries map[uint32]*Dictionary
+}
+
+// Get returns the dictionary at address, parsing it again if the story has
+// written to the memory around it since it was last parsed. Memory replaced
+// wholesale, e.g. by a restore, needs a new cache.
+func (c *Cache) Get(address uint32, core *zcore.Core, alphabets *zstring.Alphabets) *Dictionary {
+	if d, ok := c.dictionaries[address]; ok && core.Writes(address, d.end) == d.writes {
+		return d
 	}
 
-	return 0
+	if c.dictionaries == nil {
+		c.dictionaries = make(map[uint32]*Dictionary)
+	}
+	d := ParseDictionary(address, core, alphabets)
+	c.dictionaries[address] = d
+	return d
 }
@@ -0,0 +1,60 @@
+package dictionary_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/davetcode/goz/dictionary"
+	"github.com/davetcode/goz/zcore"
+	"github.com/davetcode/goz/zstring"
+)
+
+func loadCore(t *testing.T, file string) zcore.Core {
+	romFileBytes, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zcore.LoadCore(romFileBytes)
+}
+
+func TestEntriesAndFind(t *testing.T) {
+	core := loadCore(t, "../zork1.z1")
+	alphabets := zstring.LoadAlphabets(&core)
+	d := dictionary.ParseDictionary(uint32(core.DictionaryBase), &core, alphabets)
+
+	entries := d.Entries()
+	if len(entries) != int(d.Header.Count) {
+		t.Fatalf("Expected %d entries, got %d", d.Header.Count, len(entries))
+	}
+
+	for _, entry := range entries {
+		if entry.Word != "mailbo" {
+			continue
+		}
+		if found := d.Find(zstring.Encode([]rune("mailbox"), &core, alphabets)); found != entry.Address {
+			t.Errorf("Expected mailbox at 0x%x, found 0x%x", entry.Address, found)
+		}
+		return
+	}
+	t.Errorf("mailbox missing from the word list")
+}
+
+func TestCacheReparsesChangedDictionaries(t *testing.T) {
+	memory := make([]uint8, 0x100)
+	memory[0x00] = 3
+	memory[0x0e] = 0xf0 // Dictionary in dynamic memory
+	copy(memory[0x40:], []uint8{0, 7, 0, 1, 0x80, 0x00, 0x80, 0x00, 0xff, 0xff, 0xff})
+	core := zcore.LoadCore(memory)
+	alphabets := zstring.LoadAlphabets(&core)
+
+	var cache dictionary.Cache
+	first := cache.Get(0x40, &core, alphabets)
+	if cache.Get(0x40, &core, alphabets) != first {
+		t.Errorf("Expected an unchanged dictionary to come from the cache")
+	}
+
+	core.WriteZByte(0x48, 0x12)
+	if changed := cache.Get(0x40, &core, alphabets); changed == first || changed.Entries()[0].Data[0] != 0x12 {
+		t.Errorf("Expected a changed dictionary to be parsed again")
+	}
+}
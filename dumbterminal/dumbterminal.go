@@ -0,0 +1,400 @@
+// Package dumbterminal is a plain text frontend which reads commands from an
+// io.Reader and writes the story to an io.Writer with no cursor movement or
+// styling, suitable for piping a story through scripts or playing over a
+// serial line.
+package dumbterminal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/davetcode/goz/picture"
+	"github.com/davetcode/goz/storyfiles"
+	"github.com/davetcode/goz/zmachine"
+)
+
+// screenWidth matches the width the interpreter reports in the header
+const screenWidth = 80
+
+type Frontend struct {
+	files  *storyfiles.Store
+	in     io.Reader
+	lines  <-chan string
+	out    *bufio.Writer
+	errOut io.Writer
+
+	screenModel  zmachine.ScreenModel
+	lowerWindow  strings.Builder
+	upperWindow  [][]rune
+	upperChanged bool
+	statusBar    zmachine.StatusBar
+	statusLine   string
+}
+
+// New creates a frontend reading commands from in, files such as saves are
+// named after the story file at romFilePath
+func New(romFilePath string, in io.Reader, out io.Writer, errOut io.Writer) *Frontend {
+	return &Frontend{
+		files:  storyfiles.New(romFilePath),
+		in:     in,
+		out:    bufio.NewWriter(out),
+		errOut: errOut,
+	}
+}
+
+// Run plays a story loaded with this frontend until it quits, the input runs
+// out or ctx is done
+func (f *Frontend) Run(ctx context.Context, z *zmachine.ZMachine) error {
+	f.lines = readLines(ctx, f.in)
+	defer f.flush()
+
+	if err := z.RunContext(ctx); !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// readLines feeds lines from in to a channel so that reads can give up when
+// the context is done, the channel is closed at the end of the input
+func readLines(ctx context.Context, in io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- strings.TrimRight(scanner.Text(), "\r"):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return lines
+}
+
+func (f *Frontend) nextLine(ctx context.Context) (string, error) {
+	line, _, err := f.nextTimedLine(ctx, 0)
+	return line, err
+}
+
+// nextTimedLine waits for a line for at most timeout, a zero timeout waits
+// forever. The bool is true if the time ran out first.
+func (f *Frontend) nextTimedLine(ctx context.Context, timeout time.Duration) (string, bool, error) {
+	f.flush()
+
+	var timer <-chan time.Time
+	if timeout > 0 {
+		timer = time.After(timeout)
+	}
+
+	select {
+	case line, ok := <-f.lines:
+		if !ok {
+			return "", false, io.EOF
+		}
+		return line, false, nil
+	case <-timer:
+		return "", true, nil
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	}
+}
+
+// ReadCommand reads a line for something other than the story, such as a
+// debugger, sharing the story's input
+func (f *Frontend) ReadCommand(ctx context.Context) (string, error) {
+	return f.nextLine(ctx)
+}
+
+// Write writes straight to the output after any story text waiting to be
+// shown, so that a debugger's output appears in the right place
+func (f *Frontend) Write(p []byte) (int, error) {
+	f.flush()
+	n, err := f.out.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, f.out.Flush()
+}
+
+// flush writes out everything since the player was last asked for input.
+// The status line and upper window go first, and only if they've changed,
+// so that the prompt at the end of the lower window text stays last.
+func (f *Frontend) flush() {
+	if f.statusLine != "" {
+		fmt.Fprintln(f.out, f.statusLine)
+		f.statusLine = ""
+	}
+
+	if f.upperChanged {
+		f.upperChanged = false
+		for _, row := range f.upperWindow[:min(f.screenModel.UpperWindowHeight, len(f.upperWindow))] {
+			if line := strings.TrimRight(string(row), " "); line != "" {
+				fmt.Fprintln(f.out, line)
+			}
+		}
+	}
+
+	f.out.WriteString(f.lowerWindow.String()) // nolint:errcheck
+	f.lowerWindow.Reset()
+	f.out.Flush() // nolint:errcheck
+}
+
+func (f *Frontend) clearUpperWindow() {
+	for _, row := range f.upperWindow {
+		for i := range row {
+			row[i] = ' '
+		}
+	}
+}
+
+func (f *Frontend) Print(ctx context.Context, text string) {
+	text = f.screenModel.CurrentFont.Glyphs(text)
+	if f.screenModel.LowerWindowActive {
+		f.lowerWindow.WriteString(text)
+		return
+	}
+
+	// Upper window text overwrites the grid at the cursor, same as the TUI
+	cursorX := f.screenModel.UpperWindowCursorX
+	cursorY := f.screenModel.UpperWindowCursorY
+	for _, r := range text {
+		if r == '\n' {
+			cursorY++
+			cursorX = 0
+			continue
+		}
+		if cursorY >= 0 && cursorY < len(f.upperWindow) && cursorX >= 0 && cursorX < screenWidth {
+			f.upperWindow[cursorY][cursorX] = r
+		}
+		cursorX++
+	}
+	f.upperChanged = true
+}
+
+// ReadLine waits for a whole line, nothing can be typed while it's waiting
+// so a timed out request never has any partial input. Initial text left over
+// from a timed out request is assumed to already be on the screen.
+func (f *Frontend) ReadLine(ctx context.Context, request zmachine.InputRequest) (zmachine.InputResponse, error) {
+	line, timedOut, err := f.nextTimedLine(ctx, request.Timeout)
+	if err != nil || timedOut {
+		return zmachine.InputResponse{TimedOut: timedOut}, err
+	}
+	return zmachine.InputResponse{Text: request.InitialText + line, TerminatingKey: 13}, nil
+}
+
+// ReadChar takes a whole line and uses its first character, an empty line
+// is treated as pressing enter
+func (f *Frontend) ReadChar(ctx context.Context, request zmachine.CharacterRequest) (zmachine.InputResponse, error) {
+	line, timedOut, err := f.nextTimedLine(ctx, request.Timeout)
+	if err != nil || timedOut {
+		return zmachine.InputResponse{TimedOut: timedOut}, err
+	}
+	if line == "" {
+		return zmachine.InputResponse{TerminatingKey: 13}, nil
+	}
+	_, size := utf8.DecodeRuneInString(line)
+	return zmachine.InputResponse{Text: line[:size]}, nil
+}
+
+// promptFilename asks the player for a filename, keeping the default if they
+// just press enter. Auxiliary (table) files default to their own file so the
+// saved game isn't overwritten.
+func (f *Frontend) promptFilename(ctx context.Context, filename string, auxiliary bool) (string, error) {
+	switch {
+	case filename != "":
+	case auxiliary:
+		filename = f.files.DefaultAuxiliaryFilename()
+	default:
+		filename = f.files.DefaultSaveFilename()
+	}
+	fmt.Fprintf(&f.lowerWindow, "Filename [%s]: ", filename)
+	line, err := f.nextLine(ctx)
+	if err != nil {
+		return "", err
+	}
+	if line = strings.TrimSpace(line); line != "" {
+		return line, nil
+	}
+	return filename, nil
+}
+
+func (f *Frontend) Save(ctx context.Context, request zmachine.Save) (zmachine.SaveResponse, error) {
+	if request.Prompt {
+		filename, err := f.promptFilename(ctx, request.Filename, request.NumBytes != 0)
+		if err != nil {
+			return zmachine.SaveResponse{}, err
+		}
+		request.Filename = filename
+	}
+	return f.files.Save(request), nil
+}
+
+func (f *Frontend) Restore(ctx context.Context, request zmachine.Restore) (zmachine.RestoreResponse, error) {
+	if request.Prompt {
+		filename, err := f.promptFilename(ctx, request.Filename, request.NumBytes != 0)
+		if err != nil {
+			return zmachine.RestoreResponse{}, err
+		}
+		request.Filename = filename
+	}
+	return f.files.Restore(request), nil
+}
+
+func (f *Frontend) OpenTranscript(ctx context.Context) (io.WriteCloser, error) {
+	transcript, err := f.files.OpenTranscript()
+	if err != nil {
+		fmt.Fprintf(f.errOut, "Unable to open transcript: %v\n", err)
+		return nil, nil
+	}
+	return transcript, nil
+}
+
+func (f *Frontend) OpenCommandRecording(ctx context.Context) (io.WriteCloser, error) {
+	recording, err := f.files.OpenCommandRecording()
+	if err != nil {
+		fmt.Fprintf(f.errOut, "Unable to open command recording: %v\n", err)
+		return nil, nil
+	}
+	return recording, nil
+}
+
+func (f *Frontend) OpenCommandScript(ctx context.Context) (io.ReadCloser, error) {
+	script, err := f.files.OpenCommandScript()
+	if err != nil {
+		fmt.Fprintf(f.errOut, "Unable to open command file: %v\n", err)
+		return nil, nil
+	}
+	return script, nil
+}
+
+func (f *Frontend) UpdateScreen(ctx context.Context, model zmachine.ScreenModel) {
+	// Keep every row the story has ever split to so that text above a
+	// shrunk window isn't lost before it has been shown
+	for len(f.upperWindow) < model.UpperWindowHeight {
+		f.upperWindow = append(f.upperWindow, []rune(strings.Repeat(" ", screenWidth)))
+	}
+	f.screenModel = model
+}
+
+func (f *Frontend) UpdateStatusBar(ctx context.Context, status zmachine.StatusBar) {
+	if status != f.statusBar {
+		f.statusLine = status.Text(screenWidth)
+	}
+	f.statusBar = status
+}
+
+func (f *Frontend) EraseWindow(ctx context.Context, window zmachine.EraseWindowRequest) {
+	switch window {
+	case -1, -2, 1:
+		f.clearUpperWindow()
+	}
+}
+
+func (f *Frontend) EraseLine(ctx context.Context, request zmachine.EraseLineRequest) {
+	cursorX := f.screenModel.UpperWindowCursorX
+	cursorY := f.screenModel.UpperWindowCursorY
+	if f.screenModel.LowerWindowActive || cursorY < 0 || cursorY >= len(f.upperWindow) {
+		return
+	}
+	for x := max(cursorX, 0); x < screenWidth; x++ {
+		f.upperWindow[cursorY][x] = ' '
+	}
+	f.upperChanged = true
+}
+
+// DrawPicture draws pictures as ASCII shading, over the upper window grid for
+// window 1 and into the lower window's text for every other window
+func (f *Frontend) DrawPicture(ctx context.Context, request zmachine.DrawPictureRequest) {
+	lines, err := picture.ASCII(request.Data, request.Width, request.Height)
+	if err != nil {
+		fmt.Fprintf(f.errOut, "Warning: picture %d can't be drawn: %v\n", request.Picture, err)
+		return
+	}
+
+	if request.Window != 1 {
+		for _, line := range lines {
+			f.lowerWindow.WriteString(line + "\n")
+		}
+		return
+	}
+	for row, line := range lines {
+		for column, r := range line {
+			f.setUpperWindowCell(request.Y-1+row, request.X-1+column, r)
+		}
+	}
+}
+
+// ErasePicture blanks the area of a picture in the upper window, pictures
+// in the lower window have already gone by with the text
+func (f *Frontend) ErasePicture(ctx context.Context, request zmachine.ErasePictureRequest) {
+	if request.Window != 1 {
+		return
+	}
+	for row := range request.Height {
+		for column := range request.Width {
+			f.setUpperWindowCell(request.Y-1+row, request.X-1+column, ' ')
+		}
+	}
+}
+
+func (f *Frontend) setUpperWindowCell(row, column int, r rune) {
+	if row >= 0 && row < len(f.upperWindow) && column >= 0 && column < screenWidth {
+		f.upperWindow[row][column] = r
+		f.upperChanged = true
+	}
+}
+
+// ScrollWindow only scrolls the upper window, the lower window is a stream
+// of text which has already scrolled by
+func (f *Frontend) ScrollWindow(ctx context.Context, request zmachine.ScrollWindowRequest) {
+	if request.Window != 1 || request.Pixels == 0 {
+		return
+	}
+	rows := f.upperWindow[:min(f.screenModel.UpperWindowHeight, len(f.upperWindow))]
+	f.upperWindow = slices.Concat(scrollRows(rows, request.Pixels), f.upperWindow[len(rows):])
+	f.upperChanged = true
+}
+
+// scrollRows moves rows up by n, or down if it's negative, with blank rows
+// coming in behind
+func scrollRows(rows [][]rune, n int) [][]rune {
+	scrolled := make([][]rune, len(rows))
+	for ix := range scrolled {
+		if from := ix + n; from >= 0 && from < len(rows) {
+			scrolled[ix] = rows[from]
+		} else {
+			scrolled[ix] = []rune(strings.Repeat(" ", screenWidth))
+		}
+	}
+	return scrolled
+}
+
+func (f *Frontend) Sound(ctx context.Context, request zmachine.SoundEffectRequest) {}
+
+func (f *Frontend) Warning(ctx context.Context, message zmachine.Warning) {
+	fmt.Fprintf(f.errOut, "Warning: %s\n", message)
+}
+
+func (f *Frontend) RuntimeError(ctx context.Context, message zmachine.RuntimeError) {
+	f.flush()
+	fmt.Fprintf(f.errOut, "Error: %s\n", message)
+}
+
+func (f *Frontend) Quit(ctx context.Context) {
+	f.flush()
+}
+
+func (f *Frontend) Restart(ctx context.Context) {
+	f.lowerWindow.WriteString("\n")
+	f.upperWindow = nil
+	f.upperChanged = false
+	f.statusBar = zmachine.StatusBar{}
+}
@@ -0,0 +1,82 @@
+package dumbterminal
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+func TestRunPlaysScriptedCommands(t *testing.T) {
+	romFileBytes, err := os.ReadFile("../advent.z3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut strings.Builder
+	in := strings.NewReader("no\nquit\ny\n")
+	frontend := New("../advent.z3", in, &out, &errOut)
+	if err := frontend.Run(context.Background(), zmachine.LoadRomWithFrontend(romFileBytes, frontend)); err != nil {
+		t.Fatalf("unexpected error %v (stderr %q)", err, errOut.String())
+	}
+
+	for _, expected := range []string{
+		"At End Of Road                                              Score: 36    Moves 0\n",
+		"You are standing at the end of a road",
+		"Are you sure you want to quit?",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("missing %q in output %q", expected, out.String())
+		}
+	}
+}
+
+func TestRunStopsAtEndOfInput(t *testing.T) {
+	romFileBytes, err := os.ReadFile("../advent.z3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut strings.Builder
+	frontend := New("../advent.z3", strings.NewReader("no\n"), &out, &errOut)
+	if err := frontend.Run(context.Background(), zmachine.LoadRomWithFrontend(romFileBytes, frontend)); err != nil {
+		t.Fatalf("expected running out of input to end the story cleanly, got %v", err)
+	}
+}
+
+func TestReadCharKeepsWholeCharacter(t *testing.T) {
+	frontend := New("../advent.z3", nil, &strings.Builder{}, &strings.Builder{})
+	frontend.lines = readLines(context.Background(), strings.NewReader("éa\n"))
+
+	response, err := frontend.ReadChar(context.Background(), zmachine.CharacterRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Text != "é" {
+		t.Errorf("expected the first character é, got %q", response.Text)
+	}
+}
+
+func TestPromptedAuxiliarySaveKeepsSavedGame(t *testing.T) {
+	// The default files are made in the working directory
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("story.sav", []byte("saved game"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	frontend := New("story.z5", nil, &strings.Builder{}, &strings.Builder{})
+	frontend.lines = readLines(context.Background(), strings.NewReader("\n"))
+	response, err := frontend.Save(context.Background(), zmachine.Save{Prompt: true, Address: 0x100, NumBytes: 2, Data: []byte{1, 2}})
+	if err != nil || !response.Success {
+		t.Fatalf("expected the table to be saved, got %+v %v", response, err)
+	}
+
+	if saved, _ := os.ReadFile("story.sav"); string(saved) != "saved game" {
+		t.Errorf("expected the saved game to be left alone, got %q", saved)
+	}
+	if table, _ := os.ReadFile("story.aux"); string(table) != "\x01\x02" {
+		t.Errorf("expected the table in story.aux, got %q", table)
+	}
+}
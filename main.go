@@ -1,41 +1,70 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"image/color"
 	"math"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/davetcode/goz/blorb"
+	"github.com/davetcode/goz/debugger"
+	"github.com/davetcode/goz/debuginfo"
+	"github.com/davetcode/goz/dumbterminal"
+	"github.com/davetcode/goz/picture"
 	"github.com/davetcode/goz/selectstoryui"
+	"github.com/davetcode/goz/sound"
+	"github.com/davetcode/goz/storyfiles"
 	"github.com/davetcode/goz/zmachine"
 	"github.com/muesli/reflow/wordwrap"
 )
 
 var (
-	romFilePath  string
-	cacheDir     string
-	baseAppStyle lipgloss.Style
+	romFilePath   string
+	cacheDir      string
+	dumbTerminal  bool
+	replayPath    string
+	tracePath     string
+	debugMode     bool
+	debugInfoPath string
+	wavDir        string
+	strictness    string
+	baseAppStyle  lipgloss.Style
 )
 
 type textUpdateMessage string
 type eraseLineRequest zmachine.EraseLineRequest
 type eraseWindowRequest zmachine.EraseWindowRequest
+type scrollWindowRequest zmachine.ScrollWindowRequest
+type drawPictureRequest zmachine.DrawPictureRequest
+type erasePictureRequest zmachine.ErasePictureRequest
 type statusBarMessage zmachine.StatusBar
 type screenModelMessage zmachine.ScreenModel
 type inputRequestMessage zmachine.InputRequest
+type characterRequestMessage zmachine.CharacterRequest
 type saveRequestMessage zmachine.Save
 type restoreRequestMessage zmachine.Restore
+type openTranscriptRequest zmachine.OpenTranscript
+type openCommandRecordingRequest zmachine.OpenCommandRecording
+type openCommandScriptRequest zmachine.OpenCommandScript
 type restartRequest bool
 type runtimeErrorMessage zmachine.RuntimeError
 type warningMessage zmachine.Warning
 type soundEffectRequest zmachine.SoundEffectRequest
 
+// inputTimeoutMessage fires when a timed input request runs out, it's
+// ignored unless the same request is still waiting
+type inputTimeoutMessage int
+
 // keyToZChar maps Bubble Tea key messages to Z-machine character codes.
 // Function keys are mapped according to the Z-machine spec section 10.5.2.1:
 //   - 129-132: Cursor keys (up, down, left, right)
@@ -115,8 +144,10 @@ type runStoryModel struct {
 	sendChannel              chan<- zmachine.InputResponse
 	saveRestoreChannel       chan<- zmachine.SaveRestoreResponse
 	zMachine                 *zmachine.ZMachine
-	romBytes                 []byte
+	interpreterCtx           context.Context
+	stopInterpreter          context.CancelFunc
 	romFilePath              string
+	files                    *storyfiles.Store
 	statusBar                zmachine.StatusBar
 	screenModel              zmachine.ScreenModel
 	lowerWindowTextPreStyled string
@@ -125,6 +156,7 @@ type runStoryModel struct {
 	upperWindowStyle         [][]lipgloss.Style
 	appState                 runningStoryState
 	validTerminators         []uint8 // Valid terminating characters for current input
+	inputRequestID           int     // Incremented on every input request so stale timeouts can be ignored
 	inputBox                 textinput.Model
 	width                    int
 	height                   int
@@ -138,7 +170,7 @@ type runStoryModel struct {
 func (m runStoryModel) Init() tea.Cmd {
 	return tea.Batch(
 		waitForInterpreter(m.outputChannel),
-		runInterpreter(m.zMachine),
+		runInterpreter(m.interpreterCtx, m.zMachine),
 		tea.Sequence(
 			tea.SetWindowTitle(romFilePath),
 			tea.WindowSize(),
@@ -146,9 +178,9 @@ func (m runStoryModel) Init() tea.Cmd {
 	)
 }
 
-func runInterpreter(z *zmachine.ZMachine) tea.Cmd {
+func runInterpreter(ctx context.Context, z *zmachine.ZMachine) tea.Cmd {
 	return func() tea.Msg {
-		z.Run()
+		z.RunContext(ctx) // nolint:errcheck
 
 		return nil
 	}
@@ -188,6 +220,7 @@ func (m runStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" {
+			m.stopInterpreter()
 			return m, tea.Quit
 		}
 
@@ -217,41 +250,20 @@ func (m runStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case textUpdateMessage:
+		text := m.screenModel.CurrentFont.Glyphs(string(msg))
 		if m.screenModel.LowerWindowActive {
 			// In anything other than v6 the bottom window is append only (I think - TODO)
-			m.lowerWindowText += string(msg)
+			m.lowerWindowText += text
 		} else {
 			// Upper window - handle text, splitting on newlines
-			text := string(msg)
 			segments := strings.Split(text, "\n")
 			cursorX := m.screenModel.UpperWindowCursorX
 			cursorY := m.screenModel.UpperWindowCursorY
 
 			for segIdx, segment := range segments {
-				if cursorY >= 0 && cursorY < len(m.upperWindowText) {
-					row := m.upperWindowText[cursorY]
-
-					// Update styles for each character being written
-					if cursorY < len(m.upperWindowStyle) {
-						for i := 0; i < len(segment) && cursorX+i < len(m.upperWindowStyle[cursorY]); i++ {
-							m.upperWindowStyle[cursorY][cursorX+i] = m.upperWindowStyleCurrent
-						}
-					}
-
-					if cursorX < len(row) {
-						before := row[:cursorX]
-						// Replace characters at cursor position (not insert)
-						afterStart := cursorX + len(segment)
-						after := ""
-						if afterStart < len(row) {
-							after = row[afterStart:]
-						}
-						fullText := before + segment + after
-						if len(fullText) > m.width {
-							fullText = fullText[:m.width]
-						}
-						m.upperWindowText[cursorY] = fullText
-					}
+				// Replace characters at cursor position (not insert)
+				for i, r := range []rune(segment) {
+					m.setUpperWindowCell(cursorY, cursorX+i, r, m.upperWindowStyleCurrent)
 				}
 
 				// After each segment (except the last), move to next line
@@ -267,51 +279,66 @@ func (m runStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case inputRequestMessage:
 		m.appState = appWaitingForInput
 		m.validTerminators = msg.ValidTerminators
-		return m, waitForInterpreter(m.outputChannel)
+		m.inputBox.SetValue(msg.InitialText)
+		m.inputBox.CursorEnd()
+		m.inputRequestID++
+		return m, tea.Batch(waitForInterpreter(m.outputChannel), inputTimeout(m.inputRequestID, msg.Timeout))
+
+	case characterRequestMessage:
+		m.appState = appWaitingForCharacter
+		m.inputRequestID++
+		return m, tea.Batch(waitForInterpreter(m.outputChannel), inputTimeout(m.inputRequestID, msg.Timeout))
+
+	case inputTimeoutMessage:
+		if int(msg) != m.inputRequestID {
+			return m, nil
+		}
+		switch m.appState {
+		case appWaitingForInput:
+			// The partial input comes back as InitialText on the next request
+			m.appState = appRunning
+			m.sendChannel <- zmachine.InputResponse{Text: m.inputBox.Value(), TimedOut: true}
+			m.inputBox.SetValue("")
+		case appWaitingForCharacter:
+			m.appState = appRunning
+			m.sendChannel <- zmachine.InputResponse{TimedOut: true}
+		}
+		return m, nil
 
 	case saveRequestMessage:
-		if msg.NumBytes == 0 {
-			saveData := m.zMachine.ExportSaveState()
-			filename := msg.Filename
-			if filename == "" {
-				filename = m.defaultSaveFilename()
-			}
-			// TODO: If msg.Prompt is true, ask user for filename
-			err := os.WriteFile(filename, saveData, 0644)
-			if err != nil {
-				m.saveRestoreChannel <- zmachine.SaveResponse{Success: false, Result: 0}
-			} else {
-				m.saveRestoreChannel <- zmachine.SaveResponse{Success: true, Result: 1}
-			}
-		} else {
-			// TODO: Implement auxiliary save
-			m.saveRestoreChannel <- zmachine.SaveResponse{Success: false, Result: 0}
-		}
+		m.saveRestoreChannel <- m.files.Save(zmachine.Save(msg))
 		return m, waitForInterpreter(m.outputChannel)
 
 	case restoreRequestMessage:
-		if msg.NumBytes == 0 {
-			filename := msg.Filename
-			if filename == "" {
-				filename = m.defaultSaveFilename()
-			}
-			// TODO: If msg.Prompt is true, ask user for filename
-			data, err := os.ReadFile(filename)
-			if err != nil {
-				m.saveRestoreChannel <- zmachine.RestoreResponse{Success: false, Result: 0}
-			} else {
-				m.saveRestoreChannel <- zmachine.RestoreResponse{Success: true, Result: 2, Data: data}
-			}
-		} else {
-			// TODO: Implement auxiliary restore
-			m.saveRestoreChannel <- zmachine.RestoreResponse{Success: false, Result: 0}
+		m.saveRestoreChannel <- m.files.Restore(zmachine.Restore(msg))
+		return m, waitForInterpreter(m.outputChannel)
+
+	case openTranscriptRequest:
+		transcript, err := m.files.OpenTranscript()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to open transcript: %v\n", err)
 		}
+		m.saveRestoreChannel <- zmachine.TranscriptResponse{Transcript: transcript}
+		return m, waitForInterpreter(m.outputChannel)
+
+	case openCommandRecordingRequest:
+		recording, err := m.files.OpenCommandRecording()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to open command recording: %v\n", err)
+		}
+		m.saveRestoreChannel <- zmachine.CommandRecordingResponse{Recording: recording}
+		return m, waitForInterpreter(m.outputChannel)
+
+	case openCommandScriptRequest:
+		script, err := m.files.OpenCommandScript()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to open command file: %v\n", err)
+		}
+		m.saveRestoreChannel <- zmachine.CommandScriptResponse{Script: script}
 		return m, waitForInterpreter(m.outputChannel)
 
 	case zmachine.StateChangeRequest:
 		switch msg {
-		case zmachine.WaitForCharacter:
-			m.appState = appWaitingForCharacter
 		case zmachine.Running:
 			m.appState = appRunning
 		}
@@ -365,16 +392,7 @@ func (m runStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, waitForInterpreter(m.outputChannel)
 
 	case restartRequest:
-		// Reload the ROM from stored bytes
-		zMachineOutputChannel := make(chan any)
-		zMachineInputChannel := make(chan zmachine.InputResponse)
-		zMachineSaveRestoreChannel := make(chan zmachine.SaveRestoreResponse)
-		m.zMachine = zmachine.LoadRom(m.romBytes, zMachineInputChannel, zMachineSaveRestoreChannel, zMachineOutputChannel)
-		m.outputChannel = zMachineOutputChannel
-		m.sendChannel = zMachineInputChannel
-		m.saveRestoreChannel = zMachineSaveRestoreChannel
-
-		// Clear screen state
+		// The interpreter restarts the story itself, just clear the screen state
 		m.lowerWindowText = ""
 		m.lowerWindowTextPreStyled = ""
 		for row := range len(m.upperWindowText) {
@@ -382,10 +400,7 @@ func (m runStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.upperWindowStyle[row] = slices.Repeat([]lipgloss.Style{baseAppStyle}, m.width)
 		}
 		m.appState = appRunning
-		return m, tea.Batch(
-			waitForInterpreter(m.outputChannel),
-			runInterpreter(m.zMachine),
-		)
+		return m, waitForInterpreter(m.outputChannel)
 
 	case eraseLineRequest:
 		// Don't think you can erase line in lower window
@@ -407,6 +422,8 @@ func (m runStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		return m, waitForInterpreter(m.outputChannel)
+
 	case eraseWindowRequest:
 		switch int(msg) {
 		case -2: // Keep split windows and clear both
@@ -431,6 +448,7 @@ func (m runStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.upperWindowText[row] = strings.Repeat(" ", m.width)
 				m.upperWindowStyle[row] = slices.Repeat([]lipgloss.Style{baseAppStyle}, m.width)
 			}
+		case 2, 3, 4, 5, 6, 7: // V6 windows, their text is shown in the lower window
 		default:
 			m.runtimeError = fmt.Sprintf("Unexpected erase_window value: %d", int(msg))
 			return m, tea.Quit
@@ -438,8 +456,71 @@ func (m runStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, waitForInterpreter(m.outputChannel)
 
+	case scrollWindowRequest:
+		// Only the upper window is drawn as rows, the lower window scrolls as text is added
+		if msg.Window == 1 && msg.Pixels != 0 {
+			rows := len(m.upperWindowText)
+			text := make([]string, rows)
+			style := make([][]lipgloss.Style, rows)
+			for row := range rows {
+				if from := row + msg.Pixels; from >= 0 && from < rows {
+					text[row] = m.upperWindowText[from]
+					style[row] = m.upperWindowStyle[from]
+				} else {
+					text[row] = strings.Repeat(" ", m.width)
+					style[row] = slices.Repeat([]lipgloss.Style{baseAppStyle}, m.width)
+				}
+			}
+			m.upperWindowText = text
+			m.upperWindowStyle = style
+		}
+
+		return m, waitForInterpreter(m.outputChannel)
+
+	case drawPictureRequest:
+		cells, err := picture.HalfBlocks(msg.Data, msg.Width, msg.Height)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: picture %d can't be drawn: %v\n", msg.Picture, err)
+			return m, waitForInterpreter(m.outputChannel)
+		}
+
+		if msg.Window == 1 {
+			for row, cellRow := range cells {
+				for column, cell := range cellRow {
+					m.setUpperWindowCell(msg.Y-1+row, msg.X-1+column, picture.HalfBlock, halfBlockStyle(cell))
+				}
+			}
+		} else {
+			// Everywhere else is the lower window, which the picture is added to like text
+			prerenderLowerWindowText(&m)
+			var lines strings.Builder
+			for _, cellRow := range cells {
+				for _, cell := range cellRow {
+					lines.WriteString(halfBlockStyle(cell).Render(string(picture.HalfBlock)))
+				}
+				lines.WriteString("\n")
+			}
+			m.lowerWindowTextPreStyled += lines.String()
+		}
+
+		return m, waitForInterpreter(m.outputChannel)
+
+	case erasePictureRequest:
+		// Pictures in the lower window have scrolled into the text, only the upper window can be erased
+		if msg.Window == 1 {
+			style := baseAppStyle.Background(lipgloss.Color(msg.Background.ToHex()))
+			for row := range msg.Height {
+				for column := range msg.Width {
+					m.setUpperWindowCell(msg.Y-1+row, msg.X-1+column, ' ', style)
+				}
+			}
+		}
+
+		return m, waitForInterpreter(m.outputChannel)
+
 	case runtimeErrorMessage:
 		m.runtimeError = string(msg)
+		m.stopInterpreter()
 		return m, tea.Quit
 
 	case warningMessage:
@@ -454,10 +535,10 @@ func (m runStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case 2: // Low pitched beep, no repeats, volume etc
 			fmt.Print("\a")
 		default:
-			// Not supporting other sound effects at the moment
+			// Sampled sounds only reach here without -wav to play them
 			if msg.Routine != 0 {
 				// Warnings are non-fatal - print to stderr and continue
-				fmt.Fprintf(os.Stderr, "Warning: sound effect (%d) expecting routine call after completion not supported\n", msg.Effect)
+				fmt.Fprintf(os.Stderr, "Warning: sound %d can't be played without -wav, its routine won't be called\n", msg.SoundNumber)
 			}
 		}
 
@@ -471,6 +552,29 @@ func (m runStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// setUpperWindowCell writes a character to the upper window grid, ignoring
+// anything off the edges
+func (m *runStoryModel) setUpperWindowCell(row, column int, r rune, style lipgloss.Style) {
+	if row < 0 || row >= len(m.upperWindowText) || row >= len(m.upperWindowStyle) {
+		return
+	}
+	runes := []rune(m.upperWindowText[row])
+	if column < 0 || column >= len(runes) || column >= len(m.upperWindowStyle[row]) {
+		return
+	}
+	runes[column] = r
+	m.upperWindowText[row] = string(runes)
+	m.upperWindowStyle[row][column] = style
+}
+
+// halfBlockStyle colours a half block with the top and bottom of a picture cell
+func halfBlockStyle(cell picture.Cell) lipgloss.Style {
+	hex := func(c color.RGBA) lipgloss.Color {
+		return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+	}
+	return baseAppStyle.Foreground(hex(cell.Top)).Background(hex(cell.Bottom))
+}
+
 func prerenderLowerWindowText(m *runStoryModel) {
 	if m.lowerWindowText != "" {
 		lines := strings.Split(m.lowerWindowText, "\n")
@@ -482,42 +586,6 @@ func prerenderLowerWindowText(m *runStoryModel) {
 	}
 }
 
-// defaultSaveFilename derives a save filename from the ROM file path.
-// It replaces the .z* extension with .sav, e.g., "zork1.z1" -> "zork1.sav"
-func (m runStoryModel) defaultSaveFilename() string {
-	if m.romFilePath == "" {
-		return "game.sav"
-	}
-	base := filepath.Base(m.romFilePath)
-	// Remove .z* extension (z1, z2, z3, z4, z5, z6, z7, z8)
-	ext := filepath.Ext(base)
-	if len(ext) >= 2 && (ext[1] == 'z' || ext[1] == 'Z') {
-		base = base[:len(base)-len(ext)]
-	}
-	return base + ".sav"
-}
-
-func createStatusLine(width int, placeName string, scoreOrHours int, movesOrMinutes int, isTimeBasedGame bool) string {
-	rightHandSide := fmt.Sprintf("Score: %d    Moves %d", scoreOrHours, movesOrMinutes)
-
-	if isTimeBasedGame {
-		rightHandSide = fmt.Sprintf("Time: %d:%d", scoreOrHours, movesOrMinutes)
-	}
-
-	// Too narrow to show properly so just show as much of the score/time/moves as we can manage
-	if len(rightHandSide) >= width {
-		return rightHandSide[:width]
-	}
-
-	if len(placeName)+len(rightHandSide)+1 >= width {
-		return fmt.Sprintf("%s %s", placeName[:width-len(rightHandSide)-1], rightHandSide)
-	}
-
-	numberSpaces := width - len(placeName) - len(rightHandSide)
-
-	return fmt.Sprintf("%s%s%s", placeName, strings.Repeat(" ", numberSpaces), rightHandSide)
-}
-
 func (m runStoryModel) View() string {
 	// If there was a runtime error, display it
 	if m.runtimeError != "" {
@@ -536,7 +604,7 @@ func (m runStoryModel) View() string {
 	lowerWindowHeight := m.height
 
 	if m.statusBar.PlaceName != "" {
-		s.WriteString(m.statusBarStyle.Render(createStatusLine(m.width, m.statusBar.PlaceName, m.statusBar.Score, m.statusBar.Moves, m.statusBar.IsTimeBased)))
+		s.WriteString(m.statusBarStyle.Render(m.statusBar.Text(m.width)))
 		s.WriteString(m.lowerWindowStyle.Render("\n"))
 		lowerWindowHeight -= 2 // 2 fewer lines to work with if there's a status bar
 	} else {
@@ -594,20 +662,49 @@ func (m runStoryModel) View() string {
 		Render(s.String())
 }
 
+// inputTimeout ticks once the timeout on an input request has passed, there's
+// nothing to wait for if the request isn't timed
+func inputTimeout(requestID int, timeout time.Duration) tea.Cmd {
+	if timeout <= 0 {
+		return nil
+	}
+	return tea.Tick(timeout, func(time.Time) tea.Msg {
+		return inputTimeoutMessage(requestID)
+	})
+}
+
 func waitForInterpreter(sub <-chan any) tea.Cmd {
 	return func() tea.Msg {
 		msg := <-sub
 		switch msg := msg.(type) {
 		case zmachine.InputRequest:
 			return inputRequestMessage(msg)
+		case zmachine.CharacterRequest:
+			return characterRequestMessage(msg)
 		case zmachine.Save:
 			return saveRequestMessage(msg)
 		case zmachine.Restore:
 			return restoreRequestMessage(msg)
+		case zmachine.OpenTranscript:
+			return openTranscriptRequest(msg)
+		case zmachine.OpenCommandRecording:
+			return openCommandRecordingRequest(msg)
+		case zmachine.OpenCommandScript:
+			return openCommandScriptRequest(msg)
 		case zmachine.StateChangeRequest:
 			return msg // Pass through directly, handled in Update
 		case zmachine.EraseWindowRequest:
 			return eraseWindowRequest(msg)
+		case zmachine.EraseLineRequest:
+			return eraseLineRequest(msg)
+		case zmachine.ScrollWindowRequest:
+			return scrollWindowRequest(msg)
+		case zmachine.DrawPictureRequest:
+			return drawPictureRequest(msg)
+		case zmachine.ErasePictureRequest:
+			return erasePictureRequest(msg)
+		case zmachine.SoundEffectRequest:
+			return soundEffectRequest(msg)
 		case zmachine.StatusBar:
 			return statusBarMessage(msg)
 		case zmachine.ScreenModel:
@@ -631,10 +728,17 @@ func waitForInterpreter(sub <-chan any) tea.Cmd {
 func init() {
 	flag.StringVar(&romFilePath, "rom", "", "The path of a z-machine rom")
 	flag.StringVar(&cacheDir, "cache", "", "Directory to cache downloaded stories (cached for 7 days)")
+	flag.BoolVar(&dumbTerminal, "dumb", false, "Play the -rom story as plain text over stdin/stdout instead of the full screen UI")
+	flag.StringVar(&replayPath, "replay", "", "Command file to take input from before falling back to the keyboard, requires -rom")
+	flag.StringVar(&tracePath, "trace", "", "File to write every executed instruction to, requires -rom")
+	flag.BoolVar(&debugMode, "debug", false, "Debug the -rom story from a command line sharing stdin/stdout, implies -dumb")
+	flag.StringVar(&debugInfoPath, "debuginfo", "", "Inform debug information file (gameinfo.dbg) naming routines and variables in traces, errors and the debugger, requires -rom")
+	flag.StringVar(&wavDir, "wav", "", "Directory to write the story's sampled sounds to as WAV files as they play, requires -rom")
+	flag.StringVar(&strictness, "strict", "warn", "What to do when the story writes to memory it mustn't change: ignore, warn or fatal")
 	flag.Parse()
 }
 
-func newApplicationModel(zMachine *zmachine.ZMachine, inputChannel chan<- zmachine.InputResponse, saveRestoreChannel chan<- zmachine.SaveRestoreResponse, outputChannel <-chan any, romBytes []byte, romPath string) tea.Model {
+func newApplicationModel(zMachine *zmachine.ZMachine, inputChannel chan<- zmachine.InputResponse, saveRestoreChannel chan<- zmachine.SaveRestoreResponse, outputChannel <-chan any, romPath string) tea.Model {
 
 	ti := textinput.New()
 	ti.Focus()
@@ -642,13 +746,17 @@ func newApplicationModel(zMachine *zmachine.ZMachine, inputChannel chan<- zmachi
 	ti.Width = 20
 	ti.Prompt = ""
 
+	interpreterCtx, stopInterpreter := context.WithCancel(context.Background())
+
 	return runStoryModel{
 		outputChannel:           outputChannel,
 		sendChannel:             inputChannel,
 		saveRestoreChannel:      saveRestoreChannel,
 		zMachine:                zMachine,
-		romBytes:                romBytes,
+		interpreterCtx:          interpreterCtx,
+		stopInterpreter:         stopInterpreter,
 		romFilePath:             romPath,
+		files:                   storyfiles.New(romPath),
 		appState:                appRunning,
 		validTerminators:        []uint8{13}, // Default to just Enter
 		inputBox:                ti,
@@ -660,10 +768,15 @@ func newApplicationModel(zMachine *zmachine.ZMachine, inputChannel chan<- zmachi
 }
 
 func main() {
+	if dumbTerminal || debugMode {
+		runDumbTerminal()
+		return
+	}
+
 	var model tea.Model
 
 	if romFilePath != "" {
-		romFileBytes, err := os.ReadFile(romFilePath)
+		romFileBytes, resources, err := readStory(romFilePath)
 		if err != nil {
 			panic(err)
 		}
@@ -671,8 +784,26 @@ func main() {
 		zMachineInputChannel := make(chan zmachine.InputResponse)
 		zMachineSaveRestoreChannel := make(chan zmachine.SaveRestoreResponse)
 		zMachine := zmachine.LoadRom(romFileBytes, zMachineInputChannel, zMachineSaveRestoreChannel, zMachineOutputChannel)
+		zMachine.SetResources(resources)
+		if err := protectMemory(zMachine); err != nil {
+			panic(err)
+		}
+		if err := replayCommands(zMachine); err != nil {
+			panic(err)
+		}
+		if _, err := loadDebugInfo(zMachine); err != nil {
+			panic(err)
+		}
+		if err := playSounds(zMachine, resources); err != nil {
+			panic(err)
+		}
+		trace, err := traceInstructions(zMachine)
+		if err != nil {
+			panic(err)
+		}
+		defer trace.Close() // nolint:errcheck
 
-		model = newApplicationModel(zMachine, zMachineInputChannel, zMachineSaveRestoreChannel, zMachineOutputChannel, romFileBytes, romFilePath)
+		model = newApplicationModel(zMachine, zMachineInputChannel, zMachineSaveRestoreChannel, zMachineOutputChannel, romFilePath)
 	} else {
 		model = selectstoryui.NewUIModel(newApplicationModel, cacheDir)
 	}
@@ -684,3 +815,141 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+func runDumbTerminal() {
+	if romFilePath == "" {
+		fmt.Fprintln(os.Stderr, "-dumb and -debug require a story file to be given with -rom")
+		os.Exit(2)
+	}
+
+	romFileBytes, resources, err := readStory(romFilePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading story file:", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	frontend := dumbterminal.New(romFilePath, os.Stdin, os.Stdout, os.Stderr)
+	zMachine := zmachine.LoadRomWithFrontend(romFileBytes, frontend)
+	zMachine.SetResources(resources)
+	if err := protectMemory(zMachine); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := replayCommands(zMachine); err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading command file:", err)
+		os.Exit(1)
+	}
+	info, err := loadDebugInfo(zMachine)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading debug information:", err)
+		os.Exit(1)
+	}
+	if err := playSounds(zMachine, resources); err != nil {
+		fmt.Fprintln(os.Stderr, "Error creating sound directory:", err)
+		os.Exit(1)
+	}
+	trace, err := traceInstructions(zMachine)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error creating trace file:", err)
+		os.Exit(1)
+	}
+	defer trace.Close() // nolint:errcheck
+
+	if debugMode {
+		console := debugger.NewConsole(zMachine, func() (string, error) { return frontend.ReadCommand(ctx) }, frontend)
+		if info != nil {
+			console.SetDebugInfo(info)
+		}
+	}
+
+	if err := frontend.Run(ctx, zMachine); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, debugger.ErrQuit) {
+		os.Exit(1)
+	}
+}
+
+// readStory reads a bare story file or the story from a Blorb, along with
+// the Blorb's resources
+func readStory(path string) ([]byte, *blorb.Blorb, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return blorb.Unwrap(data)
+}
+
+// playSounds plays sampled sounds into the -wav directory, if given, taking
+// them from the Blorb or else from Infocom sound files next to the story
+func playSounds(zMachine *zmachine.ZMachine, resources *blorb.Blorb) error {
+	if wavDir == "" {
+		return nil
+	}
+	sink, err := sound.NewWAVSink(wavDir)
+	if err != nil {
+		return err
+	}
+	source := sound.FromFiles(romFilePath)
+	if resources != nil {
+		source = sound.FromBlorb(resources)
+	}
+	zMachine.SetSoundSystem(sound.NewPlayer(sink, source))
+	return nil
+}
+
+// protectMemory sets what happens to writes to protected memory from -strict
+func protectMemory(zMachine *zmachine.ZMachine) error {
+	switch strictness {
+	case "ignore":
+		zMachine.SetMemoryStrictness(zmachine.MemoryStrictnessIgnore)
+	case "warn":
+		zMachine.SetMemoryStrictness(zmachine.MemoryStrictnessWarn)
+	case "fatal":
+		zMachine.SetMemoryStrictness(zmachine.MemoryStrictnessFatal)
+	default:
+		return fmt.Errorf("-strict must be ignore, warn or fatal, not %q", strictness)
+	}
+	return nil
+}
+
+// replayCommands points the machine at the -replay command file, if given
+func replayCommands(zMachine *zmachine.ZMachine) error {
+	if replayPath == "" {
+		return nil
+	}
+	script, err := os.Open(replayPath)
+	if err != nil {
+		return err
+	}
+	zMachine.SetCommandScript(script)
+	return nil
+}
+
+// loadDebugInfo names routines and variables from the -debuginfo file, if
+// given, the returned info is nil if there isn't one
+func loadDebugInfo(zMachine *zmachine.ZMachine) (*debuginfo.Info, error) {
+	if debugInfoPath == "" {
+		return nil, nil
+	}
+	info, err := debuginfo.Load(debugInfoPath, &zMachine.Core)
+	if err != nil {
+		return nil, err
+	}
+	zMachine.SetSymbols(info)
+	return info, nil
+}
+
+// traceInstructions writes every instruction the machine executes to the
+// -trace file, the file is nil if there isn't one
+func traceInstructions(zMachine *zmachine.ZMachine) (*os.File, error) {
+	if tracePath == "" {
+		return nil, nil
+	}
+	trace, err := os.Create(tracePath)
+	if err != nil {
+		return nil, err
+	}
+	zMachine.SetTracer(zmachine.NewWriterTracer(trace))
+	return trace, nil
+}
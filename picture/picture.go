@@ -0,0 +1,105 @@
+// Package picture reads the PNG and JPEG pictures stored in a Blorb and
+// draws them as text for frontends on a terminal, either with half blocks,
+// two coloured pixels to a character cell, or as plain ASCII shading.
+package picture
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/jpeg" // Registers the formats Blorb allows for pictures
+	_ "image/png"
+	"strings"
+)
+
+// HalfBlock is drawn in a cell's top colour over its bottom colour
+const HalfBlock = '▀'
+
+// ramp shades ASCII pictures from dark to light
+const ramp = " .:-=+*#%@"
+
+// Cell is a character cell of a picture drawn with half blocks
+type Cell struct {
+	Top    color.RGBA
+	Bottom color.RGBA
+}
+
+// Size is the width and height of a picture in pixels
+func Size(data []byte) (int, int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+// HalfBlocks scales a picture to fit columns by rows character cells, each
+// cell averaging the pixels of the top and bottom halves it covers
+func HalfBlocks(data []byte, columns, rows int) ([][]Cell, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make([][]Cell, rows)
+	for row := range cells {
+		cells[row] = make([]Cell, columns)
+		for column := range cells[row] {
+			cells[row][column] = Cell{
+				Top:    average(img, column, 2*row, columns, 2*rows),
+				Bottom: average(img, column, 2*row+1, columns, 2*rows),
+			}
+		}
+	}
+	return cells, nil
+}
+
+// ASCII scales a picture to fit columns by rows characters, shading each by
+// how bright the pixels it covers are
+func ASCII(data []byte, columns, rows int) ([]string, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, rows)
+	for row := range lines {
+		var line strings.Builder
+		for column := range columns {
+			c := average(img, column, row, columns, rows)
+			luminance := (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+			line.WriteByte(ramp[luminance*(len(ramp)-1)/255])
+		}
+		lines[row] = line.String()
+	}
+	return lines, nil
+}
+
+// average is the colour of the part of img at column x, row y when it's cut
+// into a grid of columns by rows, transparent pixels count as black
+func average(img image.Image, x, y, columns, rows int) color.RGBA {
+	bounds := img.Bounds()
+	area := image.Rect(
+		bounds.Min.X+x*bounds.Dx()/columns, bounds.Min.Y+y*bounds.Dy()/rows,
+		bounds.Min.X+(x+1)*bounds.Dx()/columns, bounds.Min.Y+(y+1)*bounds.Dy()/rows,
+	)
+	// Pictures smaller than the grid repeat pixels rather than leave gaps
+	area.Max.X = max(area.Max.X, area.Min.X+1)
+	area.Max.Y = max(area.Max.Y, area.Min.Y+1)
+	area = area.Intersect(bounds)
+	if area.Empty() {
+		return color.RGBA{A: 0xff}
+	}
+
+	var r, g, b, count uint64
+	for py := area.Min.Y; py < area.Max.Y; py++ {
+		for px := area.Min.X; px < area.Max.X; px++ {
+			pr, pg, pb, _ := img.At(px, py).RGBA()
+			r += uint64(pr)
+			g += uint64(pg)
+			b += uint64(pb)
+			count++
+		}
+	}
+	return color.RGBA{R: uint8(r / count >> 8), G: uint8(g / count >> 8), B: uint8(b / count >> 8), A: 0xff}
+}
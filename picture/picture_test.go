@@ -0,0 +1,75 @@
+package picture
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// encodePNG makes a width by height PNG coloured by at
+func encodePNG(t *testing.T, width, height int, at func(x, y int) color.RGBA) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetRGBA(x, y, at(x, y))
+		}
+	}
+	var data bytes.Buffer
+	if err := png.Encode(&data, img); err != nil {
+		t.Fatal(err)
+	}
+	return data.Bytes()
+}
+
+var (
+	red   = color.RGBA{R: 0xff, A: 0xff}
+	blue  = color.RGBA{B: 0xff, A: 0xff}
+	white = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	black = color.RGBA{A: 0xff}
+)
+
+func TestHalfBlocks(t *testing.T) {
+	// Red over blue on the left, all blue on the right
+	data := encodePNG(t, 8, 4, func(x, y int) color.RGBA {
+		if x < 4 && y < 2 {
+			return red
+		}
+		return blue
+	})
+
+	if width, height, err := Size(data); err != nil || width != 8 || height != 4 {
+		t.Errorf("expected an 8x4 picture, got %dx%d %v", width, height, err)
+	}
+
+	cells, err := HalfBlocks(data, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]Cell{{{red, blue}, {blue, blue}}}; len(cells) != 1 || cells[0][0] != want[0][0] || cells[0][1] != want[0][1] {
+		t.Errorf("expected %v, got %v", want, cells)
+	}
+}
+
+func TestASCII(t *testing.T) {
+	data := encodePNG(t, 4, 4, func(x, y int) color.RGBA {
+		if x < 2 {
+			return white
+		}
+		return black
+	})
+
+	lines, err := ASCII(data, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0] != "@@  " || lines[1] != "@@  " {
+		t.Errorf("expected white on the left and black on the right, got %q", lines)
+	}
+
+	if _, err := ASCII([]byte("not a picture"), 1, 1); err == nil {
+		t.Errorf("expected a picture which can't be decoded to fail")
+	}
+}
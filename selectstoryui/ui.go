@@ -18,6 +18,7 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/davetcode/goz/blorb"
 	"github.com/davetcode/goz/zmachine"
 )
 
@@ -52,7 +53,7 @@ type selectStoryModel struct {
 	storyList              list.Model
 	spinner                spinner.Model
 	err                    error
-	createApplicationModel func(*zmachine.ZMachine, chan<- zmachine.InputResponse, chan<- zmachine.SaveRestoreResponse, <-chan any, []byte, string) tea.Model
+	createApplicationModel func(*zmachine.ZMachine, chan<- zmachine.InputResponse, chan<- zmachine.SaveRestoreResponse, <-chan any, string) tea.Model
 	selectedStoryName      string
 	cacheDir               string
 }
@@ -64,7 +65,7 @@ type errMsg struct{ error }
 
 func (e errMsg) Error() string { return e.error.Error() }
 
-func NewUIModel(createAppModel func(*zmachine.ZMachine, chan<- zmachine.InputResponse, chan<- zmachine.SaveRestoreResponse, <-chan any, []byte, string) tea.Model, cacheDir string) tea.Model {
+func NewUIModel(createAppModel func(*zmachine.ZMachine, chan<- zmachine.InputResponse, chan<- zmachine.SaveRestoreResponse, <-chan any, string) tea.Model, cacheDir string) tea.Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
@@ -112,9 +113,15 @@ func (m selectStoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		zMachineOutputChannel := make(chan any)
 		zMachineInputChannel := make(chan zmachine.InputResponse)
 		zMachineSaveRestoreChannel := make(chan zmachine.SaveRestoreResponse)
-		zMachine := zmachine.LoadRom([]uint8(msg), zMachineInputChannel, zMachineSaveRestoreChannel, zMachineOutputChannel)
+		story, resources, err := blorb.Unwrap([]uint8(msg))
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		zMachine := zmachine.LoadRom(story, zMachineInputChannel, zMachineSaveRestoreChannel, zMachineOutputChannel)
+		zMachine.SetResources(resources)
 
-		newModel := m.createApplicationModel(zMachine, zMachineInputChannel, zMachineSaveRestoreChannel, zMachineOutputChannel, []byte(msg), m.selectedStoryName)
+		newModel := m.createApplicationModel(zMachine, zMachineInputChannel, zMachineSaveRestoreChannel, zMachineOutputChannel, m.selectedStoryName)
 		return newModel, newModel.Init()
 
 	case errMsg:
@@ -270,7 +277,8 @@ func downloadStoryList(cacheDir string) tea.Cmd {
 			// For each item found, get the title
 			title := strings.Replace(s.Find("a").Text(), "◆", "", 1)
 			href, _ := s.Find("a").Attr("href")
-			match, _ := regexp.Match(".*\\.z[12345678]", []byte(href))
+			// Bare story files and stories released in a Blorb
+			match, _ := regexp.Match(".*\\.(z[12345678]|zblorb|zlb|blb)", []byte(href))
 
 			if match {
 				re := regexp.MustCompile(`\d{2}-\w{3}-\d{4}`)
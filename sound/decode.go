@@ -0,0 +1,134 @@
+package sound
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// decodeAIFF reads the uncompressed AIFF used for Blorb sounds, a FORM
+// holding a COMM chunk describing the samples and an SSND chunk with them
+func decodeAIFF(data []byte) (*PCM, error) {
+	if len(data) < 12 || string(data[0:4]) != "FORM" || string(data[8:12]) != "AIFF" {
+		return nil, errors.New("not an AIFF file")
+	}
+
+	var pcm *PCM
+	var frames int
+	var samples []byte
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		length := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+		start := offset + 8
+		if length > len(data)-start {
+			return nil, errors.New("AIFF chunk runs past the end of the file")
+		}
+		chunk := data[start : start+length]
+
+		switch id {
+		case "COMM":
+			if len(chunk) < 18 {
+				return nil, errors.New("AIFF COMM chunk is too short")
+			}
+			pcm = &PCM{
+				Channels:      int(binary.BigEndian.Uint16(chunk[0:2])),
+				BitsPerSample: int(binary.BigEndian.Uint16(chunk[6:8])),
+				SampleRate:    int(extendedFloat(chunk[8:18])),
+			}
+			frames = int(binary.BigEndian.Uint32(chunk[2:6]))
+		case "SSND":
+			if len(chunk) < 8 {
+				return nil, errors.New("AIFF SSND chunk is too short")
+			}
+			skip := int(binary.BigEndian.Uint32(chunk[0:4]))
+			if skip > len(chunk)-8 {
+				return nil, errors.New("AIFF SSND offset is past its samples")
+			}
+			samples = chunk[8+skip:]
+		}
+		offset = start + length + length%2
+	}
+	if pcm == nil || samples == nil {
+		return nil, errors.New("AIFF file has no COMM or SSND chunk")
+	}
+	if pcm.Channels == 0 || pcm.BitsPerSample == 0 || pcm.BitsPerSample > 32 {
+		return nil, errors.New("AIFF file has an unsupported sample format")
+	}
+
+	sampleSize := (pcm.BitsPerSample + 7) / 8
+	samples = samples[:min(len(samples), frames*pcm.Channels*sampleSize)]
+	pcm.Samples = make([]byte, len(samples)-len(samples)%sampleSize)
+	for ix := 0; ix < len(pcm.Samples); ix += sampleSize {
+		if sampleSize == 1 {
+			// AIFF's 8 bit samples are signed, WAV's unsigned
+			pcm.Samples[ix] = samples[ix] + 128
+			continue
+		}
+		for b := range sampleSize {
+			pcm.Samples[ix+b] = samples[ix+sampleSize-1-b]
+		}
+	}
+	return pcm, nil
+}
+
+// extendedFloat reads the 80 bit IEEE 754 extended precision number AIFF
+// gives the sample rate as
+func extendedFloat(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:2]) & 0x7fff)
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+	value := math.Ldexp(float64(mantissa), exponent-16383-63)
+	if b[0]&0x80 != 0 {
+		value = -value
+	}
+	return value
+}
+
+// decodeInfocom reads Infocom's sound files, as Frotz does: a 10 byte header
+// of the length of what follows it, how many times to play the sound, its
+// base note, the sample rate, an unused word and the number of samples, all
+// followed by 8 bit unsigned mono samples. Zero repeats is forever.
+func decodeInfocom(data []byte) (*PCM, int, error) {
+	if len(data) < 10 {
+		return nil, 0, errors.New("sound file is too short")
+	}
+	repeats := int(data[2])
+	rate := int(binary.BigEndian.Uint16(data[4:6]))
+	length := int(binary.BigEndian.Uint16(data[8:10]))
+	if rate == 0 {
+		return nil, 0, errors.New("sound file has no sample rate")
+	}
+	samples := data[10:]
+	samples = samples[:min(len(samples), length)]
+	return &PCM{SampleRate: rate, Channels: 1, BitsPerSample: 8, Samples: samples}, repeats, nil
+}
+
+// oggDuration works out how long an Ogg Vorbis file plays for from the
+// sample rate in its identification header and the position, in samples,
+// given by its last page. It's zero if either can't be found.
+func oggDuration(data []byte) time.Duration {
+	const identification = "\x01vorbis"
+	var rate uint32
+	for ix := 0; ix+len(identification)+9 <= len(data); ix++ {
+		if string(data[ix:ix+len(identification)]) == identification {
+			rate = binary.LittleEndian.Uint32(data[ix+12 : ix+16])
+			break
+		}
+	}
+
+	var granule uint64
+	for ix := len(data) - 27; ix >= 0; ix-- {
+		if string(data[ix:ix+4]) == "OggS" {
+			granule = binary.LittleEndian.Uint64(data[ix+6 : ix+14])
+			break
+		}
+	}
+
+	if rate == 0 || granule == 0 || granule == math.MaxUint64 {
+		return 0
+	}
+	return time.Duration(granule) * time.Second / time.Duration(rate)
+}
@@ -0,0 +1,281 @@
+// Package sound plays the sampled sounds, numbers 3 and up, of sound_effect.
+// A Player keeps track of which sounds are loaded and which is playing, sounds
+// come from a Source, either a Blorb or Infocom's numbered sound files, and
+// are heard through a Sink. WAVSink writes what would be heard to files for
+// machines without audio hardware.
+package sound
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davetcode/goz/blorb"
+	"github.com/davetcode/goz/zmachine"
+)
+
+// Format is how a sound's data is encoded, the same as its Blorb chunk id
+type Format string
+
+const (
+	AIFF    Format = "AIFF"
+	Ogg     Format = "OGGV"
+	MOD     Format = "MOD "
+	Infocom Format = "SND " // The numbered sound files of Infocom's V3 and V5 stories
+)
+
+// PCM is decoded audio laid out as in a WAV file: 8 bit samples are
+// unsigned and wider samples signed little endian, channels interleaved
+type PCM struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	Samples       []byte
+}
+
+// Duration is how long the samples take to play once
+func (p *PCM) Duration() time.Duration {
+	frameSize := p.Channels * ((p.BitsPerSample + 7) / 8)
+	if frameSize == 0 || p.SampleRate == 0 {
+		return 0
+	}
+	frames := len(p.Samples) / frameSize
+	return time.Duration(frames) * time.Second / time.Duration(p.SampleRate)
+}
+
+// Sound is a loaded sound ready for a sink to play
+type Sound struct {
+	Number  uint16
+	Format  Format
+	Data    []byte // As it was stored
+	PCM     *PCM   // Decoded AIFF and Infocom sounds, nil for formats left to the sink
+	Repeats int    // How many times it plays when the story doesn't say, zero is forever
+}
+
+// Duration is how long the sound takes to play once, zero if it can't be
+// told without decoding it, as for MOD music
+func (s *Sound) Duration() time.Duration {
+	switch {
+	case s.PCM != nil:
+		return s.PCM.Duration()
+	case s.Format == Ogg:
+		return oggDuration(s.Data)
+	default:
+		return 0
+	}
+}
+
+// newSound decodes the formats which can be decoded here
+func newSound(number uint16, format Format, data []byte) (*Sound, error) {
+	s := &Sound{Number: number, Format: format, Data: data, Repeats: 1}
+	var err error
+	switch format {
+	case AIFF:
+		s.PCM, err = decodeAIFF(data)
+	case Infocom:
+		s.PCM, s.Repeats, err = decodeInfocom(data)
+	case Ogg, MOD:
+	default:
+		err = fmt.Errorf("unsupported sound format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sound %d: %w", number, err)
+	}
+	return s, nil
+}
+
+// Source finds sounds by number
+type Source interface {
+	Sound(number uint16) (*Sound, error)
+}
+
+type blorbSource struct {
+	b *blorb.Blorb
+}
+
+// FromBlorb finds sounds in a Blorb's Snd resources, taking the default
+// repeats from its Loop chunk
+func FromBlorb(b *blorb.Blorb) Source {
+	return blorbSource{b: b}
+}
+
+func (s blorbSource) Sound(number uint16) (*Sound, error) {
+	resource, ok := s.b.Sound(uint32(number))
+	if !ok {
+		return nil, fmt.Errorf("no sound %d in the Blorb", number)
+	}
+	sound, err := newSound(number, Format(resource.Type), resource.Data)
+	if err != nil {
+		return nil, err
+	}
+	if loop, ok := s.b.Loop(uint32(number)); ok {
+		sound.Repeats = int(loop)
+	}
+	return sound, nil
+}
+
+type fileSource struct {
+	dir  string
+	base string
+}
+
+// FromFiles finds the numbered sound files Infocom shipped alongside the
+// story, e.g. lurking01.snd for lurking.z3, either next to it or in a sound
+// directory. Names cut to 6 characters, as on DOS, are tried too.
+func FromFiles(storyPath string) Source {
+	base := filepath.Base(storyPath)
+	return fileSource{dir: filepath.Dir(storyPath), base: strings.TrimSuffix(base, filepath.Ext(base))}
+}
+
+func (s fileSource) Sound(number uint16) (*Sound, error) {
+	bases := []string{s.base, strings.ToLower(s.base)}
+	if len(s.base) > 6 {
+		bases = append(bases, s.base[:6], strings.ToLower(s.base[:6]))
+	}
+	for _, dir := range []string{s.dir, filepath.Join(s.dir, "sound")} {
+		for _, base := range bases {
+			data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("%s%02d.snd", base, number)))
+			if err == nil {
+				return newSound(number, Infocom, data)
+			}
+		}
+	}
+	return nil, fmt.Errorf("no sound file for sound %d", number)
+}
+
+// Sink is somewhere sounds are heard. Only one sound plays at a time.
+type Sink interface {
+	// Play starts sound, replacing whatever was playing, at volume from 1 to
+	// 8. It plays repeats times or forever if repeats is zero, after which
+	// finished is called from another goroutine, never from within Play.
+	// finished isn't called for a sound which is stopped or replaced.
+	Play(sound *Sound, volume int, repeats int, finished func()) error
+
+	// Stop silences the sound playing, if there is one
+	Stop()
+}
+
+// Player carries out sound_effect for a machine, see zmachine.SetSoundSystem
+type Player struct {
+	sink   Sink
+	source Source
+
+	mu         sync.Mutex
+	loaded     map[uint16]*Sound
+	playing    uint16 // Zero when nothing is
+	generation int    // Changed by every start and stop so late finishes are ignored
+}
+
+func NewPlayer(sink Sink, source Source) *Player {
+	return &Player{sink: sink, source: source, loaded: make(map[uint16]*Sound)}
+}
+
+// Playing returns the number of the sound playing, zero if there isn't one
+func (p *Player) Playing() uint16 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playing
+}
+
+func (p *Player) SoundEffect(request zmachine.SoundEffectRequest, finished func()) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch request.Effect {
+	case zmachine.SoundPrepare:
+		_, err := p.load(request.SoundNumber)
+		return err
+
+	case zmachine.SoundStart:
+		sound, err := p.load(request.SoundNumber)
+		if err != nil {
+			return err
+		}
+		p.stop()
+		p.playing = request.SoundNumber
+		generation := p.generation
+		return p.sink.Play(sound, volume(request.Volume), p.repeats(sound, request.Repeats), func() {
+			p.mu.Lock()
+			current := p.generation == generation
+			if current {
+				p.playing = 0
+			}
+			p.mu.Unlock()
+			if current {
+				finished()
+			}
+		})
+
+	case zmachine.SoundStop:
+		if p.playing == request.SoundNumber {
+			p.stop()
+		}
+		return nil
+
+	case zmachine.SoundFinishWith:
+		if p.playing == request.SoundNumber {
+			p.stop()
+		}
+		delete(p.loaded, request.SoundNumber)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown sound effect %d", request.Effect)
+	}
+}
+
+// Stop silences whatever is playing without calling its routine, as happens
+// when the story restarts or quits
+func (p *Player) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stop()
+}
+
+func (p *Player) stop() {
+	p.generation++
+	if p.playing != 0 {
+		p.sink.Stop()
+		p.playing = 0
+	}
+}
+
+func (p *Player) load(number uint16) (*Sound, error) {
+	if sound, ok := p.loaded[number]; ok {
+		return sound, nil
+	}
+	if p.source == nil {
+		return nil, errors.New("no sounds are available")
+	}
+	sound, err := p.source.Sound(number)
+	if err != nil {
+		return nil, err
+	}
+	p.loaded[number] = sound
+	return sound, nil
+}
+
+// repeats follows the story's repeats where it gives them, 255 means forever
+func (p *Player) repeats(sound *Sound, requested uint8) int {
+	switch requested {
+	case 0:
+		return sound.Repeats
+	case 255:
+		return 0
+	default:
+		return int(requested)
+	}
+}
+
+// volume runs from 1 to 8, 255 means as loud as possible and stories which
+// leave it out get 0, which is taken to mean the same
+func volume(requested uint8) int {
+	if requested == 0 || requested > 8 {
+		return 8
+	}
+	return int(requested)
+}
@@ -0,0 +1,135 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+func appendChunk(data []byte, id string, chunk []byte) []byte {
+	data = append(data, id...)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(chunk)))
+	data = append(data, chunk...)
+	if len(chunk)%2 == 1 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+// buildAIFF makes a mono 16 bit AIFF at 8000Hz
+func buildAIFF(samples []int16) []byte {
+	comm := binary.BigEndian.AppendUint16(nil, 1)
+	comm = binary.BigEndian.AppendUint32(comm, uint32(len(samples)))
+	comm = binary.BigEndian.AppendUint16(comm, 16)
+	comm = append(comm, 0x40, 0x0b, 0xfa, 0, 0, 0, 0, 0, 0, 0) // 8000 as an 80 bit float
+
+	ssnd := make([]byte, 8)
+	for _, sample := range samples {
+		ssnd = binary.BigEndian.AppendUint16(ssnd, uint16(sample))
+	}
+
+	form := append([]byte("AIFF"), appendChunk(nil, "COMM", comm)...)
+	form = appendChunk(form, "SSND", ssnd)
+	return appendChunk(nil, "FORM", form)
+}
+
+func TestDecodeAIFF(t *testing.T) {
+	pcm, err := decodeAIFF(buildAIFF([]int16{1, -2, 0x1234}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pcm.SampleRate != 8000 || pcm.Channels != 1 || pcm.BitsPerSample != 16 {
+		t.Errorf("unexpected format %+v", pcm)
+	}
+	if want := []byte{1, 0, 0xfe, 0xff, 0x34, 0x12}; !bytes.Equal(pcm.Samples, want) {
+		t.Errorf("expected samples %v, got %v", want, pcm.Samples)
+	}
+}
+
+func TestInfocomSoundFiles(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte{0, 14, 3, 60, 0x1f, 0x40, 0, 0, 0, 4, 128, 200, 56, 128, 99}
+	if err := os.MkdirAll(filepath.Join(dir, "sound"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sound", "lurkin05.snd"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sound, err := FromFiles(filepath.Join(dir, "lurkinghorror.z3")).Sound(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sound.Repeats != 3 || sound.PCM.SampleRate != 8000 || !bytes.Equal(sound.PCM.Samples, []byte{128, 200, 56, 128}) {
+		t.Errorf("unexpected sound %+v %+v", sound, sound.PCM)
+	}
+	if _, err := FromFiles(filepath.Join(dir, "lurkinghorror.z3")).Sound(6); err == nil {
+		t.Errorf("expected a missing sound to fail")
+	}
+}
+
+type testSource map[uint16][]byte
+
+func (s testSource) Sound(number uint16) (*Sound, error) {
+	return newSound(number, AIFF, s[number])
+}
+
+func TestPlayerWritesWAVAndFinishes(t *testing.T) {
+	sink, err := NewWAVSink(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	player := NewPlayer(sink, testSource{
+		3: buildAIFF([]int16{800, -800}),
+		4: buildAIFF(make([]int16, 8000)),
+	})
+
+	finished := make(chan uint16, 2)
+	start := func(number uint16, volume, repeats uint8) {
+		request := zmachine.SoundEffectRequest{SoundNumber: number, Effect: zmachine.SoundStart, Volume: volume, Repeats: repeats}
+		if err := player.SoundEffect(request, func() { finished <- number }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Half volume, played twice
+	start(3, 4, 2)
+	select {
+	case number := <-finished:
+		if number != 3 {
+			t.Errorf("expected sound 3 to finish, got %d", number)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sound 3 never finished")
+	}
+	if player.Playing() != 0 {
+		t.Errorf("expected nothing to be playing, got %d", player.Playing())
+	}
+
+	wav, err := os.ReadFile(sink.Written[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(wav[0:4]) != "RIFF" || string(wav[8:16]) != "WAVEfmt " || binary.LittleEndian.Uint32(wav[24:28]) != 8000 {
+		t.Errorf("unexpected WAV header %q", wav[:44])
+	}
+	if want := []byte{0x90, 0x01, 0x70, 0xfe, 0x90, 0x01, 0x70, 0xfe}; !bytes.Equal(wav[44:], want) {
+		t.Errorf("expected samples %v, got %v", want, wav[44:])
+	}
+
+	// A second long sound is stopped before it finishes
+	start(4, 8, 1)
+	if err := player.SoundEffect(zmachine.SoundEffectRequest{SoundNumber: 4, Effect: zmachine.SoundStop}, nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case number := <-finished:
+		t.Errorf("expected stopped sound %d not to finish", number)
+	case <-time.After(1200 * time.Millisecond):
+	}
+}
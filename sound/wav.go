@@ -0,0 +1,125 @@
+package sound
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WAVSink writes each sound played to a numbered file in a directory, as
+// WAV for decoded sounds, with repeats played out and the volume applied,
+// and as stored for Ogg and MOD. Sounds "finish" once they'd have played.
+type WAVSink struct {
+	dir string
+
+	mu      sync.Mutex
+	count   int
+	timer   *time.Timer
+	Written []string // Paths of the files written so far
+}
+
+func NewWAVSink(dir string) (*WAVSink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &WAVSink{dir: dir}, nil
+}
+
+func (w *WAVSink) Play(sound *Sound, volume int, repeats int, finished func()) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stop()
+
+	w.count++
+	name := fmt.Sprintf("%04d-sound%d", w.count, sound.Number)
+	var data []byte
+	if sound.PCM != nil {
+		// A sound playing forever is written once round
+		data = encodeWAV(sound.PCM, volume, max(repeats, 1))
+		name += ".wav"
+	} else {
+		data = sound.Data
+		name += "." + strings.ToLower(strings.TrimSpace(string(sound.Format)))
+	}
+
+	path := filepath.Join(w.dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	w.Written = append(w.Written, path)
+
+	if repeats > 0 {
+		w.timer = time.AfterFunc(sound.Duration()*time.Duration(repeats), finished)
+	}
+	return nil
+}
+
+func (w *WAVSink) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stop()
+}
+
+func (w *WAVSink) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+}
+
+// encodeWAV lays pcm out as a RIFF WAVE file, scaling the samples by
+// volume/8 and playing them repeats times over
+func encodeWAV(pcm *PCM, volume int, repeats int) []byte {
+	samples := scale(pcm, volume)
+	sampleSize := (pcm.BitsPerSample + 7) / 8
+	length := len(samples) * repeats
+
+	data := make([]byte, 0, 44+length)
+	data = append(data, "RIFF"...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(36+length))
+	data = append(data, "WAVEfmt "...)
+	data = binary.LittleEndian.AppendUint32(data, 16)
+	data = binary.LittleEndian.AppendUint16(data, 1) // Uncompressed
+	data = binary.LittleEndian.AppendUint16(data, uint16(pcm.Channels))
+	data = binary.LittleEndian.AppendUint32(data, uint32(pcm.SampleRate))
+	data = binary.LittleEndian.AppendUint32(data, uint32(pcm.SampleRate*pcm.Channels*sampleSize))
+	data = binary.LittleEndian.AppendUint16(data, uint16(pcm.Channels*sampleSize))
+	data = binary.LittleEndian.AppendUint16(data, uint16(pcm.BitsPerSample))
+	data = append(data, "data"...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(length))
+	for range repeats {
+		data = append(data, samples...)
+	}
+	return data
+}
+
+func scale(pcm *PCM, volume int) []byte {
+	if volume >= 8 {
+		return pcm.Samples
+	}
+
+	sampleSize := (pcm.BitsPerSample + 7) / 8
+	samples := make([]byte, len(pcm.Samples))
+	for ix := 0; ix+sampleSize <= len(samples); ix += sampleSize {
+		if sampleSize == 1 {
+			samples[ix] = byte(128 + (int(pcm.Samples[ix])-128)*volume/8)
+			continue
+		}
+
+		// Sign extend the little endian sample from its top byte, scale it
+		// and write it back
+		value := int64(int8(pcm.Samples[ix+sampleSize-1]))
+		for b := sampleSize - 2; b >= 0; b-- {
+			value = value<<8 | int64(pcm.Samples[ix+b])
+		}
+		value = value * int64(volume) / 8
+		for b := range sampleSize {
+			samples[ix+b] = byte(value >> (8 * b))
+		}
+	}
+	return samples
+}
@@ -0,0 +1,125 @@
+package storyfiles
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+// Store handles the files a frontend reads and writes on behalf of a story,
+// so that every frontend names and handles them the same way.
+type Store struct {
+	romFilePath string
+}
+
+func New(romFilePath string) *Store {
+	return &Store{romFilePath: romFilePath}
+}
+
+// DefaultSaveFilename derives a save filename from the ROM file path.
+// It replaces the .z* extension with .sav, e.g., "zork1.z1" -> "zork1.sav"
+func (s *Store) DefaultSaveFilename() string {
+	return s.baseName() + ".sav"
+}
+
+func (s *Store) baseName() string {
+	if s.romFilePath == "" {
+		return "game"
+	}
+	base := filepath.Base(s.romFilePath)
+	// Remove .z* extension (z1 to z8, zblorb, zlb) or .blb
+	ext := filepath.Ext(base)
+	if len(ext) >= 2 && (ext[1] == 'z' || ext[1] == 'Z') || strings.EqualFold(ext, ".blb") {
+		base = base[:len(base)-len(ext)]
+	}
+	return base
+}
+
+// DefaultAuxiliaryFilename is used for auxiliary (table) saves which don't
+// name a file, e.g. "zork1.z1" -> "zork1.aux"
+func (s *Store) DefaultAuxiliaryFilename() string {
+	return s.baseName() + ".aux"
+}
+
+// filename picks the file for a save or restore. Auxiliary files get a .aux
+// extension if the story didn't give one, as the spec recommends.
+func (s *Store) filename(filename string, auxiliary bool) string {
+	switch {
+	case filename == "" && auxiliary:
+		return s.DefaultAuxiliaryFilename()
+	case filename == "":
+		return s.DefaultSaveFilename()
+	case auxiliary && filepath.Ext(filename) == "":
+		return filename + ".aux"
+	default:
+		return filename
+	}
+}
+
+// Save writes the request's data to the named (or default) file, for both
+// full saves and auxiliary table saves
+func (s *Store) Save(request zmachine.Save) zmachine.SaveResponse {
+	filename := s.filename(request.Filename, request.NumBytes != 0)
+	// TODO: If request.Prompt is true, ask user for filename
+	if err := os.WriteFile(filename, request.Data, 0644); err != nil {
+		return zmachine.SaveResponse{Success: false, Result: 0}
+	}
+	return zmachine.SaveResponse{Success: true, Result: 1}
+}
+
+// Restore reads back a file written by Save. Auxiliary restores report the
+// number of bytes available, the interpreter loads no more than it asked for.
+func (s *Store) Restore(request zmachine.Restore) zmachine.RestoreResponse {
+	auxiliary := request.NumBytes != 0
+	filename := s.filename(request.Filename, auxiliary)
+	// TODO: If request.Prompt is true, ask user for filename
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return zmachine.RestoreResponse{Success: false, Result: 0}
+	}
+	if auxiliary {
+		return zmachine.RestoreResponse{Success: true, Result: uint16(min(len(data), int(request.NumBytes))), Data: data}
+	}
+	return zmachine.RestoreResponse{Success: true, Result: 2, Data: data}
+}
+
+// DefaultTranscriptFilename is where transcripts are written, e.g. "zork1.z1" -> "zork1.txt"
+func (s *Store) DefaultTranscriptFilename() string {
+	return s.baseName() + ".txt"
+}
+
+// OpenTranscript opens the transcript file for appending so that several
+// sessions can build up a single transcript
+func (s *Store) OpenTranscript() (io.WriteCloser, error) {
+	file, err := os.OpenFile(s.DefaultTranscriptFilename(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// DefaultCommandFilename is where commands are recorded and replayed from, e.g. "zork1.z1" -> "zork1.rec"
+func (s *Store) DefaultCommandFilename() string {
+	return s.baseName() + ".rec"
+}
+
+// OpenCommandRecording creates (or truncates) the command file for output stream 4
+func (s *Store) OpenCommandRecording() (io.WriteCloser, error) {
+	file, err := os.Create(s.DefaultCommandFilename())
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// OpenCommandScript opens the command file for input stream 1
+func (s *Store) OpenCommandScript() (io.ReadCloser, error) {
+	file, err := os.Open(s.DefaultCommandFilename())
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
@@ -35,6 +35,31 @@ type Core struct {
 	ExtensionTableBaseAddress        uint16
 	PlayerLoginName                  []uint8
 	UnicodeExtensionTableBaseAddress uint16
+	writeHook                        WriteHook
+	protectionHook                   ProtectionHook
+	pageWrites                       []uint64 // Writes made to each page of memory, see Writes
+}
+
+// pageSize is how much memory each count of writes covers
+const pageSize = 256
+
+// WriteHook is told about every write made through WriteZByte, WriteHalfWord
+// and WriteWord after it has happened, size is the number of bytes written
+type WriteHook func(address uint32, size int, old uint32, new uint32)
+
+// SetWriteHook calls hook after every write, nil removes it
+func (core *Core) SetWriteHook(hook WriteHook) {
+	core.writeHook = hook
+}
+
+// ProtectionHook is told about writes the story isn't allowed to make, to
+// static memory or to the header outside Flags 2, which are dropped
+type ProtectionHook func(address uint32, size int)
+
+// SetProtectionHook calls hook for every write which is dropped, nil drops
+// them silently
+func (core *Core) SetProtectionHook(hook ProtectionHook) {
+	core.protectionHook = hook
 }
 
 func LoadCore(bytes []uint8) Core {
@@ -60,9 +85,10 @@ func LoadCore(bytes []uint8) Core {
 	if bytes[0] <= 3 {
 		bytes[1] |= 0b0010_0000 // Only flag to set is the "split screen available one"
 	} else {
-		// Flags: colors (0x01), bold (0x04), italic (0x08), split screen (0x20)
-		// NOT claiming: pictures (0x02), fixed-width default (0x10), timed input (0x80)
-		bytes[1] |= 0b0010_1101
+		// Flags: colors (0x01), bold (0x04), italic (0x08), split screen (0x20), timed input (0x80)
+		// NOT claiming: fixed-width default (0x10), pictures (0x02) are claimed by the
+		// machine for V6 stories loaded from a Blorb with pictures
+		bytes[1] |= 0b1010_1101
 	}
 
 	// Parse the extension table for any interesting information we want
@@ -74,6 +100,7 @@ func LoadCore(bytes []uint8) Core {
 
 	return Core{
 		bytes:                            bytes,
+		pageWrites:                       make([]uint64, len(bytes)/pageSize+1),
 		Version:                          bytes[0x00],
 		FlagByte1:                        bytes[0x01],
 		StatusBarTimeBased:               bytes[0x01]&0b0000_0010 == 0b0000_0010,
@@ -108,12 +135,34 @@ func LoadCore(bytes []uint8) Core {
 	}
 }
 
-// func (z *ZMachine) flagByte2() uint8         { return bytes[0x10] }
-// func (z *ZMachine) flagByte3() uint8         { return bytes[0x11] }
-// func (z *ZMachine) serialCode() []uint8      { return bytes[0x12:0x18] }
+// Flags2 is read from memory each time as the story and interpreter both
+// change it while running
+func (core *Core) Flags2() uint16 {
+	return binary.BigEndian.Uint16(core.bytes[0x10:0x12])
+}
+
+// SerialCode is the six character serial, conventionally the compile date
+func (core *Core) SerialCode() string {
+	return string(core.bytes[0x12:0x18])
+}
+
+// ExtensionTable returns the words of the header extension table after its
+// length word, nil if the story has no extension table
+func (core *Core) ExtensionTable() []uint16 {
+	base := uint32(core.ExtensionTableBaseAddress)
+	if base == 0 || base+2 > core.MemoryLength() {
+		return nil
+	}
+	count := uint32(core.ReadHalfWord(base))
+	words := make([]uint16, 0, count)
+	for ix := uint32(1); ix <= count && base+2*ix+2 <= core.MemoryLength(); ix++ {
+		words = append(words, core.ReadHalfWord(base+2*ix))
+	}
+	return words
+}
 
-func (core *Core) FileLength() uint16 {
-	var divisor uint16
+func (core *Core) FileLength() uint32 {
+	var divisor uint32
 	version := core.Version
 	switch {
 	case version <= 3:
@@ -123,7 +172,7 @@ func (core *Core) FileLength() uint16 {
 	default:
 		divisor = 8
 	}
-	return binary.BigEndian.Uint16(core.bytes[0x1a:0x1c]) * divisor
+	return uint32(binary.BigEndian.Uint16(core.bytes[0x1a:0x1c])) * divisor
 }
 
 func (core *Core) SetDefaultBackgroundColorNumber(color uint8) {
@@ -151,19 +200,91 @@ func (core *Core) ReadSlice(startAddress uint32, endAddress uint32) []uint8 {
 	return core.bytes[startAddress:endAddress]
 }
 
+// writable is false if any of the size bytes at address are in static memory
+// or are a header field other than Flags 2, the only one a story may change. A
+// core without a static memory base, e.g. one with no header, has no limit.
+func (core *Core) writable(address uint32, size int) bool {
+	if core.StaticMemoryBase == 0 {
+		return true
+	}
+	for a := address; a < address+uint32(size); a++ {
+		if a >= uint32(core.StaticMemoryBase) || (a < 0x40 && a != 0x10 && a != 0x11) {
+			if core.protectionHook != nil {
+				core.protectionHook(address, size)
+			}
+			return false
+		}
+	}
+	return true
+}
+
 func (core *Core) WriteZByte(address uint32, value uint8) {
-	// TODO - Lots of the memory is read only, need to add validation here
-	core.bytes[address] = value
+	if core.writable(address, 1) {
+		core.InterpreterWriteZByte(address, value)
+	}
 }
 
 func (core *Core) WriteHalfWord(address uint32, value uint16) {
-	// TODO - Lots of the memory is read only, need to add validation here
-	binary.BigEndian.PutUint16(core.bytes[address:address+2], value)
+	if core.writable(address, 2) {
+		core.InterpreterWriteHalfWord(address, value)
+	}
 }
 
 func (core *Core) WriteWord(address uint32, value uint32) {
-	// TODO - Lots of the memory is read only, need to add validation here
+	if core.writable(address, 4) {
+		core.InterpreterWriteWord(address, value)
+	}
+}
+
+// InterpreterWriteZByte writes without the checks WriteZByte makes, for the
+// header fields and tables which the interpreter rather than the story fills in
+func (core *Core) InterpreterWriteZByte(address uint32, value uint8) {
+	old := core.bytes[address]
+	core.bytes[address] = value
+	core.countWrite(address, 1)
+	if core.writeHook != nil {
+		core.writeHook(address, 1, uint32(old), uint32(value))
+	}
+}
+
+// InterpreterWriteHalfWord is WriteHalfWord without its checks, see
+// InterpreterWriteZByte
+func (core *Core) InterpreterWriteHalfWord(address uint32, value uint16) {
+	old := binary.BigEndian.Uint16(core.bytes[address : address+2])
+	binary.BigEndian.PutUint16(core.bytes[address:address+2], value)
+	core.countWrite(address, 2)
+	if core.writeHook != nil {
+		core.writeHook(address, 2, uint32(old), uint32(value))
+	}
+}
+
+// InterpreterWriteWord is WriteWord without its checks, see
+// InterpreterWriteZByte
+func (core *Core) InterpreterWriteWord(address uint32, value uint32) {
+	old := binary.BigEndian.Uint32(core.bytes[address : address+4])
 	binary.BigEndian.PutUint32(core.bytes[address:address+4], value)
+	core.countWrite(address, 4)
+	if core.writeHook != nil {
+		core.writeHook(address, 4, old, value)
+	}
+}
+
+func (core *Core) countWrite(address uint32, size int) {
+	core.pageWrites[address/pageSize]++
+	if end := address + uint32(size) - 1; end/pageSize != address/pageSize {
+		core.pageWrites[end/pageSize]++
+	}
+}
+
+// Writes counts the writes made to the pages of memory holding start up to
+// end. It only goes up, so if it's the same as before nothing there changed.
+// Memory changed through ReadSlice isn't counted.
+func (core *Core) Writes(start uint32, end uint32) uint64 {
+	var writes uint64
+	for page := start / pageSize; page <= end/pageSize && int(page) < len(core.pageWrites); page++ {
+		writes += core.pageWrites[page]
+	}
+	return writes
 }
 
 func (core *Core) MemoryLength() uint32 {
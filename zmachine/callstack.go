@@ -4,11 +4,11 @@ import "fmt"
 
 type CallStackFrame struct {
 	pc              uint32   // TODO - What is the usual limit to this number?
+	routineAddress  uint32   // Start of the routine, 0 for the V1-5 main routine and frames restored from a save
 	routineStack    []uint16 // TODO - Really a stack, check how it's used to see if we care
 	locals          []uint16
 	routineType     RoutineType // v3+ only
 	numValuesPassed int         // v5+ only
-	framePointer    uint32
 }
 
 func (f *CallStackFrame) push(i uint16) {
@@ -68,9 +68,9 @@ func (s *CallStack) copy() CallStack {
 	for fx, frame := range s.frames {
 		copiedFrame := CallStackFrame{
 			pc:              frame.pc,
+			routineAddress:  frame.routineAddress,
 			routineType:     frame.routineType,
 			numValuesPassed: frame.numValuesPassed,
-			framePointer:    frame.framePointer,
 			routineStack:    make([]uint16, len(frame.routineStack)),
 			locals:          make([]uint16, len(frame.locals)),
 		}
@@ -83,3 +83,22 @@ func (s *CallStack) copy() CallStack {
 
 	return callStack
 }
+
+// pushUserStack pushes onto a V6 user stack, a table whose first word is the
+// number of free slots after it. False means the stack is full.
+func (z *ZMachine) pushUserStack(stack uint32, value uint16) bool {
+	free := z.Core.ReadHalfWord(stack)
+	if free == 0 {
+		return false
+	}
+	z.Core.WriteHalfWord(stack+2*uint32(free), value)
+	z.Core.WriteHalfWord(stack, free-1)
+	return true
+}
+
+// pullUserStack pops the value pushUserStack last pushed
+func (z *ZMachine) pullUserStack(stack uint32) uint16 {
+	free := z.Core.ReadHalfWord(stack) + 1
+	z.Core.WriteHalfWord(stack, free)
+	return z.Core.ReadHalfWord(stack + 2*uint32(free))
+}
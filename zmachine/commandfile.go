@@ -0,0 +1,228 @@
+package zmachine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davetcode/goz/zstring"
+)
+
+// Command files hold one line per input request in the same layout as
+// Frotz: a read is the text typed, a read_char is the character pressed.
+// Keys which aren't printable, and line terminators other than enter, are
+// written as their ZSCII code in square brackets, e.g. "[129]" for cursor up.
+
+// OpenCommandRecording is sent when the story selects output stream 4 for the
+// first time, the frontend replies with a CommandRecordingResponse
+type OpenCommandRecording struct{}
+
+// CommandRecordingResponse carries where recorded commands should be
+// written, nil means no recording is available
+type CommandRecordingResponse struct {
+	Recording io.WriteCloser
+}
+
+func (CommandRecordingResponse) isSaveRestoreResponse() {}
+
+// OpenCommandScript is sent when the story selects input stream 1, the
+// frontend replies with a CommandScriptResponse
+type OpenCommandScript struct{}
+
+// CommandScriptResponse carries the command file to read input from, nil
+// means input stays with the keyboard
+type CommandScriptResponse struct {
+	Script io.ReadCloser
+}
+
+func (CommandScriptResponse) isSaveRestoreResponse() {}
+
+// commandScript is input stream 1, replaying a command file
+type commandScript struct {
+	scanner *bufio.Scanner
+	source  io.Reader
+}
+
+// SetCommandScript makes the machine take its input from a command file
+// until it runs out, after which input comes from the frontend again.
+// Readers which are also io.Closers are closed once exhausted.
+func (z *ZMachine) SetCommandScript(script io.Reader) {
+	z.closeCommandScript()
+	z.commandScript = &commandScript{scanner: bufio.NewScanner(script), source: script}
+}
+
+func (z *ZMachine) closeCommandScript() {
+	if z.commandScript == nil {
+		return
+	}
+	if closer, ok := z.commandScript.source.(io.Closer); ok {
+		closer.Close() // nolint:errcheck
+	}
+	z.commandScript = nil
+}
+
+// setInputStream implements INPUT_STREAM, 0 is the keyboard and 1 a command
+// file provided by the frontend
+func (z *ZMachine) setInputStream(stream int16) {
+	switch stream {
+	case 0:
+		z.closeCommandScript()
+	case 1:
+		if z.commandScript != nil {
+			return
+		}
+		script, err := z.frontend.OpenCommandScript(z.ctx)
+		if err != nil || script == nil {
+			return
+		}
+		z.SetCommandScript(script)
+	default:
+		z.warnOnce("input_stream", "Warning: Unknown input stream %d selected (PC = %x)", stream, z.currentInstructionPC)
+	}
+}
+
+// nextScriptedLine returns the next line of the command file, closing it
+// and returning false once it's exhausted
+func (z *ZMachine) nextScriptedLine() (string, bool) {
+	if z.commandScript == nil {
+		return "", false
+	}
+	if !z.commandScript.scanner.Scan() {
+		z.closeCommandScript()
+		return "", false
+	}
+	return strings.TrimRight(z.commandScript.scanner.Text(), "\r"), true
+}
+
+// splitKeyCode pulls a trailing "[n]" key code off a command file line
+func splitKeyCode(line string) (string, uint8, bool) {
+	if !strings.HasSuffix(line, "]") {
+		return line, 0, false
+	}
+	start := strings.LastIndex(line, "[")
+	if start < 0 {
+		return line, 0, false
+	}
+	code, err := strconv.ParseUint(line[start+1:len(line)-1], 10, 8)
+	if err != nil {
+		return line, 0, false
+	}
+	return line[:start], uint8(code), true
+}
+
+// setCommandRecording turns output stream 4 on or off, the frontend is asked
+// for somewhere to write the first time it's turned on
+func (z *ZMachine) setCommandRecording(on bool) {
+	if on && z.commandRecording == nil {
+		recording, err := z.frontend.OpenCommandRecording(z.ctx)
+		if err != nil || recording == nil {
+			if err == nil {
+				z.warnOnce("command_recording_unavailable", "Warning: No command recording available, output stream 4 left off (PC = %x)", z.currentInstructionPC)
+			}
+			on = false
+		}
+		z.commandRecording = recording
+	}
+	z.streams.CommandScript = on
+}
+
+func (z *ZMachine) closeCommandRecording() {
+	if z.commandRecording != nil {
+		z.commandRecording.Close() // nolint:errcheck
+		z.commandRecording = nil
+		z.streams.CommandScript = false
+	}
+}
+
+func (z *ZMachine) recordCommand(line string) {
+	if z.streams.CommandScript {
+		io.WriteString(z.commandRecording, line+"\n") // nolint:errcheck
+	}
+}
+
+// readLine gets a line of input from the command file if one is being
+// replayed and otherwise from the frontend. Replayed commands are shown on
+// screen as though they'd been typed.
+func (z *ZMachine) readLine(request InputRequest) (InputResponse, error) {
+	var response InputResponse
+	if line, ok := z.nextScriptedLine(); ok {
+		text, key, hasKey := splitKeyCode(line)
+		response = InputResponse{Text: text, TerminatingKey: 13}
+		if hasKey {
+			response.TerminatingKey = key
+		}
+		if z.streams.Screen {
+			z.frontend.Print(z.ctx, text+"\n")
+		}
+	} else {
+		var err error
+		if response, err = z.frontend.ReadLine(z.ctx, request); err != nil {
+			return response, err
+		}
+		z.recordMouse(response)
+	}
+
+	if response.TimedOut {
+		// Recorded once the player finishes the line
+		return response, nil
+	}
+
+	if response.TerminatingKey == 13 || response.TerminatingKey == 0 {
+		z.recordCommand(response.Text)
+	} else {
+		z.recordCommand(fmt.Sprintf("%s[%d]", response.Text, response.TerminatingKey))
+	}
+	return response, nil
+}
+
+// readChar gets a single keypress as a ZSCII code from the command file or
+// the frontend, the bool is true if the request timed out before a key was
+// pressed
+func (z *ZMachine) readChar(request CharacterRequest) (uint16, bool, error) {
+	charCode := uint16(13) // Default to carriage return
+
+	if line, ok := z.nextScriptedLine(); ok {
+		if text, key, hasKey := splitKeyCode(line); hasKey && text == "" {
+			charCode = uint16(key)
+		} else if len(line) > 0 {
+			charCode = z.firstCharacter(line)
+		}
+	} else {
+		inputResponse, err := z.frontend.ReadChar(z.ctx, request)
+		if err != nil {
+			return 0, false, err
+		}
+		if inputResponse.TimedOut {
+			return 0, true, nil
+		}
+		z.recordMouse(inputResponse)
+
+		// Handle empty input (treat as newline)
+		if len(inputResponse.Text) > 0 {
+			charCode = z.firstCharacter(inputResponse.Text)
+		} else if inputResponse.TerminatingKey != 0 {
+			// Use terminating key if text is empty (e.g., function key was pressed)
+			charCode = uint16(inputResponse.TerminatingKey)
+		}
+	}
+
+	if charCode >= 32 && charCode <= 126 {
+		z.recordCommand(string(rune(charCode)))
+	} else {
+		z.recordCommand(fmt.Sprintf("[%d]", charCode))
+	}
+	return charCode, false, nil
+}
+
+// firstCharacter gives the ZSCII code of the first character of text, or a
+// question mark if it has none
+func (z *ZMachine) firstCharacter(text string) uint16 {
+	r, _ := utf8.DecodeRuneInString(text)
+	if zchr, ok := zstring.UnicodeToZscii(r, &z.Core); ok {
+		return uint16(zchr)
+	}
+	return '?'
+}
@@ -0,0 +1,67 @@
+package zmachine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+func TestReplayCommandsThenFallBackToFrontend(t *testing.T) {
+	frontend := &scriptedFrontend{}
+	z := loadWithFrontend(t, "../advent.z3", frontend)
+	z.SetCommandScript(strings.NewReader("no\nlook\n"))
+
+	// Two lines come from the script before the frontend is asked for any
+	stepUntilLinesRead(t, z, frontend, 1)
+
+	output := frontend.output.String()
+	if strings.Count(output, "You are standing at the end of a road") != 2 {
+		t.Errorf("expected the replayed look to describe the room again, got %q", output)
+	}
+	if !strings.Contains(output, "> look\n") {
+		t.Errorf("expected the replayed command to be shown, got %q", output)
+	}
+}
+
+func TestInputStreamAndCommandRecording(t *testing.T) {
+	story := buildStory(5, nil, []byte{
+		0xf4, 0x7f, 0x01, // input_stream 1
+		0xf3, 0x7f, 0x04, // output_stream 4
+		0xf6, 0x7f, 0x01, 0x10, // read_char 1 -> G00
+		0xf6, 0x7f, 0x01, 0x11, // read_char 1 -> G01
+		0xf6, 0x7f, 0x01, 0x12, // read_char 1 -> G02
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{script: "x\n[129]\n"}
+	z := runStory(t, story, frontend)
+
+	for i, expected := range []uint16{'x', 129, 13} {
+		if result := z.Core.ReadHalfWord(0x60 + 2*uint32(i)); result != expected {
+			t.Errorf("read_char %d: expected %d, got %d", i, expected, result)
+		}
+	}
+	if frontend.recording.String() != "x\n[129]\n[13]\n" {
+		t.Errorf("unexpected command recording %q", frontend.recording.String())
+	}
+}
+
+func TestReadCharStoresZsciiForTypedCharacters(t *testing.T) {
+	story := buildStory(5, nil, []byte{
+		0xf4, 0x7f, 0x01, // input_stream 1
+		0xf6, 0x7f, 0x01, 0x10, // read_char 1 -> G00
+		0xf6, 0x7f, 0x01, 0x11, // read_char 1 -> G01
+		0xba, // quit
+	})
+
+	// The first comes from the command file, the second from the frontend
+	frontend := &scriptedFrontend{script: "é\n", click: &zmachine.InputResponse{Text: "ßa"}}
+	z := runStory(t, story, frontend)
+
+	for i, expected := range []uint16{170, 161} {
+		if result := z.Core.ReadHalfWord(0x60 + 2*uint32(i)); result != expected {
+			t.Errorf("read_char %d: expected %d, got %d", i, expected, result)
+		}
+	}
+}
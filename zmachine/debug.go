@@ -0,0 +1,140 @@
+package zmachine
+
+import (
+	"slices"
+
+	"github.com/davetcode/goz/zcore"
+)
+
+// FrameKind says how a routine on the call stack was called
+type FrameKind string
+
+const (
+	FrameFunction  FrameKind = "function"  // Result is stored by the caller
+	FrameProcedure FrameKind = "procedure" // Result is thrown away
+	FrameInterrupt FrameKind = "interrupt" // Timed input or sound routine
+)
+
+// FrameInfo is a copy of a call stack frame for debuggers, changing it has no
+// effect on the machine
+type FrameInfo struct {
+	PC             uint32 // Next instruction to run in this frame
+	RoutineAddress uint32 // Start of the routine, 0 where it isn't known
+	Locals         []uint16
+	Stack          []uint16 // The routine's stack, the top is last
+	ArgumentCount  int      // v5+ only
+	Kind           FrameKind
+}
+
+// SetDebugHook calls hook before every instruction, including those run by
+// interrupt routines. A non-nil error stops the machine and is returned from
+// RunContext. nil removes the hook.
+func (z *ZMachine) SetDebugHook(hook func() error) {
+	z.debugHook = hook
+}
+
+// ObjectChange is a change to an object's parent or one of its attributes.
+// Attributes are 0 when clear and 1 when set.
+type ObjectChange struct {
+	Object    uint16
+	Parent    bool   // The parent changed rather than an attribute
+	Attribute uint16 // The attribute which changed, unless Parent is set
+	Old       uint16
+	New       uint16
+}
+
+// SetMemoryHook calls hook after every write to memory, including those to
+// globals, and keeps doing so across restarts. nil removes the hook.
+func (z *ZMachine) SetMemoryHook(hook zcore.WriteHook) {
+	z.memoryHook = hook
+	z.Core.SetWriteHook(hook)
+}
+
+// SetObjectHook calls hook whenever SET_ATTR, CLEAR_ATTR, INSERT_OBJ or
+// REMOVE_OBJ changes an object. nil removes the hook.
+func (z *ZMachine) SetObjectHook(hook func(ObjectChange)) {
+	z.objectHook = hook
+}
+
+func (z *ZMachine) attributeChanged(objId uint16, attribute uint16, wasSet bool, isSet bool) {
+	if z.objectHook == nil || wasSet == isSet {
+		return
+	}
+	change := ObjectChange{Object: objId, Attribute: attribute}
+	if wasSet {
+		change.Old = 1
+	} else {
+		change.New = 1
+	}
+	z.objectHook(change)
+}
+
+func (z *ZMachine) parentChanged(objId uint16, oldParent uint16, newParent uint16) {
+	if z.objectHook != nil && oldParent != newParent {
+		z.objectHook(ObjectChange{Object: objId, Parent: true, Old: oldParent, New: newParent})
+	}
+}
+
+// InstructionPC returns the address of the instruction being run, or the
+// last one run if the machine is between instructions
+func (z *ZMachine) InstructionPC() uint32 {
+	return z.currentInstructionPC
+}
+
+// PC returns the address of the next instruction to run
+func (z *ZMachine) PC() uint32 {
+	frame, err := z.callStack.peek()
+	if err != nil {
+		return 0
+	}
+	return frame.pc
+}
+
+// CallDepth returns how many routines are on the call stack, including the
+// main routine
+func (z *ZMachine) CallDepth() int {
+	return len(z.callStack.frames)
+}
+
+// CallFrames returns the call stack with the outermost routine first
+func (z *ZMachine) CallFrames() []FrameInfo {
+	frames := make([]FrameInfo, len(z.callStack.frames))
+	for ix, frame := range z.callStack.frames {
+		kind := FrameFunction
+		switch frame.routineType {
+		case procedure:
+			kind = FrameProcedure
+		case interrupt:
+			kind = FrameInterrupt
+		}
+		frames[ix] = FrameInfo{
+			PC:             frame.pc,
+			RoutineAddress: frame.routineAddress,
+			Locals:         slices.Clone(frame.locals),
+			Stack:          slices.Clone(frame.routineStack),
+			ArgumentCount:  frame.numValuesPassed,
+			Kind:           kind,
+		}
+	}
+	return frames
+}
+
+// Global reads global variable n, numbered from 0
+func (z *ZMachine) Global(n uint8) uint16 {
+	return z.Core.ReadHalfWord(uint32(z.Core.GlobalVariableBase) + 2*uint32(n))
+}
+
+// SetGlobal writes global variable n, numbered from 0
+func (z *ZMachine) SetGlobal(n uint8, value uint16) {
+	z.Core.WriteHalfWord(uint32(z.Core.GlobalVariableBase)+2*uint32(n), value)
+}
+
+// SetLocal writes local n, numbered from 0, of the frame at index frame in
+// CallFrames. It returns false if there's no such frame or local.
+func (z *ZMachine) SetLocal(frame int, n int, value uint16) bool {
+	if frame < 0 || frame >= len(z.callStack.frames) || n < 0 || n >= len(z.callStack.frames[frame].locals) {
+		return false
+	}
+	z.callStack.frames[frame].locals[n] = value
+	return true
+}
@@ -0,0 +1,170 @@
+package zmachine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/davetcode/goz/zcore"
+	"github.com/davetcode/goz/zstring"
+)
+
+// Instruction is a fully decoded instruction, including the store, branch
+// and text which follow the operands. Decoding doesn't need a running
+// machine so works on any address, e.g. for disassembly.
+type Instruction struct {
+	Address  uint32
+	Length   uint32
+	Mnemonic string // "unknown" if the opcode doesn't exist in this version
+	Operands []TraceOperand
+
+	Stores        bool
+	StoreVariable uint8
+	StoreName     string // StoreVariable's name, see Annotate
+
+	Branches     bool
+	BranchOnTrue bool   // Branch if the condition is true rather than false
+	BranchTarget uint32 // Address to branch to, 0 and 1 mean return false and true
+
+	JumpTarget     uint32 // Destination of a jump
+	RoutineAddress uint32 // Unpacked address for calls to a constant routine
+	Text           string // Inline text of print and print_ret
+	RoutineName    string // Name of the routine at RoutineAddress, see Annotate
+}
+
+// UnpackAddress turns a packed routine or string address into a byte address
+func UnpackAddress(core *zcore.Core, packed uint32, isZString bool) uint32 {
+	switch {
+	case core.Version < 4:
+		return 2 * packed
+	case core.Version < 6:
+		return 4 * packed
+	case core.Version < 8:
+		offset := core.RoutinesOffset
+		if isZString {
+			offset = core.StringOffset
+		}
+		return 4*packed + 8*uint32(offset)
+	default:
+		return 8 * packed
+	}
+}
+
+// DecodeInstruction decodes the instruction at address without executing it
+func DecodeInstruction(core *zcore.Core, alphabets *zstring.Alphabets, address uint32) (Instruction, error) {
+	opcode, next, err := decodeOpcode(core, address)
+	if err != nil {
+		return Instruction{}, err
+	}
+
+	instruction := Instruction{
+		Address:  address,
+		Mnemonic: opcode.mnemonic(core.Version),
+		Operands: opcode.traceOperands(),
+	}
+
+	r := instructionReader{core: core, pc: next}
+	if opcode.stores(core.Version) {
+		instruction.Stores = true
+		instruction.StoreVariable = r.readByte()
+	}
+
+	if opcode.branches(core.Version) {
+		branchArg1 := r.readByte()
+		instruction.Branches = true
+		instruction.BranchOnTrue = branchArg1&0b1000_0000 != 0
+		offset := int32(branchArg1 & 0b11_1111)
+		if branchArg1&0b0100_0000 == 0 {
+			offset = int32(int16((uint16(branchArg1&0b11_1111)<<8|uint16(r.readByte()))<<2) >> 2)
+		}
+		switch offset {
+		case 0, 1:
+			instruction.BranchTarget = uint32(offset)
+		default:
+			instruction.BranchTarget = uint32(int32(r.pc) + offset - 2)
+		}
+	}
+
+	if r.overrun {
+		return Instruction{}, fmt.Errorf("instruction at 0x%x runs past the end of memory", address)
+	}
+
+	if opcode.hasText() {
+		text, bytesRead := zstring.Decode(r.pc, core.MemoryLength(), core, alphabets, false)
+		instruction.Text = text
+		r.pc += bytesRead
+	}
+
+	switch {
+	case instruction.Mnemonic == "jump" && len(instruction.Operands) == 1:
+		instruction.JumpTarget = uint32(int32(r.pc) + int32(int16(instruction.Operands[0].Value)) - 2)
+	case strings.HasPrefix(instruction.Mnemonic, "call") && len(instruction.Operands) > 0 && !instruction.Operands[0].Variable:
+		instruction.RoutineAddress = UnpackAddress(core, uint32(instruction.Operands[0].Value), false)
+	}
+
+	instruction.Length = r.pc - address
+	return instruction, nil
+}
+
+// Annotate names the variables and called routine from symbols, which
+// String then uses in place of numbers and addresses
+func (i *Instruction) Annotate(symbols Symbols) {
+	if symbols == nil {
+		return
+	}
+	nameOperands(symbols, i.Address, i.Operands, 0)
+	if i.RoutineAddress != 0 {
+		i.RoutineName = symbols.RoutineName(i.RoutineAddress)
+	}
+	if i.Stores {
+		if name := symbolicVariableName(symbols, i.Address, i.StoreVariable); name != variableName(i.StoreVariable) {
+			i.StoreName = name
+		}
+	}
+}
+
+// String formats the instruction in the style of txd, without the address
+func (i Instruction) String() string {
+	var s strings.Builder
+	s.WriteString(i.Mnemonic)
+
+	for ix, operand := range i.Operands {
+		switch {
+		case ix == 0 && i.JumpTarget != 0:
+			fmt.Fprintf(&s, " %04x", i.JumpTarget)
+		case ix == 0 && i.RoutineName != "":
+			s.WriteString(" " + i.RoutineName)
+		case ix == 0 && i.RoutineAddress != 0:
+			fmt.Fprintf(&s, " R%04x", i.RoutineAddress)
+		default:
+			s.WriteString(" " + operand.String())
+		}
+	}
+
+	if i.Text != "" {
+		fmt.Fprintf(&s, " %q", i.Text)
+	}
+
+	switch {
+	case i.StoreName != "":
+		s.WriteString(" -> " + i.StoreName)
+	case i.Stores:
+		s.WriteString(" -> " + variableName(i.StoreVariable))
+	}
+
+	if i.Branches {
+		if i.BranchOnTrue {
+			s.WriteString(" [TRUE]")
+		} else {
+			s.WriteString(" [FALSE]")
+		}
+		switch i.BranchTarget {
+		case 0:
+			s.WriteString(" RFALSE")
+		case 1:
+			s.WriteString(" RTRUE")
+		default:
+			fmt.Fprintf(&s, " %04x", i.BranchTarget)
+		}
+	}
+	return s.String()
+}
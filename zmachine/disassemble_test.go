@@ -0,0 +1,43 @@
+package zmachine_test
+
+import (
+	"testing"
+
+	"github.com/davetcode/goz/zcore"
+	"github.com/davetcode/goz/zmachine"
+	"github.com/davetcode/goz/zstring"
+)
+
+func TestDecodeInstruction(t *testing.T) {
+	story := buildStory(5, nil, []byte{
+		0xe0, 0x1f, 0x00, 0x48, 0x05, 0x00, // call_vs 0x48 5 -> sp
+		0x41, 0x10, 0x03, 0x80, 0x08, // je G00 3 ?0x111
+		0x8c, 0xff, 0xff, // jump 0x10b
+		0xb2, 0x11, 0xaa, 0xc6, 0x34, // print "Hello"
+	})
+	core := zcore.LoadCore(story)
+	alphabets := zstring.LoadAlphabets(&core)
+
+	for _, test := range []struct {
+		address  uint32
+		length   uint32
+		expected string
+	}{
+		{0x100, 6, "call_vs R0120 #05 -> sp"},
+		{0x106, 5, "je G00 #03 [TRUE] 0111"},
+		{0x10b, 3, "jump 010b"},
+		{0x10e, 5, `print "Hello"`},
+	} {
+		instruction, err := zmachine.DecodeInstruction(&core, alphabets, test.address)
+		if err != nil {
+			t.Fatalf("failed to decode 0x%x: %v", test.address, err)
+		}
+		if instruction.String() != test.expected || instruction.Length != test.length {
+			t.Errorf("expected %q (%d bytes) at 0x%x, got %q (%d bytes)", test.expected, test.length, test.address, instruction, instruction.Length)
+		}
+	}
+
+	if _, err := zmachine.DecodeInstruction(&core, alphabets, uint32(len(story)-1)); err == nil {
+		t.Errorf("expected an error decoding past the end of memory")
+	}
+}
@@ -0,0 +1,261 @@
+package zmachine
+
+import (
+	"context"
+	"io"
+)
+
+// Frontend is the contract between the interpreter and whatever is presenting
+// the story to the player. The ZMachine calls these methods directly from the
+// goroutine executing Run so implementations are free to block, e.g. ReadLine
+// won't return until the player has typed a command.
+//
+// Every method is passed the context given to RunContext. Implementations that
+// block must give up once it is done; the methods which return an error should
+// return ctx.Err() in that case, which stops the machine.
+type Frontend interface {
+	// Print writes text to whichever window is currently active in the last
+	// screen model passed to UpdateScreen.
+	Print(ctx context.Context, text string)
+
+	// ReadLine requests a line of input, finished by one of the terminators in
+	// the request. If the request has a timeout and it passes first the
+	// response has TimedOut set and holds what had been typed so far.
+	ReadLine(ctx context.Context, request InputRequest) (InputResponse, error)
+
+	// ReadChar requests a single keypress. Either Text holds the character or
+	// TerminatingKey holds the Z-character code of a special key. Timeouts
+	// work as for ReadLine.
+	ReadChar(ctx context.Context, request CharacterRequest) (InputResponse, error)
+
+	// Save asks the frontend to persist a save; the response reports success.
+	Save(ctx context.Context, request Save) (SaveResponse, error)
+
+	// Restore asks the frontend to load a save previously written by Save.
+	Restore(ctx context.Context, request Restore) (RestoreResponse, error)
+
+	// OpenTranscript asks for somewhere to write output stream 2, it's called
+	// the first time the story turns the transcript on. A nil writer means
+	// no transcript is available and the stream stays off.
+	OpenTranscript(ctx context.Context) (io.WriteCloser, error)
+
+	// OpenCommandRecording asks for somewhere to record the player's commands
+	// (output stream 4), it's called the first time the story turns it on. A
+	// nil writer means no recording is available.
+	OpenCommandRecording(ctx context.Context) (io.WriteCloser, error)
+
+	// OpenCommandScript asks for a command file to read input from when the
+	// story selects input stream 1. A nil reader leaves input with the
+	// keyboard.
+	OpenCommandScript(ctx context.Context) (io.ReadCloser, error)
+
+	// UpdateScreen is called whenever anything in the screen model changes
+	// (window split, active window, cursor, colours, styles, font).
+	UpdateScreen(ctx context.Context, model ScreenModel)
+
+	// UpdateStatusBar is called in V1-3 before every line read so the status
+	// line can be redrawn.
+	UpdateStatusBar(ctx context.Context, status StatusBar)
+
+	// EraseWindow clears a window, see the ERASE_WINDOW opcode for the
+	// meaning of -1 and -2.
+	EraseWindow(ctx context.Context, window EraseWindowRequest)
+
+	// EraseLine clears from the cursor to the end of the line in the upper window.
+	EraseLine(ctx context.Context, request EraseLineRequest)
+
+	// DrawPicture draws a V6 picture in a window and ErasePicture fills the
+	// area it would cover with the window's background.
+	DrawPicture(ctx context.Context, request DrawPictureRequest)
+	ErasePicture(ctx context.Context, request ErasePictureRequest)
+
+	// ScrollWindow moves the contents of a V6 window up or down, the space
+	// left behind is filled with the window's background colour.
+	ScrollWindow(ctx context.Context, request ScrollWindowRequest)
+
+	// Sound plays (or prepares, stops, unloads) a sound effect.
+	Sound(ctx context.Context, request SoundEffectRequest)
+
+	// Warning reports a non-fatal problem with the story file.
+	Warning(ctx context.Context, message Warning)
+
+	// RuntimeError reports a fatal problem, the machine stops afterwards.
+	RuntimeError(ctx context.Context, message RuntimeError)
+
+	// Quit is called once when the story quits or fails with a runtime error.
+	// It is not called if the machine stopped because the context was done.
+	Quit(ctx context.Context)
+
+	// Restart is called when the story restarts, the frontend should clear
+	// the screen. The machine carries on running from the beginning of the
+	// story and sends a fresh screen model straight afterwards.
+	Restart(ctx context.Context)
+}
+
+// ChannelFrontend adapts the original channel protocol to the Frontend
+// interface. Every call is sent as a message on the output channel (text as a
+// string, everything else as its request type) and replies are read back from
+// the input and save/restore channels. This suits frontends like bubbletea
+// which need everything delivered as messages on their own event loop.
+type ChannelFrontend struct {
+	inputChannel       <-chan InputResponse
+	saveRestoreChannel <-chan SaveRestoreResponse
+	outputChannel      chan<- any
+}
+
+func NewChannelFrontend(inputChannel <-chan InputResponse, saveRestoreChannel <-chan SaveRestoreResponse, outputChannel chan<- any) *ChannelFrontend {
+	return &ChannelFrontend{
+		inputChannel:       inputChannel,
+		saveRestoreChannel: saveRestoreChannel,
+		outputChannel:      outputChannel,
+	}
+}
+
+// send delivers a message unless the context is done first, in which case
+// the message is dropped as nobody is going to act on it
+func (f *ChannelFrontend) send(ctx context.Context, msg any) {
+	select {
+	case f.outputChannel <- msg:
+	case <-ctx.Done():
+	}
+}
+
+func (f *ChannelFrontend) receiveInput(ctx context.Context) (InputResponse, error) {
+	select {
+	case response := <-f.inputChannel:
+		return response, nil
+	case <-ctx.Done():
+		return InputResponse{}, ctx.Err()
+	}
+}
+
+func (f *ChannelFrontend) receiveSaveRestore(ctx context.Context) (SaveRestoreResponse, error) {
+	select {
+	case response := <-f.saveRestoreChannel:
+		return response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *ChannelFrontend) Print(ctx context.Context, text string) {
+	f.send(ctx, text)
+}
+
+func (f *ChannelFrontend) ReadLine(ctx context.Context, request InputRequest) (InputResponse, error) {
+	f.send(ctx, request)
+	return f.receiveInput(ctx)
+}
+
+func (f *ChannelFrontend) ReadChar(ctx context.Context, request CharacterRequest) (InputResponse, error) {
+	f.send(ctx, request)
+	return f.receiveInput(ctx)
+}
+
+func (f *ChannelFrontend) Save(ctx context.Context, request Save) (SaveResponse, error) {
+	f.send(ctx, request)
+	response, err := f.receiveSaveRestore(ctx)
+	if err != nil {
+		return SaveResponse{}, err
+	}
+	if saveResponse, ok := response.(SaveResponse); ok {
+		return saveResponse, nil
+	}
+	return SaveResponse{Success: false, Result: 0}, nil
+}
+
+func (f *ChannelFrontend) Restore(ctx context.Context, request Restore) (RestoreResponse, error) {
+	f.send(ctx, request)
+	response, err := f.receiveSaveRestore(ctx)
+	if err != nil {
+		return RestoreResponse{}, err
+	}
+	if restoreResponse, ok := response.(RestoreResponse); ok {
+		return restoreResponse, nil
+	}
+	return RestoreResponse{Success: false, Result: 0}, nil
+}
+
+func (f *ChannelFrontend) OpenTranscript(ctx context.Context) (io.WriteCloser, error) {
+	f.send(ctx, OpenTranscript{})
+	response, err := f.receiveSaveRestore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if transcriptResponse, ok := response.(TranscriptResponse); ok && transcriptResponse.Transcript != nil {
+		return transcriptResponse.Transcript, nil
+	}
+	return nil, nil
+}
+
+func (f *ChannelFrontend) OpenCommandRecording(ctx context.Context) (io.WriteCloser, error) {
+	f.send(ctx, OpenCommandRecording{})
+	response, err := f.receiveSaveRestore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if recordingResponse, ok := response.(CommandRecordingResponse); ok && recordingResponse.Recording != nil {
+		return recordingResponse.Recording, nil
+	}
+	return nil, nil
+}
+
+func (f *ChannelFrontend) OpenCommandScript(ctx context.Context) (io.ReadCloser, error) {
+	f.send(ctx, OpenCommandScript{})
+	response, err := f.receiveSaveRestore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if scriptResponse, ok := response.(CommandScriptResponse); ok && scriptResponse.Script != nil {
+		return scriptResponse.Script, nil
+	}
+	return nil, nil
+}
+
+func (f *ChannelFrontend) UpdateScreen(ctx context.Context, model ScreenModel) {
+	f.send(ctx, model)
+}
+
+func (f *ChannelFrontend) UpdateStatusBar(ctx context.Context, status StatusBar) {
+	f.send(ctx, status)
+}
+
+func (f *ChannelFrontend) EraseWindow(ctx context.Context, window EraseWindowRequest) {
+	f.send(ctx, window)
+}
+
+func (f *ChannelFrontend) EraseLine(ctx context.Context, request EraseLineRequest) {
+	f.send(ctx, request)
+}
+
+func (f *ChannelFrontend) DrawPicture(ctx context.Context, request DrawPictureRequest) {
+	f.send(ctx, request)
+}
+
+func (f *ChannelFrontend) ErasePicture(ctx context.Context, request ErasePictureRequest) {
+	f.send(ctx, request)
+}
+
+func (f *ChannelFrontend) ScrollWindow(ctx context.Context, request ScrollWindowRequest) {
+	f.send(ctx, request)
+}
+
+func (f *ChannelFrontend) Sound(ctx context.Context, request SoundEffectRequest) {
+	f.send(ctx, request)
+}
+
+func (f *ChannelFrontend) Warning(ctx context.Context, message Warning) {
+	f.send(ctx, message)
+}
+
+func (f *ChannelFrontend) RuntimeError(ctx context.Context, message RuntimeError) {
+	f.send(ctx, message)
+}
+
+func (f *ChannelFrontend) Quit(ctx context.Context) {
+	f.send(ctx, Quit(true))
+}
+
+func (f *ChannelFrontend) Restart(ctx context.Context) {
+	f.send(ctx, Restart(true))
+}
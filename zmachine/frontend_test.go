@@ -0,0 +1,230 @@
+package zmachine_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+// scriptedFrontend records everything printed and answers line input from a
+// fixed list of commands. Saves are kept in memory. The first timeouts timed
+// requests time out as though partial had been typed. A click is the answer
+// to the next read_char.
+type scriptedFrontend struct {
+	output     strings.Builder
+	commands   []string
+	linesRead  int
+	statusBar  zmachine.StatusBar
+	errors     []string
+	saveData   []byte
+	transcript strings.Builder
+	recording  strings.Builder
+	script     string
+	timeouts   int
+	partial    string
+	requests   []zmachine.InputRequest
+	screen     zmachine.ScreenModel
+	scrolls    []zmachine.ScrollWindowRequest
+	click      *zmachine.InputResponse
+	pictures   []zmachine.DrawPictureRequest
+	erased     []zmachine.ErasePictureRequest
+	warnings   []string
+}
+
+func (f *scriptedFrontend) timeout(timeout time.Duration) bool {
+	if timeout > 0 && f.timeouts > 0 {
+		f.timeouts--
+		return true
+	}
+	return false
+}
+
+func (f *scriptedFrontend) Print(ctx context.Context, text string) { f.output.WriteString(text) }
+
+func (f *scriptedFrontend) ReadLine(ctx context.Context, request zmachine.InputRequest) (zmachine.InputResponse, error) {
+	f.requests = append(f.requests, request)
+	if f.timeout(request.Timeout) {
+		return zmachine.InputResponse{Text: f.partial, TimedOut: true}, nil
+	}
+	command := ""
+	if f.linesRead < len(f.commands) {
+		command = f.commands[f.linesRead]
+	}
+	f.linesRead++
+	return zmachine.InputResponse{Text: command, TerminatingKey: 13}, nil
+}
+
+func (f *scriptedFrontend) ReadChar(ctx context.Context, request zmachine.CharacterRequest) (zmachine.InputResponse, error) {
+	if f.timeout(request.Timeout) {
+		return zmachine.InputResponse{TimedOut: true}, nil
+	}
+	if click := f.click; click != nil {
+		f.click = nil
+		return *click, nil
+	}
+	return zmachine.InputResponse{TerminatingKey: 13}, nil
+}
+
+func (f *scriptedFrontend) Save(ctx context.Context, request zmachine.Save) (zmachine.SaveResponse, error) {
+	f.saveData = request.Data
+	return zmachine.SaveResponse{Success: true, Result: 1}, nil
+}
+
+func (f *scriptedFrontend) Restore(ctx context.Context, request zmachine.Restore) (zmachine.RestoreResponse, error) {
+	if f.saveData == nil {
+		return zmachine.RestoreResponse{}, nil
+	}
+	return zmachine.RestoreResponse{Success: true, Result: 2, Data: f.saveData}, nil
+}
+
+func (f *scriptedFrontend) OpenTranscript(ctx context.Context) (io.WriteCloser, error) {
+	return nopCloser{&f.transcript}, nil
+}
+
+func (f *scriptedFrontend) OpenCommandRecording(ctx context.Context) (io.WriteCloser, error) {
+	return nopCloser{&f.recording}, nil
+}
+
+func (f *scriptedFrontend) OpenCommandScript(ctx context.Context) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(f.script)), nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func (f *scriptedFrontend) UpdateScreen(ctx context.Context, model zmachine.ScreenModel) {
+	f.screen = model
+}
+func (f *scriptedFrontend) UpdateStatusBar(ctx context.Context, status zmachine.StatusBar) {
+	f.statusBar = status
+}
+func (f *scriptedFrontend) EraseWindow(ctx context.Context, window zmachine.EraseWindowRequest) {}
+func (f *scriptedFrontend) EraseLine(ctx context.Context, request zmachine.EraseLineRequest)    {}
+func (f *scriptedFrontend) DrawPicture(ctx context.Context, request zmachine.DrawPictureRequest) {
+	f.pictures = append(f.pictures, request)
+}
+func (f *scriptedFrontend) ErasePicture(ctx context.Context, request zmachine.ErasePictureRequest) {
+	f.erased = append(f.erased, request)
+}
+func (f *scriptedFrontend) ScrollWindow(ctx context.Context, request zmachine.ScrollWindowRequest) {
+	f.scrolls = append(f.scrolls, request)
+}
+func (f *scriptedFrontend) Sound(ctx context.Context, request zmachine.SoundEffectRequest) {}
+func (f *scriptedFrontend) Warning(ctx context.Context, message zmachine.Warning) {
+	f.warnings = append(f.warnings, string(message))
+}
+func (f *scriptedFrontend) RuntimeError(ctx context.Context, message zmachine.RuntimeError) {
+	f.errors = append(f.errors, string(message))
+}
+func (f *scriptedFrontend) Quit(ctx context.Context)    {}
+func (f *scriptedFrontend) Restart(ctx context.Context) {}
+
+func loadWithFrontend(t *testing.T, file string, frontend zmachine.Frontend) *zmachine.ZMachine {
+	t.Helper()
+	romFileBytes, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zmachine.LoadRomWithFrontend(romFileBytes, frontend)
+}
+
+// playStory loads story with frontend, calls setup on the machine and runs it
+// until it stops. Stories which never stop fail the test.
+func playStory(t *testing.T, story []byte, frontend *scriptedFrontend, setup ...func(*zmachine.ZMachine)) *zmachine.ZMachine {
+	t.Helper()
+	z := zmachine.LoadRomWithFrontend(story, frontend)
+	for _, f := range setup {
+		f(z)
+	}
+	for steps := 0; z.StepMachine(); steps++ {
+		if steps > 1_000_000 {
+			t.Fatal("the story never stopped")
+		}
+	}
+	return z
+}
+
+// runStory is playStory for stories which must not hit a runtime error
+func runStory(t *testing.T, story []byte, frontend *scriptedFrontend, setup ...func(*zmachine.ZMachine)) *zmachine.ZMachine {
+	t.Helper()
+	z := playStory(t, story, frontend, setup...)
+	if len(frontend.errors) > 0 {
+		t.Fatalf("runtime errors: %v", frontend.errors)
+	}
+	return z
+}
+
+// stepUntilLinesRead runs the machine until the frontend has been asked for
+// the given number of lines of input
+func stepUntilLinesRead(t *testing.T, z *zmachine.ZMachine, frontend *scriptedFrontend, lines int) {
+	t.Helper()
+	for frontend.linesRead < lines {
+		if !z.StepMachine() {
+			t.Fatalf("machine stopped early: %v", frontend.errors)
+		}
+	}
+}
+
+func TestFrontendReceivesOutputAndInput(t *testing.T) {
+	frontend := &scriptedFrontend{commands: []string{"no", "look"}}
+	z := loadWithFrontend(t, "../advent.z3", frontend)
+
+	stepUntilLinesRead(t, z, frontend, 3)
+
+	if !strings.Contains(frontend.output.String(), "Welcome to Adventure! Do you need instructions?") {
+		t.Errorf("missing welcome text, got %q", frontend.output.String())
+	}
+	if !strings.Contains(frontend.output.String(), "You are standing at the end of a road") {
+		t.Errorf("missing room description, got %q", frontend.output.String())
+	}
+	if frontend.statusBar.PlaceName == "" {
+		t.Error("status bar was never updated")
+	}
+}
+
+func TestRunContextStopsWhileWaitingForInput(t *testing.T) {
+	romFileBytes, err := os.ReadFile("../advent.z3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputChannel := make(chan any)
+	z := zmachine.LoadRom(romFileBytes, make(chan zmachine.InputResponse), make(chan zmachine.SaveRestoreResponse), outputChannel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := make(chan error)
+	go func() { result <- z.RunContext(ctx) }()
+
+	// Nobody ever answers the input request, cancelling must still unblock the machine
+	for msg := range outputChannel {
+		if _, ok := msg.(zmachine.InputRequest); ok {
+			break
+		}
+	}
+	cancel()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext did not return after cancellation")
+	}
+}
+
+func TestRunContextReportsQuit(t *testing.T) {
+	frontend := &scriptedFrontend{commands: []string{"no", "quit", "yes"}}
+	z := loadWithFrontend(t, "../advent.z3", frontend)
+
+	if err := z.RunContext(context.Background()); err != nil {
+		t.Errorf("expected nil error when the story quits, got %v", err)
+	}
+}
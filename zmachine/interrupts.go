@@ -0,0 +1,91 @@
+package zmachine
+
+import "time"
+
+// callInterrupt runs a routine to completion in the middle of another
+// instruction, as timed input needs, and returns the value it returned. False
+// means the machine stopped while the routine was running.
+func (z *ZMachine) callInterrupt(packedRoutine uint16) (uint16, bool) {
+	routineAddress := z.packedAddress(uint32(packedRoutine), false)
+	if routineAddress == 0 {
+		return 0, true
+	}
+
+	routineStart := routineAddress
+	localVariableCount := z.Core.ReadZByte(routineAddress)
+	routineAddress++
+
+	locals := make([]uint16, localVariableCount)
+	if z.Core.Version < 5 {
+		for i := range locals {
+			locals[i] = z.Core.ReadHalfWord(routineAddress)
+			routineAddress += 2
+		}
+	}
+
+	depth := len(z.callStack.frames)
+	z.callStack.push(CallStackFrame{
+		pc:             routineAddress,
+		routineAddress: routineStart,
+		locals:         locals,
+		routineStack:   make([]uint16, 0),
+		routineType:    interrupt,
+	})
+
+	for len(z.callStack.frames) > depth {
+		if z.ctx.Err() != nil || !z.StepMachine() {
+			return 0, false
+		}
+	}
+
+	return z.interruptResult, true
+}
+
+// timeout converts the tenths of a second given to read and read_char, zero
+// means no timeout or no routine to call when it passes
+func timeout(tenths uint16, routine uint16) time.Duration {
+	if tenths == 0 || routine == 0 {
+		return 0
+	}
+	return time.Duration(tenths) * 100 * time.Millisecond
+}
+
+// readLineTimed reads a line, calling the routine each time the timeout
+// passes. If the routine returns true the input is abandoned, what was typed so
+// far is kept and the terminating key is 0. Otherwise the player carries on
+// typing where they left off. The bool is false if the machine stopped.
+func (z *ZMachine) readLineTimed(request InputRequest, routine uint16) (InputResponse, bool, error) {
+	for {
+		response, err := z.readLine(request)
+		if err != nil || !response.TimedOut {
+			return response, true, err
+		}
+
+		result, ok := z.callInterrupt(routine)
+		if !ok {
+			return response, false, nil
+		}
+		if result != 0 {
+			return InputResponse{Text: response.Text, TerminatingKey: 0}, true, nil
+		}
+		request.InitialText = response.Text
+	}
+}
+
+// readCharTimed is readLineTimed for read_char, an abandoned read returns 0
+func (z *ZMachine) readCharTimed(request CharacterRequest, routine uint16) (uint16, bool, error) {
+	for {
+		charCode, timedOut, err := z.readChar(request)
+		if err != nil || !timedOut {
+			return charCode, true, err
+		}
+
+		result, ok := z.callInterrupt(routine)
+		if !ok {
+			return 0, false, nil
+		}
+		if result != 0 {
+			return 0, true, nil
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package zmachine_test
+
+import (
+	"testing"
+	"time"
+)
+
+// timerStory runs main then has a timer routine at 0x120 (packed 0x48) which
+// counts calls in G01 and returns true on the third
+func timerStory(extra []byte, main []byte) []byte {
+	code := make([]byte, 0x20)
+	copy(code, main)
+	code = append(code,
+		0x00,       // no locals
+		0x95, 0x11, // inc G01
+		0x41, 0x11, 0x03, 0xc1, // je G01 3 ?rtrue
+		0xb1, // rfalse
+	)
+	return buildStory(5, extra, code)
+}
+
+func TestReadCharTimerAbandonsInput(t *testing.T) {
+	story := timerStory(nil, []byte{
+		0xf6, 0x53, 0x01, 0x0a, 0x00, 0x48, 0x10, // read_char 1 10 timer -> G00
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{timeouts: 5}
+	z := runStory(t, story, frontend)
+
+	if calls := z.Core.ReadHalfWord(0x62); calls != 3 {
+		t.Errorf("expected the timer to be called 3 times, got %d", calls)
+	}
+	if result := z.Core.ReadHalfWord(0x60); result != 0 {
+		t.Errorf("expected abandoned read_char to return 0, got %d", result)
+	}
+}
+
+func TestReadCharTimerReturningFalseKeepsWaiting(t *testing.T) {
+	story := timerStory(nil, []byte{
+		0xf6, 0x53, 0x01, 0x0a, 0x00, 0x48, 0x10, // read_char 1 10 timer -> G00
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{timeouts: 2}
+	z := runStory(t, story, frontend)
+
+	if calls := z.Core.ReadHalfWord(0x62); calls != 2 {
+		t.Errorf("expected the timer to be called twice, got %d", calls)
+	}
+	if result := z.Core.ReadHalfWord(0x60); result != 13 {
+		t.Errorf("expected the key pressed after the timeouts, got %d", result)
+	}
+}
+
+func TestReadTimerKeepsPartialInput(t *testing.T) {
+	extra := make([]byte, 0x21)
+	extra[0x00] = 20 // Text buffer at 0x80
+	extra[0x20] = 5  // Parse buffer at 0xa0
+	story := timerStory(extra, []byte{
+		0xe4, 0x54, 0x80, 0xa0, 0x0a, 0x00, 0x48, 0x12, // aread 0x80 0xa0 10 timer -> G02
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{timeouts: 1, partial: "hel", commands: []string{"hello"}}
+	z := runStory(t, story, frontend)
+
+	if len(frontend.requests) != 2 {
+		t.Fatalf("expected the line to be requested twice, got %d", len(frontend.requests))
+	}
+	if frontend.requests[0].Timeout != time.Second {
+		t.Errorf("expected a 1s timeout, got %v", frontend.requests[0].Timeout)
+	}
+	if frontend.requests[1].InitialText != "hel" {
+		t.Errorf("expected the partial input to be carried over, got %q", frontend.requests[1].InitialText)
+	}
+	if text := string(z.Core.ReadSlice(0x82, 0x87)); text != "hello" {
+		t.Errorf("expected hello in the text buffer, got %q", text)
+	}
+	if terminator := z.Core.ReadHalfWord(0x64); terminator != 13 {
+		t.Errorf("expected the line to end with enter, got %d", terminator)
+	}
+}
+
+func TestReadOperandsComeOffTheStackInOrder(t *testing.T) {
+	extra := make([]byte, 0x11)
+	extra[0x00] = 20 // Text buffer at 0x80
+	extra[0x10] = 5  // Parse buffer at 0x90
+	story := buildStory(5, extra, []byte{
+		0xe8, 0x7f, 0x55, // push 0x55
+		0xe8, 0x7f, 0x00, // push 0
+		0xe8, 0x7f, 0x00, // push 0
+		0xe8, 0x7f, 0x90, // push 0x90
+		0xe8, 0x7f, 0x80, // push 0x80
+		0xe4, 0xaa, 0x00, 0x00, 0x00, 0x00, 0x12, // aread sp sp sp sp -> G02
+		0xe8, 0x7f, 0x00, // push 0
+		0xe8, 0x7f, 0x00, // push 0
+		0xe8, 0x7f, 0x01, // push 1
+		0xf6, 0xab, 0x00, 0x00, 0x00, 0x10, // read_char sp sp sp -> G00
+		0xe9, 0x7f, 0x11, // pull G01
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{commands: []string{"look"}}
+	z := runStory(t, story, frontend)
+
+	if text := string(z.Core.ReadSlice(0x82, 0x86)); z.Core.ReadZByte(0x81) != 4 || text != "look" {
+		t.Errorf("expected the command in the text buffer popped first, got %q", text)
+	}
+	if sentinel := z.Core.ReadHalfWord(0x62); sentinel != 0x55 {
+		t.Errorf("expected every operand to be popped leaving 0x55 on the stack, got %x", sentinel)
+	}
+}
@@ -1,5 +1,11 @@
 package zmachine
 
+import (
+	"fmt"
+
+	"github.com/davetcode/goz/zcore"
+)
+
 type OperandType int
 type OpcodeForm int
 type OperandCount int
@@ -43,6 +49,16 @@ func (operand *Operand) Value(z *ZMachine) uint16 {
 	}
 }
 
+// values reads every operand given once and in order, so those which come off
+// the stack are popped in the order the story expects
+func (opcode *Opcode) values(z *ZMachine) [8]uint16 {
+	var values [8]uint16
+	for i := range opcode.numOperands {
+		values[i] = opcode.operands[i].Value(z)
+	}
+	return values
+}
+
 type Opcode struct {
 	pc           uint32
 	opcodeByte   uint8
@@ -53,13 +69,35 @@ type Opcode struct {
 	numOperands  int        // Different to operandCount which tracks how many operands there _should_ be for decoding instructions
 }
 
-func parseVariableOperands(z *ZMachine, frame *CallStackFrame, opcode *Opcode) {
-	operandTypeByte := z.readIncPC(frame)
+// instructionReader reads an instruction's bytes from memory in order,
+// remembering if it ran off the end rather than panicking
+type instructionReader struct {
+	core    *zcore.Core
+	pc      uint32
+	overrun bool
+}
+
+func (r *instructionReader) readByte() uint8 {
+	if r.pc >= r.core.MemoryLength() {
+		r.overrun = true
+		return 0
+	}
+	v := r.core.ReadZByte(r.pc)
+	r.pc++
+	return v
+}
+
+func (r *instructionReader) readHalfWord() uint16 {
+	return uint16(r.readByte())<<8 | uint16(r.readByte())
+}
+
+func parseVariableOperands(r *instructionReader, opcode *Opcode) {
+	operandTypeByte := r.readByte()
 	operandTypeByteExtendedCall := uint8(0)
 	maxVariables := 4
 
 	if (opcode.opcodeNumber == 12 || opcode.opcodeNumber == 26) && opcode.operandCount == VAR && opcode.opcodeForm != extForm {
-		operandTypeByteExtendedCall = z.readIncPC(frame)
+		operandTypeByteExtendedCall = r.readByte()
 		maxVariables = 8
 	}
 
@@ -77,35 +115,49 @@ func parseVariableOperands(z *ZMachine, frame *CallStackFrame, opcode *Opcode) {
 
 		switch operandType {
 		case smallConstant, variable:
-			opcode.operands[opcode.numOperands] = Operand{operandType: operandType, value: uint16(z.readIncPC(frame))}
+			opcode.operands[opcode.numOperands] = Operand{operandType: operandType, value: uint16(r.readByte())}
 			opcode.numOperands++
 		case largeConstant:
-			opcode.operands[opcode.numOperands] = Operand{operandType: operandType, value: z.ReadHalfWordIncPC(frame)}
+			opcode.operands[opcode.numOperands] = Operand{operandType: operandType, value: r.readHalfWord()}
 			opcode.numOperands++
 		}
 	}
 }
 
+// ParseOpcode decodes the instruction at the current PC and moves the PC on
+// to whatever follows its operands
 func ParseOpcode(z *ZMachine) (Opcode, error) {
 	frame, err := z.callStack.peek()
 	if err != nil {
 		return Opcode{}, err
 	}
+	opcode, next, err := decodeOpcode(&z.Core, frame.pc)
+	if err != nil {
+		return Opcode{}, err
+	}
+	frame.pc = next
+	return opcode, nil
+}
+
+// decodeOpcode decodes the opcode and operands of the instruction at address,
+// returning the address of the store, branch or text which follows them
+func decodeOpcode(core *zcore.Core, address uint32) (Opcode, uint32, error) {
+	r := instructionReader{core: core, pc: address}
 	opcode := Opcode{
-		pc: frame.pc,
+		pc: address,
 	}
-	opcodeByte := z.readIncPC(frame)
+	opcodeByte := r.readByte()
 	opcode.opcodeForm = OpcodeForm(opcodeByte >> 6)
 	opcode.opcodeByte = opcodeByte
 
 	// First decode the opcode type (Short, Long, Variable, Extended (v5+))
-	if opcodeByte == 0xbe && z.Core.Version >= 5 {
-		opcode.opcodeByte = z.readIncPC(frame)
+	if opcodeByte == 0xbe && core.Version >= 5 {
+		opcode.opcodeByte = r.readByte()
 		opcode.opcodeNumber = opcode.opcodeByte
 		opcode.opcodeForm = extForm
 		opcode.operandCount = VAR
 
-		parseVariableOperands(z, frame, &opcode)
+		parseVariableOperands(&r, &opcode)
 	} else if opcode.opcodeForm == varForm {
 		opcode.opcodeNumber = opcodeByte & 0b1_1111 // 5 bits
 		opcode.operandCount = VAR
@@ -113,18 +165,18 @@ func ParseOpcode(z *ZMachine) (Opcode, error) {
 			opcode.operandCount = OP2
 		}
 
-		parseVariableOperands(z, frame, &opcode)
+		parseVariableOperands(&r, &opcode)
 	} else if opcode.opcodeForm == shortForm {
 		opcode.opcodeNumber = opcodeByte & 0b1111 // 4 bits
 		operandType := (opcodeByte >> 4) & 0b11
 
 		switch operandType {
 		case 0b00: // Large Constant (2 bytes)
-			opcode.operands[0] = Operand{operandType: OperandType(operandType), value: z.ReadHalfWordIncPC(frame)}
+			opcode.operands[0] = Operand{operandType: OperandType(operandType), value: r.readHalfWord()}
 			opcode.numOperands = 1
 			opcode.operandCount = OP1
 		case 0b01, 0b10: // Small constant or variable
-			opcode.operands[0] = Operand{operandType: OperandType(operandType), value: uint16(z.readIncPC(frame))}
+			opcode.operands[0] = Operand{operandType: OperandType(operandType), value: uint16(r.readByte())}
 			opcode.numOperands = 1
 			opcode.operandCount = OP1
 		case 0b11: // Omitted
@@ -145,10 +197,161 @@ func ParseOpcode(z *ZMachine) (Opcode, error) {
 		}
 
 		for i, operandType := range []OperandType{operand1Type, operand2Type} {
-			opcode.operands[i] = Operand{operandType: operandType, value: uint16(z.readIncPC(frame))}
+			opcode.operands[i] = Operand{operandType: operandType, value: uint16(r.readByte())}
 		}
 		opcode.numOperands = 2
 	}
 
-	return opcode, nil
+	if r.overrun {
+		return Opcode{}, 0, fmt.Errorf("instruction at 0x%x runs past the end of memory", address)
+	}
+	return opcode, r.pc, nil
+}
+
+// Opcode names as used by Inform and the standard, indexed by opcode number.
+// Names which changed between versions are resolved in mnemonic.
+var (
+	op0Names = [16]string{
+		"rtrue", "rfalse", "print", "print_ret", "nop", "save", "restore", "restart",
+		"ret_popped", "pop", "quit", "new_line", "show_status", "verify", "extended", "piracy",
+	}
+	op1Names = [16]string{
+		"jz", "get_sibling", "get_child", "get_parent", "get_prop_len", "inc", "dec", "print_addr",
+		"call_1s", "remove_obj", "print_obj", "ret", "jump", "print_paddr", "load", "not",
+	}
+	op2Names = [32]string{
+		"", "je", "jl", "jg", "dec_chk", "inc_chk", "jin", "test",
+		"or", "and", "test_attr", "set_attr", "clear_attr", "store", "insert_obj", "loadw",
+		"loadb", "get_prop", "get_prop_addr", "get_next_prop", "add", "sub", "mul", "div",
+		"mod", "call_2s", "call_2n", "set_colour", "throw", "", "", "",
+	}
+	varNames = [32]string{
+		"call_vs", "storew", "storeb", "put_prop", "aread", "print_char", "print_num", "random",
+		"push", "pull", "split_window", "set_window", "call_vs2", "erase_window", "erase_line", "set_cursor",
+		"get_cursor", "set_text_style", "buffer_mode", "output_stream", "input_stream", "sound_effect", "read_char", "scan_table",
+		"not", "call_vn", "call_vn2", "tokenise", "encode_text", "copy_table", "print_table", "check_arg_count",
+	}
+	extNames = [30]string{
+		"save", "restore", "log_shift", "art_shift", "set_font", "draw_picture", "picture_data", "erase_picture",
+		"set_margins", "save_undo", "restore_undo", "print_unicode", "check_unicode", "set_true_colour", "", "",
+		"move_window", "window_size", "window_style", "get_wind_prop", "scroll_window", "pop_stack", "read_mouse", "mouse_window",
+		"push_stack", "put_wind_prop", "print_form", "make_menu", "picture_table", "buffer_screen",
+	}
+)
+
+// mnemonic names the instruction for the given story version, opcodes which
+// don't exist are "unknown"
+func (opcode *Opcode) mnemonic(version uint8) string {
+	name := ""
+	switch {
+	case opcode.opcodeForm == extForm:
+		if int(opcode.opcodeNumber) < len(extNames) {
+			name = extNames[opcode.opcodeNumber]
+		}
+	case opcode.operandCount == OP0:
+		name = op0Names[opcode.opcodeNumber&0xf]
+		if opcode.opcodeNumber == 9 && version >= 5 {
+			name = "catch"
+		}
+	case opcode.operandCount == OP1:
+		name = op1Names[opcode.opcodeNumber&0xf]
+		if opcode.opcodeNumber == 15 && version >= 5 {
+			name = "call_1n"
+		}
+	case opcode.operandCount == OP2:
+		name = op2Names[opcode.opcodeNumber&0x1f]
+	default:
+		name = varNames[opcode.opcodeNumber&0x1f]
+		switch {
+		case opcode.opcodeNumber == 0 && version < 4:
+			name = "call"
+		case opcode.opcodeNumber == 4 && version < 5:
+			name = "sread"
+		}
+	}
+
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+// stores is true if the instruction is followed by a variable to store its
+// result in
+func (opcode *Opcode) stores(version uint8) bool {
+	switch {
+	case opcode.opcodeForm == extForm:
+		switch opcode.opcodeNumber {
+		case 0, 1, 2, 3, 4, 9, 10, 12, 19, 29:
+			return true
+		}
+	case opcode.operandCount == OP0:
+		switch opcode.opcodeNumber {
+		case 5, 6:
+			return version == 4
+		case 9:
+			return version >= 5
+		}
+	case opcode.operandCount == OP1:
+		switch opcode.opcodeNumber {
+		case 1, 2, 3, 4, 8, 14:
+			return true
+		case 15:
+			return version < 5
+		}
+	case opcode.operandCount == OP2:
+		switch opcode.opcodeNumber {
+		case 8, 9, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25:
+			return true
+		}
+	default:
+		switch opcode.opcodeNumber {
+		case 0, 7, 12, 22, 23, 24:
+			return true
+		case 4:
+			return version >= 5
+		case 9:
+			return version == 6
+		}
+	}
+	return false
+}
+
+// branches is true if the instruction is followed by a branch offset
+func (opcode *Opcode) branches(version uint8) bool {
+	switch {
+	case opcode.opcodeForm == extForm:
+		switch opcode.opcodeNumber {
+		case 6, 24, 27:
+			return true
+		}
+	case opcode.operandCount == OP0:
+		switch opcode.opcodeNumber {
+		case 5, 6:
+			return version < 4
+		case 13, 15:
+			return true
+		}
+	case opcode.operandCount == OP1:
+		switch opcode.opcodeNumber {
+		case 0, 1, 2:
+			return true
+		}
+	case opcode.operandCount == OP2:
+		switch opcode.opcodeNumber {
+		case 1, 2, 3, 4, 5, 6, 7, 10:
+			return true
+		}
+	default:
+		switch opcode.opcodeNumber {
+		case 23, 31:
+			return true
+		}
+	}
+	return false
+}
+
+// hasText is true for print and print_ret, which are followed by a string
+func (opcode *Opcode) hasText() bool {
+	return opcode.opcodeForm != extForm && opcode.operandCount == OP0 && (opcode.opcodeNumber == 2 || opcode.opcodeNumber == 3)
 }
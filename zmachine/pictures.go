@@ -0,0 +1,147 @@
+package zmachine
+
+import (
+	"github.com/davetcode/goz/blorb"
+	"github.com/davetcode/goz/picture"
+)
+
+// Pictures are measured in screen units, a character cell each, taken to be 4
+// pixels wide and 8 high so that a 320x200 picture, the screen Infocom drew
+// its V6 pictures for, fills the 80x25 screen
+const (
+	pixelsPerUnitX = 4
+	pixelsPerUnitY = 8
+)
+
+// DrawPictureRequest asks for a picture from the Blorb to be drawn scaled to
+// Height by Width screen units with its top left at Y, X in Window, where 1, 1
+// is the window's top left as for the cursor
+type DrawPictureRequest struct {
+	Picture uint16
+	Window  int
+	Y       int
+	X       int
+	Height  int
+	Width   int
+	Type    string // The Blorb chunk type, "PNG " or "JPEG"
+	Data    []byte
+}
+
+// ErasePictureRequest asks for the area a picture would cover to be filled
+// with the window's background colour
+type ErasePictureRequest struct {
+	Picture    uint16
+	Window     int
+	Y          int
+	X          int
+	Height     int
+	Width      int
+	Background Color
+}
+
+// claimPictures sets the header bit telling V6 stories pictures can be drawn,
+// which they can when the story came in a Blorb with pictures
+func (z *ZMachine) claimPictures() {
+	if z.Core.Version != 6 || z.resources == nil || len(z.resources.Numbers(blorb.Picture)) == 0 {
+		return
+	}
+	z.Core.FlagByte1 |= 0b0000_0010
+	z.Core.InterpreterWriteZByte(0x01, z.Core.FlagByte1)
+}
+
+// picture finds a picture in the Blorb along with its size in screen units,
+// false if there's no such picture or it can't be read
+func (z *ZMachine) picture(number uint16) (blorb.Resource, int, int, bool) {
+	if z.resources == nil || number == 0 {
+		return blorb.Resource{}, 0, 0, false
+	}
+	resource, ok := z.resources.Picture(uint32(number))
+	if !ok {
+		return blorb.Resource{}, 0, 0, false
+	}
+	width, height, err := picture.Size(resource.Data)
+	if err != nil {
+		z.warnOnce("picture_format", "Warning: picture %d can't be read: %v (PC = %x)", number, err, z.currentInstructionPC)
+		return blorb.Resource{}, 0, 0, false
+	}
+	return resource, (height + pixelsPerUnitY - 1) / pixelsPerUnitY, (width + pixelsPerUnitX - 1) / pixelsPerUnitX, true
+}
+
+// picturePosition is where draw_picture and erase_picture put a picture in the
+// current window, the cursor stands in for a position of 0 or one left out
+func (z *ZMachine) picturePosition(operands [8]uint16) (int, int) {
+	w := &z.screenModel.Windows[z.screenModel.CurrentWindow]
+	y, x := w.CursorY, w.CursorX
+	if operands[1] != 0 {
+		y = int(operands[1])
+	}
+	if operands[2] != 0 {
+		x = int(operands[2])
+	}
+	return y, x
+}
+
+// drawPicture is draw_picture
+func (z *ZMachine) drawPicture(opcode *Opcode) {
+	operands := opcode.values(z)
+	number := operands[0]
+	resource, height, width, ok := z.picture(number)
+	if !ok {
+		z.warnOnce("draw_picture", "Warning: @draw_picture %d isn't in the Blorb (PC = %x)", number, opcode.pc)
+		return
+	}
+	y, x := z.picturePosition(operands)
+	z.frontend.DrawPicture(z.ctx, DrawPictureRequest{
+		Picture: number,
+		Window:  z.screenModel.CurrentWindow,
+		Y:       y,
+		X:       x,
+		Height:  height,
+		Width:   width,
+		Type:    resource.Type,
+		Data:    resource.Data,
+	})
+}
+
+// erasePicture is erase_picture
+func (z *ZMachine) erasePicture(opcode *Opcode) {
+	operands := opcode.values(z)
+	number := operands[0]
+	_, height, width, ok := z.picture(number)
+	if !ok {
+		return
+	}
+	y, x := z.picturePosition(operands)
+	z.frontend.ErasePicture(z.ctx, ErasePictureRequest{
+		Picture:    number,
+		Window:     z.screenModel.CurrentWindow,
+		Y:          y,
+		X:          x,
+		Height:     height,
+		Width:      width,
+		Background: z.screenModel.Windows[z.screenModel.CurrentWindow].Background,
+	})
+}
+
+// pictureData is picture_data, writing a picture's height and width to a
+// table, or for picture 0 how many pictures there are and the Blorb's
+// release number. False if there's no such picture.
+func (z *ZMachine) pictureData(number uint16, table uint32) bool {
+	if number == 0 {
+		if z.resources == nil {
+			return false
+		}
+		count := len(z.resources.Numbers(blorb.Picture))
+		z.Core.WriteHalfWord(table, uint16(count))
+		z.Core.WriteHalfWord(table+2, z.resources.Release)
+		return count > 0
+	}
+
+	_, height, width, ok := z.picture(number)
+	if !ok {
+		return false
+	}
+	z.Core.WriteHalfWord(table, uint16(height))
+	z.Core.WriteHalfWord(table+2, uint16(width))
+	return true
+}
@@ -0,0 +1,114 @@
+package zmachine_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"slices"
+	"testing"
+
+	"github.com/davetcode/goz/blorb"
+	"github.com/davetcode/goz/zmachine"
+)
+
+// pictureBlorb is a Blorb holding a single 40x16 pixel PNG as picture 1, and
+// a release number of 3
+func pictureBlorb(t *testing.T) *blorb.Blorb {
+	t.Helper()
+	var picture bytes.Buffer
+	if err := png.Encode(&picture, image.NewGray(image.Rect(0, 0, 40, 16))); err != nil {
+		t.Fatal(err)
+	}
+
+	chunk := func(id string, data []byte) []byte {
+		c := binary.BigEndian.AppendUint32([]byte(id), uint32(len(data)))
+		c = append(c, data...)
+		if len(data)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	index := binary.BigEndian.AppendUint32(nil, 1)
+	index = append(index, "Pict"...)
+	index = binary.BigEndian.AppendUint32(index, 1)
+	index = binary.BigEndian.AppendUint32(index, 12+8+16)
+
+	form := append([]byte("IFRS"), chunk("RIdx", index)...)
+	form = append(form, chunk("PNG ", picture.Bytes())...)
+	form = append(form, chunk("RelN", []byte{0, 3})...)
+	b, err := blorb.Parse(chunk("FORM", form))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestV6Pictures(t *testing.T) {
+	story := v6Story(nil, []byte{
+		0xbe, 0x06, 0x5f, 0x01, 0x80, 0xc4, // picture_data 1 0x80 ?+4
+		0x95, 0x10, // inc G00
+		0xbe, 0x06, 0x5f, 0x02, 0x84, 0xc4, // picture_data 2 0x84 ?+4
+		0x95, 0x10, // inc G00
+		0xbe, 0x06, 0x5f, 0x00, 0x88, 0xc4, // picture_data 0 0x88 ?+4
+		0x95, 0x10, // inc G00
+		0xef, 0x5f, 0x02, 0x04, // set_cursor 2 4
+		0xbe, 0x05, 0x7f, 0x01, // draw_picture 1
+		0xeb, 0x7f, 0x01, // set_window 1
+		0xbe, 0x05, 0x57, 0x01, 0x03, 0x05, // draw_picture 1 3 5
+		0xbe, 0x07, 0x57, 0x01, 0x03, 0x05, // erase_picture 1 3 5
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend, func(z *zmachine.ZMachine) { z.SetResources(pictureBlorb(t)) })
+
+	if z.Core.ReadZByte(0x01)&0b10 == 0 {
+		t.Errorf("expected the header to claim pictures")
+	}
+	if failed := z.Core.ReadHalfWord(0x60); failed != 1 {
+		t.Errorf("expected only picture_data for the missing picture 2 to fail, got %d failures", failed)
+	}
+	data := []uint16{z.Core.ReadHalfWord(0x80), z.Core.ReadHalfWord(0x82), z.Core.ReadHalfWord(0x84), z.Core.ReadHalfWord(0x88), z.Core.ReadHalfWord(0x8a)}
+	if want := []uint16{2, 10, 0, 1, 3}; !slices.Equal(data, want) {
+		t.Errorf("expected the picture's size in units then a count and release of %v, got %v", want, data)
+	}
+
+	if len(frontend.pictures) != 2 {
+		t.Fatalf("expected 2 pictures drawn, got %+v", frontend.pictures)
+	}
+	first, second := frontend.pictures[0], frontend.pictures[1]
+	if first.Window != 0 || first.Y != 2 || first.X != 4 || first.Height != 2 || first.Width != 10 || first.Type != "PNG " {
+		t.Errorf("expected picture 1 at the cursor in window 0, got %+v", first)
+	}
+	if second.Window != 1 || second.Y != 3 || second.X != 5 {
+		t.Errorf("expected picture 1 at 3,5 in window 1, got %+v", second)
+	}
+	if len(frontend.erased) != 1 || frontend.erased[0].Y != 3 || frontend.erased[0].Width != 10 {
+		t.Errorf("expected the second picture erased, got %+v", frontend.erased)
+	}
+}
+
+func TestV6PicturesPopEveryStackOperand(t *testing.T) {
+	story := v6Story(nil, []byte{
+		0xe8, 0x7f, 0x01, // push 1
+		0xe8, 0x7f, 0x05, // push 5
+		0xe8, 0x7f, 0x03, // push 3
+		0xbe, 0x05, 0x6b, 0x01, 0x00, 0x00, // draw_picture 1 sp sp
+		0xe8, 0x7f, 0x09, // push 9
+		0xe8, 0x7f, 0x09, // push 9
+		0xbe, 0x05, 0x6b, 0x02, 0x00, 0x00, // draw_picture 2 sp sp, there's no picture 2
+		0x54, 0x00, 0x00, 0x10, // add sp 0 -> G00
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend, func(z *zmachine.ZMachine) { z.SetResources(pictureBlorb(t)) })
+
+	if len(frontend.pictures) != 1 || frontend.pictures[0].Y != 3 || frontend.pictures[0].X != 5 {
+		t.Errorf("expected picture 1 drawn at 3,5, got %+v", frontend.pictures)
+	}
+	if bottom := z.Core.ReadHalfWord(0x60); bottom != 1 {
+		t.Errorf("expected only the first push left on the stack, got %d", bottom)
+	}
+}
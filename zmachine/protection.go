@@ -0,0 +1,49 @@
+package zmachine
+
+// MemoryStrictness selects what happens when the story writes to static
+// memory or to a header field it mustn't change. The write never happens.
+type MemoryStrictness int
+
+const (
+	MemoryStrictnessWarn   MemoryStrictness = iota // Warn the first time, the default
+	MemoryStrictnessIgnore MemoryStrictness = iota // Drop the write silently
+	MemoryStrictnessFatal  MemoryStrictness = iota // Stop with a runtime error
+)
+
+// SetMemoryStrictness sets what happens to writes to protected memory, and
+// keeps doing so across restarts
+func (z *ZMachine) SetMemoryStrictness(strictness MemoryStrictness) {
+	z.memoryStrictness = strictness
+	z.Core.SetProtectionHook(z.refuseWrite)
+}
+
+// protectedWrite is a write the core refused, kept until the instruction
+// making it has finished so that MemoryStrictnessFatal can stop the machine
+type protectedWrite struct {
+	address uint32
+	size    int
+	pc      uint32
+}
+
+// refuseWrite is the core's protection hook
+func (z *ZMachine) refuseWrite(address uint32, size int) {
+	switch z.memoryStrictness {
+	case MemoryStrictnessWarn:
+		z.warnOnce("protected_write", "Warning: Write of %d bytes to protected memory at %x (PC = %x)", size, address, z.currentInstructionPC)
+	case MemoryStrictnessFatal:
+		if z.protectedWrite == nil {
+			z.protectedWrite = &protectedWrite{address: address, size: size, pc: z.currentInstructionPC}
+		}
+	}
+}
+
+// checkProtectedWrites stops the machine if the instruction just executed
+// made a write which MemoryStrictnessFatal doesn't allow
+func (z *ZMachine) checkProtectedWrites() bool {
+	if z.protectedWrite == nil {
+		return true
+	}
+	write := z.protectedWrite
+	z.protectedWrite = nil
+	return z.reportError("Write of %d bytes to protected memory at %x (PC = %x)", write.size, write.address, write.pc)
+}
@@ -0,0 +1,47 @@
+package zmachine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+func TestProtectedMemoryWrites(t *testing.T) {
+	story := buildStory(5, nil, []byte{
+		0xe2, 0x57, 0x05, 0x00, 0x01, // storeb 5 0 1
+		0xe2, 0x17, 0x01, 0x00, 0x00, 0x55, // storeb 0x100 0 0x55
+		0xe2, 0x57, 0x80, 0x00, 0x55, // storeb 0x80 0 0x55
+		0xba, // quit
+	})
+
+	for _, test := range []struct {
+		strictness zmachine.MemoryStrictness
+		stored     uint8
+		warnings   int
+		err        string
+	}{
+		{zmachine.MemoryStrictnessWarn, 0x55, 1, ""},
+		{zmachine.MemoryStrictnessIgnore, 0x55, 0, ""},
+		{zmachine.MemoryStrictnessFatal, 0, 0, "protected memory at 5 (PC = 100)"},
+	} {
+		frontend := &scriptedFrontend{}
+		z := playStory(t, append([]byte(nil), story...), frontend, func(z *zmachine.ZMachine) { z.SetMemoryStrictness(test.strictness) })
+
+		if code := z.Core.ReadZByte(0x100); code != 0xe2 {
+			t.Errorf("%d: expected the write to static memory to be dropped, got %x", test.strictness, code)
+		}
+		if header := z.Core.ReadZByte(0x05); header != 0 {
+			t.Errorf("%d: expected the write to the header to be dropped, got %x", test.strictness, header)
+		}
+		if stored := z.Core.ReadZByte(0x80); stored != test.stored {
+			t.Errorf("%d: expected 0x80 to hold %x, got %x", test.strictness, test.stored, stored)
+		}
+		if len(frontend.warnings) != test.warnings {
+			t.Errorf("%d: expected %d warnings, got %q", test.strictness, test.warnings, frontend.warnings)
+		}
+		if failed := len(frontend.errors) == 1 && strings.Contains(frontend.errors[0], test.err); failed != (test.err != "") {
+			t.Errorf("%d: expected a runtime error %q, got %q", test.strictness, test.err, frontend.errors)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package zmachine
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// SaveFormat selects the file format written by ExportSaveState, restores
+// work out the format from the file itself.
+type SaveFormat int
+
+const (
+	SaveFormatQuetzal SaveFormat = iota // Standard Quetzal 1.4, readable by other interpreters
+	SaveFormatGOZM    SaveFormat = iota // Original goz format, see SaveState.serialize
+)
+
+const (
+	quetzalDiscardResult = 0x10
+	quetzalLocalsMask    = 0x0f
+)
+
+// iffChunk is a single chunk from an IFF file, the id is always 4 characters
+type iffChunk struct {
+	id   string
+	data []byte
+}
+
+func appendChunk(data []byte, id string, chunk []byte) []byte {
+	data = append(data, id...)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(chunk)))
+	data = append(data, chunk...)
+	// Chunks are padded to an even length, the pad byte isn't in the length
+	if len(chunk)%2 == 1 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+func readChunks(data []byte) ([]iffChunk, bool) {
+	var chunks []iffChunk
+	for offset := 0; offset < len(data); {
+		if offset+8 > len(data) {
+			return nil, false
+		}
+		id := string(data[offset : offset+4])
+		length := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+		offset += 8
+		if offset+length > len(data) {
+			return nil, false
+		}
+		chunks = append(chunks, iffChunk{id: id, data: data[offset : offset+length]})
+		offset += length + length%2
+	}
+	return chunks, true
+}
+
+func isQuetzal(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "FORM" && string(data[8:12]) == "IFZS"
+}
+
+// quetzalHeader is the contents of the IFhd chunk minus the PC, it identifies
+// which story a save belongs to
+func (z *ZMachine) quetzalHeader() []byte {
+	header := make([]byte, 0, 13)
+	header = append(header, z.Core.ReadSlice(0x02, 0x04)...) // Release number
+	header = append(header, z.Core.ReadSlice(0x12, 0x18)...) // Serial code
+	header = append(header, z.Core.ReadSlice(0x1c, 0x1e)...) // Checksum
+	return header
+}
+
+// compressMemory implements the CMem run length encoding. Memory is XORed
+// with the original story file so unchanged bytes become zero, then each run
+// of zeros is written as a zero followed by the run length minus one.
+// Trailing zeros are dropped entirely.
+func compressMemory(current []uint8, original []uint8) []byte {
+	var result []byte
+	zeroRun := 0
+	for i, b := range current {
+		b ^= original[i]
+		if b == 0 {
+			zeroRun++
+			continue
+		}
+		for zeroRun > 0 {
+			run := min(zeroRun, 256)
+			result = append(result, 0, byte(run-1))
+			zeroRun -= run
+		}
+		result = append(result, b)
+	}
+	return result
+}
+
+func decompressMemory(compressed []byte, original []uint8) ([]uint8, bool) {
+	memory := make([]uint8, len(original))
+	copy(memory, original)
+
+	address := 0
+	for i := 0; i < len(compressed); i++ {
+		if compressed[i] == 0 {
+			if i+1 >= len(compressed) {
+				return nil, false
+			}
+			i++
+			address += int(compressed[i]) + 1
+			continue
+		}
+		if address >= len(memory) {
+			return nil, false
+		}
+		memory[address] ^= compressed[i]
+		address++
+	}
+
+	if address > len(memory) {
+		return nil, false
+	}
+	return memory, true
+}
+
+// serializeQuetzal writes the state in Quetzal format. Each Quetzal frame
+// records where its caller resumes whereas our frames record where they
+// themselves resume, so the return PC comes from the frame below. Frame 0 has
+// no caller and, outside V6, no locals which makes it the dummy frame Quetzal
+// expects to hold the main evaluation stack.
+func (z *ZMachine) serializeQuetzal(state SaveState) []byte {
+	frames := state.callStack.frames
+
+	ifhd := z.quetzalHeader()
+	pc := frames[len(frames)-1].pc
+	ifhd = append(ifhd, byte(pc>>16), byte(pc>>8), byte(pc))
+
+	var stks []byte
+	for i, frame := range frames {
+		var returnPC uint32
+		var flags, resultVariable, args uint8
+
+		flags = uint8(len(frame.locals)) & quetzalLocalsMask
+		if i > 0 {
+			returnPC = frames[i-1].pc
+			if frame.routineType == function {
+				resultVariable = z.Core.ReadZByte(returnPC)
+				returnPC++
+			} else {
+				flags |= quetzalDiscardResult
+			}
+			args = uint8(1<<min(frame.numValuesPassed, 7)) - 1
+		}
+
+		stks = append(stks, byte(returnPC>>16), byte(returnPC>>8), byte(returnPC), flags, resultVariable, args)
+		stks = binary.BigEndian.AppendUint16(stks, uint16(len(frame.routineStack)))
+		for _, local := range frame.locals {
+			stks = binary.BigEndian.AppendUint16(stks, local)
+		}
+		for _, val := range frame.routineStack {
+			stks = binary.BigEndian.AppendUint16(stks, val)
+		}
+	}
+
+	var form []byte
+	form = append(form, "IFZS"...)
+	form = appendChunk(form, "IFhd", ifhd)
+	form = appendChunk(form, "CMem", compressMemory(state.dynamicMemory, z.originalMemory))
+	form = appendChunk(form, "Stks", stks)
+
+	data := []byte("FORM")
+	data = binary.BigEndian.AppendUint32(data, uint32(len(form)))
+	return append(data, form...)
+}
+
+// deserializeQuetzal reads a Quetzal save back into a state, failing if the
+// save belongs to a different story
+func (z *ZMachine) deserializeQuetzal(data []byte) (SaveState, bool) {
+	if !isQuetzal(data) {
+		return SaveState{}, false
+	}
+	formLength := int(binary.BigEndian.Uint32(data[4:8]))
+	if formLength < 4 || 8+formLength > len(data) {
+		return SaveState{}, false
+	}
+	chunks, ok := readChunks(data[12 : 8+formLength])
+	if !ok {
+		return SaveState{}, false
+	}
+
+	var ifhd, stks []byte
+	var dynamicMemory []uint8
+	for _, chunk := range chunks {
+		switch chunk.id {
+		case "IFhd":
+			ifhd = chunk.data
+		case "CMem":
+			if dynamicMemory, ok = decompressMemory(chunk.data, z.originalMemory); !ok {
+				return SaveState{}, false
+			}
+		case "UMem":
+			if len(chunk.data) != len(z.originalMemory) {
+				return SaveState{}, false
+			}
+			dynamicMemory = make([]uint8, len(chunk.data))
+			copy(dynamicMemory, chunk.data)
+		case "Stks":
+			stks = chunk.data
+		}
+	}
+
+	if len(ifhd) < 13 || dynamicMemory == nil || stks == nil {
+		return SaveState{}, false
+	}
+	if string(ifhd[0:10]) != string(z.quetzalHeader()) {
+		return SaveState{}, false
+	}
+
+	var frames []CallStackFrame
+	var returnPCs []uint32
+	for offset := 0; offset < len(stks); {
+		if offset+8 > len(stks) {
+			return SaveState{}, false
+		}
+		returnPC := uint32(stks[offset])<<16 | uint32(stks[offset+1])<<8 | uint32(stks[offset+2])
+		flags := stks[offset+3]
+		args := stks[offset+5]
+		stackSize := int(binary.BigEndian.Uint16(stks[offset+6 : offset+8]))
+		localCount := int(flags & quetzalLocalsMask)
+		offset += 8
+
+		if offset+(localCount+stackSize)*2 > len(stks) {
+			return SaveState{}, false
+		}
+
+		frame := CallStackFrame{
+			routineType:     function,
+			numValuesPassed: bits.OnesCount8(args),
+			locals:          make([]uint16, localCount),
+			routineStack:    make([]uint16, stackSize),
+		}
+		if flags&quetzalDiscardResult != 0 {
+			frame.routineType = procedure
+			returnPCs = append(returnPCs, returnPC)
+		} else {
+			// Our frames resume at the store byte, not after it
+			returnPCs = append(returnPCs, returnPC-1)
+		}
+
+		for i := range frame.locals {
+			frame.locals[i] = binary.BigEndian.Uint16(stks[offset : offset+2])
+			offset += 2
+		}
+		for i := range frame.routineStack {
+			frame.routineStack[i] = binary.BigEndian.Uint16(stks[offset : offset+2])
+			offset += 2
+		}
+		frames = append(frames, frame)
+	}
+
+	if len(frames) == 0 {
+		return SaveState{}, false
+	}
+
+	for i := range len(frames) - 1 {
+		frames[i].pc = returnPCs[i+1]
+	}
+	frames[len(frames)-1].pc = uint32(ifhd[10])<<16 | uint32(ifhd[11])<<8 | uint32(ifhd[12])
+
+	return SaveState{
+		staticMemoryBase: z.Core.StaticMemoryBase,
+		dynamicMemory:    dynamicMemory,
+		callStack:        CallStack{frames: frames},
+	}, true
+}
@@ -0,0 +1,101 @@
+package zmachine_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+// playSaveRestore saves inside the building, walks out and restores, the
+// player should be back inside afterwards
+func playSaveRestore(t *testing.T, format zmachine.SaveFormat) []byte {
+	t.Helper()
+	frontend := &scriptedFrontend{commands: []string{"no", "east", "save", "west", "restore", "look"}}
+	z := loadWithFrontend(t, "../advent.z3", frontend)
+	z.SaveFormat = format
+
+	stepUntilLinesRead(t, z, frontend, 5)
+	if frontend.statusBar.PlaceName != "At End Of Road" {
+		t.Fatalf("expected to have left the building before restoring, in %q", frontend.statusBar.PlaceName)
+	}
+
+	stepUntilLinesRead(t, z, frontend, 7)
+	if frontend.statusBar.PlaceName != "Inside Building" {
+		t.Errorf("expected restore to return to the building, in %q", frontend.statusBar.PlaceName)
+	}
+	return frontend.saveData
+}
+
+func TestQuetzalSaveRestore(t *testing.T) {
+	data := playSaveRestore(t, zmachine.SaveFormatQuetzal)
+
+	if string(data[0:4]) != "FORM" || string(data[8:12]) != "IFZS" {
+		t.Fatalf("save isn't an IFZS form: %q", data[0:12])
+	}
+	if int(binary.BigEndian.Uint32(data[4:8])) != len(data)-8 {
+		t.Errorf("FORM length %d doesn't match file length %d", binary.BigEndian.Uint32(data[4:8]), len(data))
+	}
+
+	var chunks []string
+	for offset := 12; offset < len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+		chunks = append(chunks, string(data[offset:offset+4]))
+		offset += 8 + length + length%2
+	}
+	if len(chunks) != 3 || chunks[0] != "IFhd" || chunks[1] != "CMem" || chunks[2] != "Stks" {
+		t.Errorf("unexpected chunks %v", chunks)
+	}
+
+	// IFhd holds the release number and serial code straight from the header
+	if string(data[22:28]) != "151001" {
+		t.Errorf("unexpected serial code in IFhd %q", data[22:28])
+	}
+}
+
+func TestGOZMSaveRestore(t *testing.T) {
+	data := playSaveRestore(t, zmachine.SaveFormatGOZM)
+
+	if string(data[0:4]) != "GOZM" {
+		t.Fatalf("save isn't in GOZM format: %q", data[0:4])
+	}
+}
+
+func TestQuetzalRestoreRejectsOtherStory(t *testing.T) {
+	frontend := &scriptedFrontend{}
+	z := loadWithFrontend(t, "../advent.z3", frontend)
+	save := z.ExportSaveState()
+
+	other := loadWithFrontend(t, "../hhgg.z3", frontend)
+	if other.ImportSaveState(save) {
+		t.Error("restored a save made by a different story")
+	}
+	if !z.ImportSaveState(save) {
+		t.Error("failed to restore a save made by the same story")
+	}
+}
+
+func TestThrowAfterQuetzalRestore(t *testing.T) {
+	story := buildStory(5, nil, []byte{
+		0xe0, 0x3f, 0x00, 0x42, 0x10, // call_vs 0x108 -> G00
+		0xba,       // quit
+		0x00, 0x00, // padding
+		0x00,       // 0x108: routine with no locals
+		0xb9, 0x13, // catch -> G03
+		0xbe, 0x00, 0xff, 0x11, // save -> G01
+		0x41, 0x11, 0x02, 0xc6, // je G01 2 ?0x117
+		0xbe, 0x01, 0xff, 0x12, // restore -> G02
+		0xf9, 0x2f, 0x00, 0x48, 0x13, // 0x117: call_vn 0x120 G03
+		0xb1,             // rfalse
+		0x00, 0x00, 0x00, // padding
+		0x00,             // 0x120: routine with no locals
+		0x3c, 0x07, 0x13, // throw 7 G03
+	})
+
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend)
+
+	if caught, thrown := z.Core.ReadHalfWord(0x66), z.Core.ReadHalfWord(0x60); caught != 2 || thrown != 7 {
+		t.Errorf("expected catch to give the frame count 2 and the throw to return 7 from it, got %d and %d", caught, thrown)
+	}
+}
@@ -0,0 +1,15 @@
+package zmachine
+
+import "github.com/davetcode/goz/blorb"
+
+// SetResources gives the machine the Blorb the story was loaded from so its
+// pictures, sounds and metadata are available to the story and frontend
+func (z *ZMachine) SetResources(resources *blorb.Blorb) {
+	z.resources = resources
+	z.claimPictures()
+}
+
+// Resources returns the story's Blorb, nil if it was a bare story file
+func (z *ZMachine) Resources() *blorb.Blorb {
+	return z.resources
+}
@@ -1,10 +1,13 @@
 package zmachine
 
+import "github.com/davetcode/goz/dictionary"
+
 type Save struct {
 	Prompt   bool
 	Filename string
 	Address  uint32 // 0 means full save
 	NumBytes uint32 // 0 means full save
+	Data     []byte // Bytes to write, the full save state or the auxiliary table
 }
 
 type Restore struct {
@@ -28,7 +31,7 @@ func (SaveResponse) isSaveRestoreResponse() {}
 type RestoreResponse struct {
 	Success bool
 	Result  uint16 // 0 = failure, 2 = success; for auxiliary: bytes loaded
-	Data    []byte // Save file bytes for full restore
+	Data    []byte // Save file bytes, for auxiliary restores the interpreter loads at most NumBytes of them
 }
 
 func (RestoreResponse) isSaveRestoreResponse() {}
@@ -59,8 +62,12 @@ func (z *ZMachine) applyState(state SaveState) bool {
 		return false
 	}
 
-	// TODO: retain transcription and fixed font bits per spec
+	// The transcript and fixed pitch bits belong to the player's session
+	// rather than the saved game so are kept (spec 6.1.2.2)
+	preservedFlags := z.Core.ReadZByte(0x11) & (flags2Transcript | flags2FixedPitch)
 	copy(z.Core.ReadSlice(0, uint32(z.Core.StaticMemoryBase)), state.dynamicMemory)
+	z.userDictionaries = dictionary.Cache{}
+	z.Core.WriteZByte(0x11, z.Core.ReadZByte(0x11)&^(flags2Transcript|flags2FixedPitch)|preservedFlags)
 	z.callStack = state.callStack.copy()
 	return true
 }
@@ -101,12 +108,23 @@ func (z *ZMachine) readSaveFilename(address uint32) string {
 	return string(bytes)
 }
 
+// ExportSaveState serializes the current state in the machine's SaveFormat
 func (z *ZMachine) ExportSaveState() []byte {
-	return z.captureState().serialize()
+	if z.SaveFormat == SaveFormatGOZM {
+		return z.captureState().serialize()
+	}
+	return z.serializeQuetzal(z.captureState())
 }
 
+// ImportSaveState restores a save in either Quetzal or GOZM format
 func (z *ZMachine) ImportSaveState(data []byte) bool {
-	state, ok := deserializeSaveState(data)
+	var state SaveState
+	var ok bool
+	if isQuetzal(data) {
+		state, ok = z.deserializeQuetzal(data)
+	} else {
+		state, ok = deserializeSaveState(data)
+	}
 	if !ok {
 		return false
 	}
@@ -178,7 +196,7 @@ func (cs *CallStack) serialize() []byte {
 	return result
 }
 
-// Frame format: pc(4) + framePointer(4) + routineType(1) + numValuesPassed(2) +
+// Frame format: pc(4) + unused(4) + routineType(1) + numValuesPassed(2) +
 // localsCount(2) + locals + stackSize(2) + stack
 func (f *CallStackFrame) serialize() []byte {
 	size := 4 + 4 + 1 + 2 + 2 + len(f.locals)*2 + 2 + len(f.routineStack)*2
@@ -191,10 +209,7 @@ func (f *CallStackFrame) serialize() []byte {
 	data[offset+3] = byte(f.pc)
 	offset += 4
 
-	data[offset] = byte(f.framePointer >> 24)
-	data[offset+1] = byte(f.framePointer >> 16)
-	data[offset+2] = byte(f.framePointer >> 8)
-	data[offset+3] = byte(f.framePointer)
+	// Once a CATCH frame pointer, CATCH values are now frame counts
 	offset += 4
 
 	data[offset] = byte(f.routineType)
@@ -240,9 +255,7 @@ func deserializeCallStack(data []byte, frameCount int) ([]CallStackFrame, int) {
 			uint32(data[offset+2])<<8 | uint32(data[offset+3])
 		offset += 4
 
-		frame.framePointer = uint32(data[offset])<<24 | uint32(data[offset+1])<<16 |
-			uint32(data[offset+2])<<8 | uint32(data[offset+3])
-		offset += 4
+		offset += 4 // Unused
 
 		frame.routineType = RoutineType(data[offset])
 		offset++
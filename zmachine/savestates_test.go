@@ -0,0 +1,54 @@
+package zmachine_test
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+// buildStory lays out a minimal story file of the given version with an
+// empty dictionary at 0x40, globals at 0x60, dynamic memory up to 0x100 and
+// the code starting at 0x100. The extra bytes are copied in at 0x80.
+func buildStory(version uint8, extra []byte, code []byte) []byte {
+	story := make([]byte, 0x100, 0x100+len(code))
+	story[0x00] = version
+	binary.BigEndian.PutUint16(story[0x04:], 0x100) // High memory
+	binary.BigEndian.PutUint16(story[0x06:], 0x100) // Initial PC
+	binary.BigEndian.PutUint16(story[0x08:], 0x40)  // Dictionary
+	binary.BigEndian.PutUint16(story[0x0c:], 0x60)  // Globals
+	binary.BigEndian.PutUint16(story[0x0e:], 0x100) // Static memory
+	story[0x41] = 9                                 // Dictionary entry length, no separators or entries
+	copy(story[0x80:], extra)
+	return append(story, code...)
+}
+
+func TestAuxiliarySaveRestore(t *testing.T) {
+	story := buildStory(5,
+		[]byte{
+			1, 2, 3, 4, // Table at 0x80
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			4, 'T', 'E', 'S', 'T', // Filename at 0x90
+		},
+		[]byte{
+			0xbe, 0x00, 0x55, 0x80, 0x04, 0x90, 0x00, 0x10, // save 0x80 4 0x90 0 -> G00
+			0xe2, 0x57, 0x80, 0x00, 0x09, // storeb 0x80 0 9
+			0xbe, 0x01, 0x55, 0x80, 0x02, 0x90, 0x00, 0x11, // restore 0x80 2 0x90 0 -> G01
+			0xba, // quit
+		})
+
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend)
+
+	if !slices.Equal(frontend.saveData, []byte{1, 2, 3, 4}) {
+		t.Errorf("expected the table to be saved verbatim, got %v", frontend.saveData)
+	}
+	if result := z.Core.ReadHalfWord(0x60); result != 1 {
+		t.Errorf("expected save to store 1, got %d", result)
+	}
+	if result := z.Core.ReadHalfWord(0x62); result != 2 {
+		t.Errorf("expected restore to store the 2 bytes loaded, got %d", result)
+	}
+	if table := z.Core.ReadSlice(0x80, 0x84); !slices.Equal(table, []byte{1, 2, 3, 4}) {
+		t.Errorf("expected the first byte to be restored, table is %v", table)
+	}
+}
@@ -1,6 +1,9 @@
 package zmachine
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type TextStyle int
 
@@ -32,10 +35,33 @@ const (
 	FontFixedPitch Font = 4
 )
 
-// ScreenModel - This is very deliberately a _not_ V6 screen model
+// characterGraphics are the closest Unicode glyphs to font 3's characters 32
+// to 126, the runes (97-122) are shown as the letters they stand for
+var characterGraphics = []rune(
+	" ←→╱╲ ──││┴┬├┤└┌┐┘╰╭╮╯█▀▄▌▐▄▀▌▐▝▗▖▘▝▗▖▘▝▗▖▘▔▁▏▕" +
+		" ▏▎▍▌▋▊▉████╳↑↓↕□?" +
+		"abcdefghijklmnopqrstuvwxyz" +
+		"{|}~")
+
+// Glyphs converts text printed in the font to what should be shown for it,
+// only font 3 (character graphics) differs from the text itself
+func (f Font) Glyphs(s string) string {
+	if f != FontCharGraphs {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 32 && int(r-32) < len(characterGraphics) {
+			return characterGraphics[r-32]
+		}
+		return r
+	}, s)
+}
+
+// ScreenModel - The upper and lower windows of V1-5, V6 stories use Windows
+// and the upper and lower window fields follow windows 1 and 0
 type ScreenModel struct {
 	LowerWindowActive bool
-	CurrentFont       Font // TODO - Not actually changing the rendering code based on this at the moment
+	CurrentFont       Font // Frontends show text with CurrentFont.Glyphs
 
 	UpperWindowHeight            int
 	UpperWindowForeground        Color
@@ -51,6 +77,13 @@ type ScreenModel struct {
 	LowerWindowForeground        Color
 	LowerWindowBackground        Color
 	LowerWindowTextStyle         TextStyle
+
+	// V6 only, text goes to Windows[CurrentWindow]
+	Windows        [8]Window
+	CurrentWindow  int
+	CursorHidden   bool
+	MouseWindow    int  // The window the mouse is kept inside, -1 for anywhere
+	ScreenBuffered bool // Screen updates can wait until input is read, see buffer_screen
 }
 
 func (m *ScreenModel) NewZMachineColor(i uint16, isForeground bool) Color {
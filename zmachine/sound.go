@@ -0,0 +1,79 @@
+package zmachine
+
+// The effects sound_effect asks for
+const (
+	SoundPrepare    uint16 = 1
+	SoundStart      uint16 = 2
+	SoundStop       uint16 = 3
+	SoundFinishWith uint16 = 4
+)
+
+// SoundSystem plays the sampled sounds, numbers 3 and up, in place of the
+// frontend, which is still sent the bleeps. See the sound package.
+type SoundSystem interface {
+	// SoundEffect carries out a sound_effect. finished is called, from any
+	// goroutine, when a sound which was started has played all its repeats.
+	SoundEffect(request SoundEffectRequest, finished func()) error
+
+	// Stop silences everything, without any routines being called
+	Stop()
+}
+
+// SetSoundSystem plays sampled sounds through s, nil leaves them all to the
+// frontend
+func (z *ZMachine) SetSoundSystem(s SoundSystem) {
+	z.soundSystem = s
+	z.soundFinished = make(chan uint16, 8)
+}
+
+func (z *ZMachine) soundEffect(request SoundEffectRequest) {
+	if z.soundSystem == nil || request.SoundNumber < 3 {
+		z.frontend.Sound(z.ctx, request)
+		return
+	}
+
+	routine := request.Routine
+	finished := z.soundFinished
+	err := z.soundSystem.SoundEffect(request, func() {
+		if routine == 0 {
+			return
+		}
+		select {
+		case finished <- routine:
+		default:
+			// Only one sound plays at a time so this never fills up
+		}
+	})
+	if err != nil {
+		z.warnOnce("sound_effect", "Warning: @sound_effect %d failed: %v (PC = %x)", request.SoundNumber, err, z.currentInstructionPC)
+	}
+}
+
+// runSoundRoutines calls the routine of each sound which has finished since
+// the last instruction, the same way as an interrupt. Sounds which finish
+// while waiting for the player to type have their routines called once the
+// read is over. False means the machine stopped in a routine.
+func (z *ZMachine) runSoundRoutines() bool {
+	for {
+		select {
+		case routine := <-z.soundFinished:
+			if _, ok := z.callInterrupt(routine); !ok {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+// stopSounds silences everything and forgets about routines waiting to be
+// called, as when the story restarts or stops
+func (z *ZMachine) stopSounds() {
+	if z.soundSystem == nil {
+		return
+	}
+	z.soundSystem.Stop()
+	for len(z.soundFinished) > 0 {
+		<-z.soundFinished
+	}
+}
@@ -0,0 +1,62 @@
+package zmachine_test
+
+import (
+	"testing"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+type finishingSoundSystem struct {
+	requests []zmachine.SoundEffectRequest
+}
+
+func (s *finishingSoundSystem) SoundEffect(request zmachine.SoundEffectRequest, finished func()) error {
+	s.requests = append(s.requests, request)
+	if request.Effect == zmachine.SoundStart {
+		go finished()
+	}
+	return nil
+}
+
+func (s *finishingSoundSystem) Stop() {}
+
+func TestSoundCallsRoutineWhenFinished(t *testing.T) {
+	story := timerStory(nil, []byte{
+		0xf5, 0x50, 0x03, 0x02, 0x01, 0x08, 0x00, 0x48, // sound_effect 3 2 0x0108 timer
+		0xa0, 0x11, 0xbf, 0xfe, // jz G01 ?(back to itself)
+		0xba, // quit
+	})
+
+	sounds := &finishingSoundSystem{}
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend, func(z *zmachine.ZMachine) { z.SetSoundSystem(sounds) })
+
+	if calls := z.Core.ReadHalfWord(0x62); calls != 1 {
+		t.Errorf("expected the routine to be called once, got %d", calls)
+	}
+	want := zmachine.SoundEffectRequest{SoundNumber: 3, Effect: zmachine.SoundStart, Volume: 8, Repeats: 1, Routine: 0x48}
+	if len(sounds.requests) != 1 || sounds.requests[0] != want {
+		t.Errorf("expected %+v, got %+v", want, sounds.requests)
+	}
+}
+
+func TestSoundEffectReadsVolumeAndRepeatsOnce(t *testing.T) {
+	story := buildStory(5, nil, []byte{
+		0xe8, 0x7f, 0x01, // push 1
+		0xe8, 0x3f, 0x02, 0x08, // push 0x0208
+		0xf5, 0x5b, 0x03, 0x03, 0x00, // sound_effect 3 3 sp
+		0x54, 0x00, 0x00, 0x10, // add sp 0 -> G00
+		0xba, // quit
+	})
+
+	sounds := &finishingSoundSystem{}
+	z := runStory(t, story, &scriptedFrontend{}, func(z *zmachine.ZMachine) { z.SetSoundSystem(sounds) })
+
+	want := zmachine.SoundEffectRequest{SoundNumber: 3, Effect: zmachine.SoundStop, Volume: 8, Repeats: 2}
+	if len(sounds.requests) != 1 || sounds.requests[0] != want {
+		t.Errorf("expected %+v, got %+v", want, sounds.requests)
+	}
+	if bottom := z.Core.ReadHalfWord(0x60); bottom != 1 {
+		t.Errorf("expected only the first push left on the stack, got %d", bottom)
+	}
+}
@@ -0,0 +1,75 @@
+package zmachine
+
+// Symbols gives names to addresses and variables, usually from the debug
+// information the compiler wrote, see the debuginfo package. Each method
+// returns "" when it doesn't know.
+type Symbols interface {
+	// Describe names the routine containing pc and the source line, e.g.
+	// "LookSub (verbs.h:812)"
+	Describe(pc uint32) string
+	// RoutineName names the routine starting at address
+	RoutineName(address uint32) string
+	// GlobalName names global n, numbered from 0
+	GlobalName(n uint8) string
+	// LocalName names local n, numbered from 0, of the routine containing pc
+	LocalName(pc uint32, n uint8) string
+}
+
+// SetSymbols names routines and variables in traces, crash reports and
+// warnings, nil goes back to addresses and numbers
+func (z *ZMachine) SetSymbols(symbols Symbols) {
+	z.symbols = symbols
+}
+
+func (z *ZMachine) Symbols() Symbols {
+	return z.symbols
+}
+
+// symbolicVariableName is variableName using the names of locals and globals
+// where they're known, pc says which routine's locals
+func symbolicVariableName(symbols Symbols, pc uint32, variable uint8) string {
+	if symbols != nil {
+		var name string
+		switch {
+		case variable == 0:
+		case variable < 16:
+			name = symbols.LocalName(pc, variable-1)
+		default:
+			name = symbols.GlobalName(variable - 16)
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return variableName(variable)
+}
+
+// nameOperands names the variables in operands and a constant routine
+// called by the instruction at pc
+func nameOperands(symbols Symbols, pc uint32, operands []TraceOperand, routine uint32) {
+	if symbols == nil {
+		return
+	}
+	for ix := range operands {
+		switch {
+		case operands[ix].Variable:
+			if name := symbolicVariableName(symbols, pc, uint8(operands[ix].Value)); name != variableName(uint8(operands[ix].Value)) {
+				operands[ix].Name = name
+			}
+		case ix == 0 && routine != 0:
+			operands[ix].Name = symbols.RoutineName(routine)
+		}
+	}
+}
+
+// describe names where pc is for warnings and errors, e.g. " in LookSub
+// (verbs.h:812)", empty without symbols
+func (z *ZMachine) describe(pc uint32) string {
+	if z.symbols == nil {
+		return ""
+	}
+	if description := z.symbols.Describe(pc); description != "" {
+		return " in " + description
+	}
+	return ""
+}
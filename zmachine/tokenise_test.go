@@ -0,0 +1,45 @@
+package zmachine_test
+
+import (
+	"testing"
+
+	"github.com/davetcode/goz/zcore"
+	"github.com/davetcode/goz/zstring"
+)
+
+func encodeV5(word string) []byte {
+	core := zcore.LoadCore(append([]byte{5}, make([]byte, 0xff)...))
+	return zstring.Encode([]rune(word), &core, zstring.LoadAlphabets(&core))
+}
+
+func TestTokeniseFlagWithUnsortedDictionary(t *testing.T) {
+	extra := make([]byte, 0x40)
+	copy(extra[0x00:], append([]byte{20, 7}, "foo bar"...)) // Text buffer at 0x80
+	extra[0x10] = 4                                         // Parse buffer at 0x90
+	for i := 0x12; i < 0x22; i++ {
+		extra[i] = 0xee
+	}
+	// Unsorted user dictionary at 0xa8, entries at 0xad and 0xb3
+	dict := []byte{1, ',', 6, 0xff, 0xfe}
+	dict = append(dict, encodeV5("zzz")...)
+	dict = append(dict, encodeV5("bar")...)
+	copy(extra[0x28:], dict)
+
+	story := buildStory(5, extra, []byte{
+		0xfb, 0x55, 0x80, 0x90, 0xa8, 0x01, // tokenise 0x80 0x90 0xa8 1
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend)
+
+	if words := z.Core.ReadZByte(0x91); words != 2 {
+		t.Errorf("expected 2 words, got %d", words)
+	}
+	if slot := z.Core.ReadSlice(0x92, 0x96); string(slot) != "\xee\xee\xee\xee" {
+		t.Errorf("expected the unrecognised word's slot to be left alone, got %x", slot)
+	}
+	if slot := z.Core.ReadSlice(0x96, 0x9a); string(slot) != "\x00\xb3\x03\x06" {
+		t.Errorf("expected bar to be found in the unsorted dictionary, got %x", slot)
+	}
+}
@@ -0,0 +1,189 @@
+package zmachine
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultHistorySize is how many instructions a machine remembers for the
+// report made when it crashes, see SetHistorySize
+const DefaultHistorySize = 20
+
+// Tracer receives every instruction the machine executes, after it has run.
+// Instructions run by an interrupt routine in the middle of a read arrive
+// before the read itself.
+type Tracer interface {
+	Trace(event TraceEvent)
+}
+
+// TraceOperand is an operand as it was written in the story, variables
+// aren't read because reading the stack pops it
+type TraceOperand struct {
+	Variable bool   // Value is a variable number rather than a constant
+	Large    bool   // A two byte constant
+	Value    uint16 // The constant or variable number
+	Name     string // The variable or called routine's name from the symbols, if known
+}
+
+func (o TraceOperand) String() string {
+	switch {
+	case o.Name != "":
+		return o.Name
+	case !o.Variable && o.Large:
+		return fmt.Sprintf("#%04x", o.Value)
+	case !o.Variable:
+		return fmt.Sprintf("#%02x", o.Value)
+	default:
+		return variableName(uint8(o.Value))
+	}
+}
+
+// variableName gives sp for the stack, Lnn for locals and Gnn for globals
+// with the local or global numbered from 0 in hex
+func variableName(variable uint8) string {
+	switch {
+	case variable == 0:
+		return "sp"
+	case variable < 16:
+		return fmt.Sprintf("L%02x", variable-1)
+	default:
+		return fmt.Sprintf("G%02x", variable-16)
+	}
+}
+
+// TraceEvent describes a single executed instruction
+type TraceEvent struct {
+	PC       uint32
+	Mnemonic string
+	Operands []TraceOperand
+
+	Stored        bool // The instruction stored a result, in StoreVariable
+	StoreVariable uint8
+	StoreValue    uint16
+	StoreName     string // StoreVariable's name from the symbols, if known
+
+	Branched    bool // The instruction had a branch, BranchTaken says if it was followed
+	BranchTaken bool
+
+	Location string // Routine and source line from the symbols, if known
+}
+
+func (e TraceEvent) String() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "%06x %s", e.PC, e.Mnemonic)
+	for _, operand := range e.Operands {
+		s.WriteString(" " + operand.String())
+	}
+	if e.Stored {
+		name := e.StoreName
+		if name == "" {
+			name = variableName(e.StoreVariable)
+		}
+		fmt.Fprintf(&s, " -> %s = %04x", name, e.StoreValue)
+	}
+	if e.Branched {
+		if e.BranchTaken {
+			s.WriteString(" ?taken")
+		} else {
+			s.WriteString(" ?not taken")
+		}
+	}
+	if e.Location != "" {
+		s.WriteString(" ; " + e.Location)
+	}
+	return s.String()
+}
+
+// WriterTracer writes each instruction on its own line
+type WriterTracer struct {
+	w io.Writer
+}
+
+func NewWriterTracer(w io.Writer) *WriterTracer {
+	return &WriterTracer{w: w}
+}
+
+func (t *WriterTracer) Trace(event TraceEvent) {
+	fmt.Fprintln(t.w, event.String()) // nolint:errcheck
+}
+
+// SetTracer sends every instruction executed from now on to tracer, nil turns
+// tracing off
+func (z *ZMachine) SetTracer(tracer Tracer) {
+	z.tracer = tracer
+}
+
+// SetHistorySize changes how many instructions are kept for crash reports,
+// forgetting those already kept. Zero turns the history off.
+func (z *ZMachine) SetHistorySize(size int) {
+	z.history = history{opcodes: make([]Opcode, max(size, 0))}
+}
+
+// history is a ring buffer of the most recently executed instructions
+type history struct {
+	opcodes []Opcode
+	next    int
+	full    bool
+}
+
+func (h *history) record(opcode Opcode) {
+	if len(h.opcodes) == 0 {
+		return
+	}
+	h.opcodes[h.next] = opcode
+	h.next = (h.next + 1) % len(h.opcodes)
+	h.full = h.full || h.next == 0
+}
+
+// recent returns the kept instructions, oldest first
+func (h *history) recent() []Opcode {
+	if !h.full {
+		return h.opcodes[:h.next]
+	}
+	return append(h.opcodes[h.next:len(h.opcodes):len(h.opcodes)], h.opcodes[:h.next]...)
+}
+
+func (z *ZMachine) traceEvent(opcode *Opcode) TraceEvent {
+	event := TraceEvent{
+		PC:       opcode.pc,
+		Mnemonic: opcode.mnemonic(z.Core.Version),
+		Operands: opcode.traceOperands(),
+	}
+	if z.symbols != nil {
+		var routine uint32
+		if strings.HasPrefix(event.Mnemonic, "call") && len(event.Operands) > 0 && !event.Operands[0].Variable {
+			routine = UnpackAddress(&z.Core, uint32(event.Operands[0].Value), false)
+		}
+		nameOperands(z.symbols, opcode.pc, event.Operands, routine)
+		event.Location = z.symbols.Describe(opcode.pc)
+	}
+	return event
+}
+
+func (opcode *Opcode) traceOperands() []TraceOperand {
+	operands := make([]TraceOperand, opcode.numOperands)
+	for i, operand := range opcode.operands[:opcode.numOperands] {
+		operands[i] = TraceOperand{
+			Variable: operand.operandType == variable,
+			Large:    operand.operandType == largeConstant,
+			Value:    operand.value,
+		}
+	}
+	return operands
+}
+
+// storeResult writes an instruction's result to the variable named by the
+// store byte at the PC
+func (z *ZMachine) storeResult(frame *CallStackFrame, value uint16) {
+	destination := z.readIncPC(frame)
+	z.writeVariable(destination, value, false) // nolint:errcheck
+	if z.tracing != nil {
+		z.tracing.Stored = true
+		z.tracing.StoreVariable = destination
+		z.tracing.StoreValue = value
+		if name := symbolicVariableName(z.symbols, z.tracing.PC, destination); name != variableName(destination) {
+			z.tracing.StoreName = name
+		}
+	}
+}
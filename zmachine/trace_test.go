@@ -0,0 +1,85 @@
+package zmachine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+type recordingTracer []zmachine.TraceEvent
+
+func (t *recordingTracer) Trace(event zmachine.TraceEvent) {
+	*t = append(*t, event)
+}
+
+func TestTracerSeesStoresAndBranches(t *testing.T) {
+	story := buildStory(5, nil, []byte{
+		0x14, 0x01, 0x02, 0x10, // add 1 2 -> G00
+		0x41, 0x10, 0x03, 0xc3, // je G00 3 ?skip
+		0xbb, // new_line
+		0xba, // quit
+	})
+
+	var tracer recordingTracer
+	runStory(t, story, &scriptedFrontend{}, func(z *zmachine.ZMachine) { z.SetTracer(&tracer) })
+
+	var lines []string
+	for _, event := range tracer {
+		lines = append(lines, event.String())
+	}
+	expected := []string{
+		"000100 add #01 #02 -> G00 = 0003",
+		"000104 je G00 #03 ?taken",
+		"000109 quit",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected trace\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+}
+
+// testSymbols names everything from 0x100 as the routine Main
+type testSymbols struct{}
+
+func (testSymbols) Describe(pc uint32) string {
+	if pc < 0x100 {
+		return ""
+	}
+	return "Main (test.inf:3)"
+}
+
+func (testSymbols) RoutineName(address uint32) string { return "" }
+
+func (testSymbols) GlobalName(n uint8) string {
+	if n == 0 {
+		return "score"
+	}
+	return ""
+}
+
+func (testSymbols) LocalName(pc uint32, n uint8) string { return "" }
+
+func TestTracerUsesSymbols(t *testing.T) {
+	story := buildStory(5, nil, []byte{
+		0x14, 0x01, 0x02, 0x10, // add 1 2 -> G00
+		0x41, 0x10, 0x03, 0xc3, // je G00 3 ?skip
+		0xbb, // new_line
+		0xba, // quit
+	})
+
+	var tracer recordingTracer
+	runStory(t, story, &scriptedFrontend{}, func(z *zmachine.ZMachine) {
+		z.SetTracer(&tracer)
+		z.SetSymbols(testSymbols{})
+	})
+
+	if len(tracer) < 2 {
+		t.Fatalf("expected at least two instructions traced, got %v", tracer)
+	}
+	if line := tracer[0].String(); line != "000100 add #01 #02 -> score = 0003 ; Main (test.inf:3)" {
+		t.Errorf("expected the store to score to be named, got %q", line)
+	}
+	if line := tracer[1].String(); line != "000104 je score #03 ?taken ; Main (test.inf:3)" {
+		t.Errorf("expected the read of score to be named, got %q", line)
+	}
+}
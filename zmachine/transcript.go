@@ -0,0 +1,72 @@
+package zmachine
+
+import "io"
+
+// OpenTranscript is sent when the story first turns on output stream 2, the
+// frontend replies with a TranscriptResponse
+type OpenTranscript struct{}
+
+// TranscriptResponse carries where the transcript should be written, a nil
+// Transcript means the frontend couldn't or wouldn't provide one
+type TranscriptResponse struct {
+	Transcript io.WriteCloser
+}
+
+func (TranscriptResponse) isSaveRestoreResponse() {}
+
+// Bits of the low byte of Flags 2 (0x11) which must survive restore and restart
+const (
+	flags2Transcript = 0b0000_0001
+	flags2FixedPitch = 0b0000_0010
+)
+
+// setTranscript turns output stream 2 on or off and mirrors it in the header.
+// The frontend is only asked for a transcript the first time, turning it off
+// and back on carries on writing to the same one.
+func (z *ZMachine) setTranscript(on bool) {
+	if on && z.transcript == nil {
+		transcript, err := z.frontend.OpenTranscript(z.ctx)
+		if err != nil {
+			// The context is done so the machine is about to stop anyway
+			on = false
+		} else if transcript == nil {
+			z.warnOnce("transcript_unavailable", "Warning: No transcript available, output stream 2 left off (PC = %x)", z.currentInstructionPC)
+			on = false
+		}
+		z.transcript = transcript
+	}
+
+	z.streams.Transcript = on
+
+	flags := z.Core.ReadZByte(0x11)
+	if on {
+		flags |= flags2Transcript
+	} else {
+		flags &^= flags2Transcript
+	}
+	z.Core.WriteZByte(0x11, flags)
+}
+
+// syncTranscript picks up the story setting or clearing the transcript bit
+// in the header directly rather than using OUTPUT_STREAM
+func (z *ZMachine) syncTranscript() {
+	on := z.Core.ReadZByte(0x11)&flags2Transcript != 0
+	if on != z.streams.Transcript {
+		z.setTranscript(on)
+	}
+}
+
+func (z *ZMachine) writeTranscript(s string) {
+	z.syncTranscript()
+	if z.streams.Transcript {
+		io.WriteString(z.transcript, s) // nolint:errcheck
+	}
+}
+
+func (z *ZMachine) closeTranscript() {
+	if z.transcript != nil {
+		z.transcript.Close() // nolint:errcheck
+		z.transcript = nil
+		z.streams.Transcript = false
+	}
+}
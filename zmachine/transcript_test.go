@@ -0,0 +1,52 @@
+package zmachine_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTranscriptSurvivesRestart(t *testing.T) {
+	frontend := &scriptedFrontend{commands: []string{"no", "script", "look", "restart", "y", "no", "unscript", "look"}}
+	z := loadWithFrontend(t, "../advent.z3", frontend)
+
+	stepUntilLinesRead(t, z, frontend, 3)
+	if z.Core.ReadZByte(0x11)&1 != 1 {
+		t.Error("expected SCRIPT to set the transcript bit in Flags 2")
+	}
+
+	stepUntilLinesRead(t, z, frontend, 9)
+	if z.Core.ReadZByte(0x11)&1 != 0 {
+		t.Error("expected UNSCRIPT to clear the transcript bit in Flags 2")
+	}
+
+	transcript := frontend.transcript.String()
+	if !strings.Contains(transcript, "> look\nAt End Of Road") {
+		t.Errorf("expected the command to be echoed into the transcript, got %q", transcript)
+	}
+	if !strings.Contains(transcript, "Do you need instructions? (y/n) >no\n") {
+		t.Errorf("expected the transcript to carry on after restarting, got %q", transcript)
+	}
+	if strings.Count(transcript, "At End Of Road") != 2 {
+		t.Errorf("expected the room to be transcribed once before and once after restarting, got %q", transcript)
+	}
+}
+
+func TestTranscriptFollowsHeaderBit(t *testing.T) {
+	story := buildStory(5, nil, []byte{
+		0xe2, 0x57, 0x11, 0x00, 0x01, // storeb 0x11 0 1
+		0xb2, 0xb5, 0xc5, // print "hi"
+		0xe2, 0x57, 0x11, 0x00, 0x00, // storeb 0x11 0 0
+		0xb2, 0x9f, 0xca, // print "bye"
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{}
+	runStory(t, story, frontend)
+
+	if frontend.output.String() != "hibye" {
+		t.Errorf("unexpected screen output %q", frontend.output.String())
+	}
+	if frontend.transcript.String() != "hi" {
+		t.Errorf("expected only the text printed while the bit was set to be transcribed, got %q", frontend.transcript.String())
+	}
+}
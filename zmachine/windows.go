@@ -0,0 +1,463 @@
+package zmachine
+
+import "strings"
+
+// WindowAttributes are the flags window_style sets on a V6 window
+type WindowAttributes uint16
+
+const (
+	WindowWrapping   WindowAttributes = 0b0001
+	WindowScrolling  WindowAttributes = 0b0010
+	WindowTranscript WindowAttributes = 0b0100
+	WindowBuffered   WindowAttributes = 0b1000
+)
+
+// Window is one of the eight windows of the V6 screen model (8.8). Positions
+// and sizes are in screen units and 1 based, as the story sees them, with the
+// cursor relative to the window's top left.
+type Window struct {
+	Y                  int
+	X                  int
+	Height             int
+	Width              int
+	CursorY            int
+	CursorX            int
+	LeftMargin         int
+	RightMargin        int
+	InterruptRoutine   uint16 // Packed routine called when InterruptCountdown runs out
+	InterruptCountdown int    // Newlines until InterruptRoutine is called, zero for never
+	TextStyle          TextStyle
+	Foreground         Color
+	Background         Color
+	Font               Font
+	FontHeight         int
+	FontWidth          int
+	Attributes         WindowAttributes
+	LineCount          int // Lines printed since the player last typed, for [MORE] prompts
+}
+
+// initialiseWindows lays out the V6 windows as at the start of a story, the
+// lower window (0) covering the screen and the rest empty in the top left
+func (m *ScreenModel) initialiseWindows(width, height, fontWidth, fontHeight int) {
+	for ix := range m.Windows {
+		m.Windows[ix] = Window{
+			Y:          1,
+			X:          1,
+			CursorY:    1,
+			CursorX:    1,
+			TextStyle:  Roman,
+			Foreground: m.DefaultLowerWindowForeground,
+			Background: m.DefaultLowerWindowBackground,
+			Font:       FontNormal,
+			FontHeight: max(fontHeight, 1),
+			FontWidth:  max(fontWidth, 1),
+			Attributes: WindowBuffered,
+		}
+	}
+	m.Windows[0].Width = width
+	m.Windows[0].Height = height
+	m.Windows[0].Attributes = WindowWrapping | WindowScrolling | WindowTranscript | WindowBuffered
+	m.Windows[1].Width = width
+	m.CurrentWindow = 0
+	m.CursorHidden = false
+	m.MouseWindow = 1
+	m.ScreenBuffered = false
+}
+
+// followWindows copies windows 0 and 1 into the upper and lower window
+// fields, so frontends which know nothing of V6 show something sensible.
+// Text for windows 2 to 7 is treated as going to the lower window.
+func (m *ScreenModel) followWindows() {
+	lower, upper := &m.Windows[0], &m.Windows[1]
+
+	m.LowerWindowActive = m.CurrentWindow != 1
+	m.CurrentFont = m.Windows[m.CurrentWindow].Font
+
+	m.UpperWindowHeight = upper.Height / upper.FontHeight
+	m.UpperWindowCursorX = (upper.CursorX - 1) / upper.FontWidth
+	m.UpperWindowCursorY = (upper.CursorY - 1) / upper.FontHeight
+	m.UpperWindowForeground = upper.Foreground
+	m.UpperWindowBackground = upper.Background
+	m.UpperWindowTextStyle = upper.TextStyle
+
+	m.LowerWindowForeground = lower.Foreground
+	m.LowerWindowBackground = lower.Background
+	m.LowerWindowTextStyle = lower.TextStyle
+}
+
+// updateScreen sends the screen model to the frontend
+func (z *ZMachine) updateScreen() {
+	if z.Core.Version == 6 {
+		z.screenModel.followWindows()
+	}
+	z.frontend.UpdateScreen(z.ctx, z.screenModel)
+}
+
+// window resolves a window operand, where -3 means the current window
+func (z *ZMachine) window(operand uint16) (*Window, int, bool) {
+	n := int(int16(operand))
+	if n == -3 {
+		n = z.screenModel.CurrentWindow
+	}
+	if n < 0 || n >= len(z.screenModel.Windows) {
+		z.warnOnce("window_number", "Warning: window %d doesn't exist (PC = %x)", n, z.currentInstructionPC)
+		return nil, 0, false
+	}
+	return &z.screenModel.Windows[n], n, true
+}
+
+// optionalWindow is the window given by the operand at index, or the current
+// window if the story left it out
+func (z *ZMachine) optionalWindow(opcode *Opcode, index int) (*Window, int, bool) {
+	if opcode.numOperands > index {
+		return z.window(opcode.operands[index].Value(z))
+	}
+	n := z.screenModel.CurrentWindow
+	return &z.screenModel.Windows[n], n, true
+}
+
+// splitWindowV6 gives the top lines units of the screen to window 1 and the
+// rest to window 0, keeping window 0's cursor on the same screen line
+func (z *ZMachine) splitWindowV6(lines int) {
+	lower, upper := &z.screenModel.Windows[0], &z.screenModel.Windows[1]
+	screenHeight := int(z.Core.ScreenHeightUnits)
+	lines = min(lines, screenHeight)
+
+	upper.Y = 1
+	upper.X = 1
+	upper.Height = lines
+	upper.Width = int(z.Core.ScreenWidthUnits)
+	if upper.CursorY > lines {
+		upper.CursorY = 1
+		upper.CursorX = 1 + upper.LeftMargin
+	}
+
+	cursorLine := lower.Y + lower.CursorY - 1
+	lower.Y = lines + 1
+	lower.Height = screenHeight - lines
+	lower.CursorY = cursorLine - lower.Y + 1
+	if lower.CursorY < 1 {
+		lower.CursorY = 1
+		lower.CursorX = 1 + lower.LeftMargin
+	}
+}
+
+// setCursorV6 moves the cursor of a window, a line of -1 hides the cursor and
+// -2 shows it again
+func (z *ZMachine) setCursorV6(line, column int16, w *Window) {
+	switch line {
+	case -1:
+		z.screenModel.CursorHidden = true
+	case -2:
+		z.screenModel.CursorHidden = false
+	default:
+		w.CursorY = int(line)
+		w.CursorX = max(int(column), 1+w.LeftMargin)
+	}
+}
+
+// eraseWindowV6 clears a window to its background and puts the cursor in its
+// top left. -1 clears the screen and joins it back into window 0, -2 clears
+// it leaving the windows alone.
+func (z *ZMachine) eraseWindowV6(operand uint16) EraseWindowRequest {
+	switch int16(operand) {
+	case -1:
+		z.splitWindowV6(0)
+		z.screenModel.CurrentWindow = 0
+		fallthrough
+	case -2:
+		for ix := range z.screenModel.Windows {
+			z.resetCursor(&z.screenModel.Windows[ix])
+		}
+		return EraseWindowRequest(int16(operand))
+	}
+
+	w, n, ok := z.window(operand)
+	if !ok {
+		return EraseWindowRequest(int16(operand))
+	}
+	z.resetCursor(w)
+	return EraseWindowRequest(n)
+}
+
+func (z *ZMachine) resetCursor(w *Window) {
+	w.CursorY = 1
+	w.CursorX = 1 + w.LeftMargin
+	w.LineCount = 0
+}
+
+// setWindowStyle changes a window's attributes, operation 0 sets them to
+// flags, 1 sets the bits in flags, 2 clears them and 3 flips them
+func (z *ZMachine) setWindowStyle(w *Window, flags WindowAttributes, operation uint16) {
+	switch operation {
+	case 0:
+		w.Attributes = flags
+	case 1:
+		w.Attributes |= flags
+	case 2:
+		w.Attributes &^= flags
+	case 3:
+		w.Attributes ^= flags
+	default:
+		z.warnOnce("window_style", "Warning: @window_style operation %d unknown (PC = %x)", operation, z.currentInstructionPC)
+	}
+}
+
+// colour turns a colour number from set_colour into a colour for window w,
+// 0 keeps the current colour and -1, the colour under the cursor, is taken
+// to be the same
+func (m *ScreenModel) colour(i uint16, isForeground bool, w *Window) Color {
+	switch int16(i) {
+	case 0, -1:
+		if isForeground {
+			return w.Foreground
+		}
+		return w.Background
+	case 1:
+		if isForeground {
+			return m.DefaultLowerWindowForeground
+		}
+		return m.DefaultLowerWindowBackground
+	default:
+		return m.NewZMachineColor(i, isForeground)
+	}
+}
+
+// advanceCursor moves the current window's cursor past text printed in it.
+// Each character is taken to be a font width wide, and windows which wrap do
+// so at the right margin a character at a time, which is close enough to the
+// word wrapping a frontend does to keep the cursor on the right line.
+func (z *ZMachine) advanceCursor(s string) {
+	w := &z.screenModel.Windows[z.screenModel.CurrentWindow]
+	for _, r := range s {
+		if r == '\n' {
+			z.newline(w)
+			continue
+		}
+		if w.Attributes&WindowWrapping != 0 && w.CursorX+w.FontWidth-1 > w.Width-w.RightMargin {
+			z.newline(w)
+		}
+		w.CursorX += w.FontWidth
+	}
+}
+
+// newline moves the cursor to the start of the next line, scrolling if the
+// window does, and counts down to the window's newline interrupt, which is
+// called before the next instruction
+func (z *ZMachine) newline(w *Window) {
+	w.CursorX = 1 + w.LeftMargin
+	w.CursorY += w.FontHeight
+	w.LineCount++
+	if w.Attributes&WindowScrolling != 0 && w.CursorY+w.FontHeight-1 > w.Height {
+		w.CursorY = max(w.Height-w.FontHeight+1, 1)
+	}
+	if w.InterruptCountdown > 0 {
+		w.InterruptCountdown--
+		if w.InterruptCountdown == 0 && w.InterruptRoutine != 0 {
+			z.newlineInterrupts = append(z.newlineInterrupts, w.InterruptRoutine)
+		}
+	}
+}
+
+// runNewlineInterrupts calls the routines of windows whose newline countdown
+// ran out during the last instruction. False means the machine stopped.
+func (z *ZMachine) runNewlineInterrupts() bool {
+	for len(z.newlineInterrupts) > 0 {
+		routine := z.newlineInterrupts[0]
+		z.newlineInterrupts = z.newlineInterrupts[1:]
+		if _, ok := z.callInterrupt(routine); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// trueColour turns a 15 bit colour from set_true_colour into a colour, -1 is
+// the default colour and -2 keeps the current one
+func trueColour(value uint16, current Color, defaultColour Color) Color {
+	switch int16(value) {
+	case -1:
+		return defaultColour
+	case -2:
+		return current
+	default:
+		return trueColourValue(value)
+	}
+}
+
+// trueColourValue is the colour set_true_colour gives as three 5-bit
+// components, each spread over 0-255
+func trueColourValue(value uint16) Color {
+	component := func(v uint16) int { return int(v<<3 | v>>2) }
+	return Color{component(value & 0b11111), component((value >> 5) & 0b11111), component((value >> 10) & 0b11111)}
+}
+
+// resetLineCounts starts counting lines again for each window as the player
+// is about to type
+func (z *ZMachine) resetLineCounts() {
+	for ix := range z.screenModel.Windows {
+		z.screenModel.Windows[ix].LineCount = 0
+	}
+}
+
+// windowProperty reads one of the properties get_wind_prop numbers (8.8.3.2),
+// false if there's no such property
+func (m *ScreenModel) windowProperty(w *Window, property uint16) (uint16, bool) {
+	switch property {
+	case 0:
+		return uint16(w.Y), true
+	case 1:
+		return uint16(w.X), true
+	case 2:
+		return uint16(w.Height), true
+	case 3:
+		return uint16(w.Width), true
+	case 4:
+		return uint16(w.CursorY), true
+	case 5:
+		return uint16(w.CursorX), true
+	case 6:
+		return uint16(w.LeftMargin), true
+	case 7:
+		return uint16(w.RightMargin), true
+	case 8:
+		return w.InterruptRoutine, true
+	case 9:
+		return uint16(w.InterruptCountdown), true
+	case 10:
+		return uint16(w.TextStyle), true
+	case 11:
+		return m.colourNumber(w.Background)<<8 | m.colourNumber(w.Foreground), true
+	case 12:
+		return uint16(w.Font), true
+	case 13:
+		return uint16(w.FontHeight)<<8 | uint16(w.FontWidth), true
+	case 14:
+		return uint16(w.Attributes), true
+	case 15:
+		return uint16(w.LineCount), true
+	case 16:
+		return trueColourNumber(w.Foreground), true
+	case 17:
+		return trueColourNumber(w.Background), true
+	default:
+		return 0, false
+	}
+}
+
+// setWindowProperty is put_wind_prop, the inverse of windowProperty
+func (m *ScreenModel) setWindowProperty(w *Window, property uint16, value uint16) bool {
+	switch property {
+	case 0:
+		w.Y = int(value)
+	case 1:
+		w.X = int(value)
+	case 2:
+		w.Height = int(value)
+	case 3:
+		w.Width = int(value)
+	case 4:
+		w.CursorY = int(value)
+	case 5:
+		w.CursorX = int(value)
+	case 6:
+		w.LeftMargin = int(value)
+	case 7:
+		w.RightMargin = int(value)
+	case 8:
+		w.InterruptRoutine = value
+	case 9:
+		w.InterruptCountdown = int(value)
+	case 10:
+		w.TextStyle = TextStyle(value)
+	case 11:
+		w.Foreground = m.colour(value&0xff, true, w)
+		w.Background = m.colour(value>>8, false, w)
+	case 12:
+		w.Font = Font(value)
+	case 13:
+		w.FontHeight = max(int(value>>8), 1)
+		w.FontWidth = max(int(value&0xff), 1)
+	case 14:
+		w.Attributes = WindowAttributes(value)
+	case 15:
+		w.LineCount = int(value)
+	case 16:
+		w.Foreground = trueColour(value, w.Foreground, m.DefaultLowerWindowForeground)
+	case 17:
+		w.Background = trueColour(value, w.Background, m.DefaultLowerWindowBackground)
+	default:
+		return false
+	}
+	return true
+}
+
+// colourNumber is the set_colour number of c, or 1 (the default) for colours
+// set_colour can't give
+func (m *ScreenModel) colourNumber(c Color) uint16 {
+	for i := uint16(2); i <= 12; i++ {
+		if m.NewZMachineColor(i, true) == c {
+			return i
+		}
+	}
+	return 1
+}
+
+// trueColourNumber is the inverse of trueColour for colours it made
+func trueColourNumber(c Color) uint16 {
+	component := func(v int) uint16 { return uint16(v >> 3) }
+	return component(c.r) | component(c.g)<<5 | component(c.b)<<10
+}
+
+// moveWindow and resizeWindow are move_window and window_size, a cursor left
+// outside the resized window goes back to its top left
+func (z *ZMachine) moveWindow(w *Window, y, x int) {
+	w.Y = y
+	w.X = x
+}
+
+func (z *ZMachine) resizeWindow(w *Window, height, width int) {
+	w.Height = height
+	w.Width = width
+	if w.CursorY > height || w.CursorX > width {
+		z.resetCursor(w)
+	}
+}
+
+// recordMouse keeps where the player clicked for read_mouse, and in V5+ in
+// words 1 and 2 of the header extension table
+func (z *ZMachine) recordMouse(response InputResponse) {
+	if response.TerminatingKey != 253 && response.TerminatingKey != 254 {
+		return
+	}
+	z.mouse = response
+	if base := uint32(z.Core.ExtensionTableBaseAddress); len(z.Core.ExtensionTable()) >= 2 {
+		z.Core.InterpreterWriteHalfWord(base+2, uint16(response.MouseX))
+		z.Core.InterpreterWriteHalfWord(base+4, uint16(response.MouseY))
+	}
+}
+
+// readMouse is read_mouse, writing the row, column, buttons and menu
+// selection of the last click to a table. There are no menus.
+func (z *ZMachine) readMouse(table uint32) {
+	z.Core.WriteHalfWord(table, uint16(z.mouse.MouseY))
+	z.Core.WriteHalfWord(table+2, uint16(z.mouse.MouseX))
+	z.Core.WriteHalfWord(table+4, z.mouse.MouseButtons)
+	z.Core.WriteHalfWord(table+6, 0)
+}
+
+// printForm prints a table in the form output stream 3 writes in V6, lines
+// each given as a length word followed by that many characters and ended by
+// a length of zero
+func (z *ZMachine) printForm(table uint32) {
+	var lines []string
+	for {
+		length := uint32(z.Core.ReadHalfWord(table))
+		if length == 0 {
+			break
+		}
+		lines = append(lines, string(z.Core.ReadSlice(table+2, table+2+length)))
+		table += 2 + length
+	}
+	z.appendText(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,264 @@
+package zmachine_test
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/davetcode/goz/zmachine"
+)
+
+// v6Story is buildStory for V6, where the story starts in a routine, here
+// one with no locals running code
+func v6Story(extra []byte, code []byte) []byte {
+	story := buildStory(6, extra, append([]byte{0}, code...))
+	binary.BigEndian.PutUint16(story[0x06:], 0x100/4)
+	return story
+}
+
+func TestV6Windows(t *testing.T) {
+	story := v6Story(nil, []byte{
+		0xea, 0x7f, 0x03, // split_window 3
+		0xeb, 0x7f, 0x01, // set_window 1
+		0xe5, 0x7f, 'a', // print_char 'a'
+		0xe5, 0x7f, 'b', // print_char 'b'
+		0xf1, 0x7f, 0x02, // set_text_style bold
+		0xef, 0x57, 0x02, 0x05, 0x07, // set_cursor 2 5 7
+		0xbe, 0x12, 0x57, 0x07, 0x01, 0x01, // window_style 7 wrapping set
+		0xbe, 0x08, 0x57, 0x03, 0x00, 0x07, // set_margins 3 0 7
+		0xf0, 0x7f, 0x80, // get_cursor 0x80
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend)
+
+	screen := frontend.screen
+	lower, upper, seven := screen.Windows[0], screen.Windows[1], screen.Windows[7]
+	if upper.Y != 1 || upper.Height != 3 || upper.Width != 80 || lower.Y != 4 || lower.Height != 22 {
+		t.Errorf("expected the screen split after 3 lines, got %+v and %+v", upper, lower)
+	}
+	if screen.CurrentWindow != 1 || upper.CursorY != 1 || upper.CursorX != 3 || upper.TextStyle != zmachine.Bold {
+		t.Errorf("expected bold text after \"ab\" in window 1, got window %d %+v", screen.CurrentWindow, upper)
+	}
+	if seven.CursorY != 2 || seven.CursorX != 5 || seven.LeftMargin != 3 || seven.Attributes != zmachine.WindowWrapping|zmachine.WindowBuffered {
+		t.Errorf("unexpected window 7 %+v", seven)
+	}
+	if row, column := z.Core.ReadHalfWord(0x80), z.Core.ReadHalfWord(0x82); row != 1 || column != 3 {
+		t.Errorf("expected get_cursor to give 1,3, got %d,%d", row, column)
+	}
+
+	// Frontends without V6 windows see windows 0 and 1 as the lower and upper window
+	if screen.LowerWindowActive || screen.UpperWindowHeight != 3 || screen.UpperWindowCursorX != 2 || screen.UpperWindowTextStyle != zmachine.Bold {
+		t.Errorf("expected the upper window fields to follow window 1, got %+v", screen)
+	}
+}
+
+func TestV6EraseWindowJoinsScreen(t *testing.T) {
+	story := v6Story(nil, []byte{
+		0xea, 0x7f, 0x05, // split_window 5
+		0xeb, 0x7f, 0x01, // set_window 1
+		0xe5, 0x7f, 'a', // print_char 'a'
+		0xed, 0x3f, 0xff, 0xff, // erase_window -1
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{}
+	runStory(t, story, frontend)
+
+	screen := frontend.screen
+	if screen.CurrentWindow != 0 || screen.Windows[1].Height != 0 || screen.Windows[0].Y != 1 || screen.Windows[0].Height != 25 {
+		t.Errorf("expected window 0 to cover the screen again, got %+v", screen.Windows[:2])
+	}
+	if screen.Windows[1].CursorX != 1 {
+		t.Errorf("expected window 1's cursor back in its corner, got %d", screen.Windows[1].CursorX)
+	}
+}
+
+func TestV6WindowGeometryAndProperties(t *testing.T) {
+	story := v6Story(nil, []byte{
+		0xbe, 0x10, 0x57, 0x02, 0x05, 0x0a, // move_window 2 5 10
+		0xbe, 0x11, 0x57, 0x02, 0x04, 0x14, // window_size 2 4 20
+		0xbe, 0x19, 0x57, 0x02, 0x06, 0x03, // put_wind_prop 2 6 3
+		0xbe, 0x13, 0x5f, 0x02, 0x03, 0x10, // get_wind_prop 2 3 -> G00
+		0xbe, 0x13, 0x5f, 0x02, 0x0d, 0x11, // get_wind_prop 2 13 -> G01
+		0xbe, 0x13, 0x5f, 0x02, 0x0b, 0x12, // get_wind_prop 2 11 -> G02
+		0xbe, 0x14, 0x5f, 0x01, 0x02, // scroll_window 1 2
+		0xbe, 0x17, 0x3f, 0xff, 0xff, // mouse_window -1
+		0xbe, 0x1d, 0x7f, 0x01, 0x13, // buffer_screen 1 -> G03
+		0xbe, 0x1d, 0x3f, 0xff, 0xff, 0x14, // buffer_screen -1 -> G04
+		0xbe, 0x1c, 0x7f, 0x80, // picture_table 0x80
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend)
+
+	w := frontend.screen.Windows[2]
+	if w.Y != 5 || w.X != 10 || w.Height != 4 || w.Width != 20 || w.LeftMargin != 3 {
+		t.Errorf("unexpected window 2 %+v", w)
+	}
+	globals := []uint16{z.Core.ReadHalfWord(0x60), z.Core.ReadHalfWord(0x62), z.Core.ReadHalfWord(0x64), z.Core.ReadHalfWord(0x66), z.Core.ReadHalfWord(0x68)}
+	if want := []uint16{20, 0x0101, 0x0209, 0, 1}; !slices.Equal(globals, want) {
+		t.Errorf("expected width, font size, colours and buffer modes %x, got %x", want, globals)
+	}
+	if len(frontend.scrolls) != 1 || frontend.scrolls[0] != (zmachine.ScrollWindowRequest{Window: 1, Pixels: 2}) {
+		t.Errorf("expected window 1 to scroll up 2, got %+v", frontend.scrolls)
+	}
+	if frontend.screen.MouseWindow != -1 || !frontend.screen.ScreenBuffered {
+		t.Errorf("expected the mouse free and the screen buffered, got %d %t", frontend.screen.MouseWindow, frontend.screen.ScreenBuffered)
+	}
+}
+
+func TestV6UserStacks(t *testing.T) {
+	story := v6Story([]byte{0, 2, 0, 0, 0, 0}, []byte{ // A user stack with 2 free slots at 0x80
+		0xbe, 0x18, 0x5f, 0x05, 0x80, 0xc4, // push_stack 5 0x80 ?+4
+		0x95, 0x10, // inc G00
+		0xbe, 0x18, 0x5f, 0x06, 0x80, 0xc4, // push_stack 6 0x80 ?+4
+		0x95, 0x10, // inc G00
+		0xbe, 0x18, 0x5f, 0x07, 0x80, 0xc4, // push_stack 7 0x80 ?+4
+		0x95, 0x10, // inc G00
+		0xe9, 0x7f, 0x80, 0x11, // pull 0x80 -> G01
+		0xbe, 0x15, 0x5f, 0x01, 0x80, // pop_stack 1 0x80
+		0xe8, 0x7f, 0x08, // push 8
+		0xe8, 0x7f, 0x09, // push 9
+		0xbe, 0x15, 0x7f, 0x01, // pop_stack 1
+		0xe9, 0xff, 0x12, // pull -> G02
+		0xba, // quit
+	})
+
+	z := runStory(t, story, &scriptedFrontend{})
+
+	if failed := z.Core.ReadHalfWord(0x60); failed != 1 {
+		t.Errorf("expected only the push onto a full stack to fail, got %d failures", failed)
+	}
+	if pulled := z.Core.ReadHalfWord(0x62); pulled != 6 {
+		t.Errorf("expected to pull 6 from the user stack, got %d", pulled)
+	}
+	if pulled := z.Core.ReadHalfWord(0x64); pulled != 8 {
+		t.Errorf("expected pop_stack to throw away 9, got %d", pulled)
+	}
+	if free := z.Core.ReadHalfWord(0x80); free != 2 {
+		t.Errorf("expected the user stack to be empty again, got %d free", free)
+	}
+}
+
+func TestV6FormsMenusMouseAndNewlineInterrupts(t *testing.T) {
+	code := make([]byte, 0x3f) // Leaving the routine below at 0x140 (packed 0x50)
+	copy(code, []byte{
+		0xbe, 0x19, 0x57, 0x00, 0x09, 0x02, // put_wind_prop 0 9 2
+		0xbe, 0x19, 0x57, 0x00, 0x08, 0x50, // put_wind_prop 0 8 routine
+		0xbe, 0x1a, 0x7f, 0x90, // print_form 0x90
+		0xbb,                               // new_line
+		0xbe, 0x1b, 0x5f, 0x03, 0xa0, 0xc4, // make_menu 3 0xa0 ?+4
+		0x95, 0x10, // inc G00
+		0xf6, 0x7f, 0x01, 0x11, // read_char 1 -> G01
+		0xbe, 0x16, 0x7f, 0xa0, // read_mouse 0xa0
+		0xba, // quit
+	})
+	code = append(code,
+		0x00,       // no locals
+		0x95, 0x15, // inc G05
+		0xb0, // rtrue
+	)
+	story := v6Story(nil, code)
+	copy(story[0x90:], []byte{0, 2, 'a', 'b', 0, 1, 'c', 0, 0}) // The form, two lines
+
+	frontend := &scriptedFrontend{click: &zmachine.InputResponse{TerminatingKey: 254, MouseY: 3, MouseX: 7, MouseButtons: 1}}
+	z := runStory(t, story, frontend)
+
+	if output := frontend.output.String(); output != "ab\nc\n" {
+		t.Errorf("expected the form's lines, got %q", output)
+	}
+	if calls := z.Core.ReadHalfWord(0x6a); calls != 1 {
+		t.Errorf("expected the newline interrupt to be called once, got %d", calls)
+	}
+	if failed := z.Core.ReadHalfWord(0x60); failed != 1 {
+		t.Errorf("expected make_menu to fail")
+	}
+	if key := z.Core.ReadHalfWord(0x62); key != 254 {
+		t.Errorf("expected read_char to return the click, got %d", key)
+	}
+	mouse := []uint16{z.Core.ReadHalfWord(0xa0), z.Core.ReadHalfWord(0xa2), z.Core.ReadHalfWord(0xa4), z.Core.ReadHalfWord(0xa6)}
+	if want := []uint16{3, 7, 1, 0}; !slices.Equal(mouse, want) {
+		t.Errorf("expected read_mouse to give %v, got %v", want, mouse)
+	}
+}
+
+func TestCharacterGraphicsFont(t *testing.T) {
+	story := buildStory(5, nil, []byte{
+		0xbe, 0x04, 0x7f, 0x03, 0x10, // set_font 3 -> G00
+		0xbe, 0x04, 0x7f, 0x02, 0x11, // set_font 2 -> G01
+		0xbe, 0x04, 0x7f, 0x00, 0x12, // set_font 0 -> G02
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend)
+
+	globals := []uint16{z.Core.ReadHalfWord(0x60), z.Core.ReadHalfWord(0x62), z.Core.ReadHalfWord(0x64)}
+	if want := []uint16{1, 0, 3}; !slices.Equal(globals, want) {
+		t.Errorf("expected font 3 to be accepted and the picture font refused %v, got %v", want, globals)
+	}
+	if frontend.screen.CurrentFont != zmachine.FontCharGraphs {
+		t.Errorf("expected the screen to be in font 3, got %d", frontend.screen.CurrentFont)
+	}
+
+	if glyphs := zmachine.FontCharGraphs.Glyphs("/&&0 !\"6a"); glyphs != "┌──┐ ←→█a" {
+		t.Errorf("unexpected font 3 glyphs %q", glyphs)
+	}
+	if text := zmachine.FontNormal.Glyphs("/&&0"); text != "/&&0" {
+		t.Errorf("expected font 1 text unchanged, got %q", text)
+	}
+}
+
+func TestV6WindowOpcodesPopEveryStackOperand(t *testing.T) {
+	story := v6Story(nil, []byte{
+		0xe8, 0x7f, 0x01, // push 1
+		0xe8, 0x7f, 0x05, // push 5
+		0xe8, 0x7f, 0x06, // push 6
+		0xe8, 0x7f, 0x09, // push 9
+		0xbe, 0x11, 0xab, 0x00, 0x00, 0x00, // window_size sp sp sp, there's no window 9
+		0xe8, 0x7f, 0x02, // push 2
+		0xbe, 0x17, 0xbf, 0x00, // mouse_window sp
+		0x54, 0x00, 0x00, 0x10, // add sp 0 -> G00
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend)
+
+	if frontend.screen.MouseWindow != 2 {
+		t.Errorf("expected mouse_window to take window 2 off the stack, got %d", frontend.screen.MouseWindow)
+	}
+	if bottom := z.Core.ReadHalfWord(0x60); bottom != 1 {
+		t.Errorf("expected only the first push left on the stack, got %d", bottom)
+	}
+}
+
+func TestV6TrueColourWindowProperties(t *testing.T) {
+	story := v6Story(nil, []byte{
+		0xbe, 0x19, 0x53, 0x00, 0x10, 0x7c, 0x1f, // put_wind_prop 0 16 0x7c1f
+		0xbe, 0x19, 0x53, 0x00, 0x11, 0x03, 0xe0, // put_wind_prop 0 17 0x03e0
+		0xbe, 0x13, 0x5f, 0x00, 0x10, 0x10, // get_wind_prop 0 16 -> G00
+		0xbe, 0x13, 0x5f, 0x00, 0x11, 0x11, // get_wind_prop 0 17 -> G01
+		0xba, // quit
+	})
+
+	frontend := &scriptedFrontend{}
+	z := runStory(t, story, frontend)
+
+	if foreground := z.Core.ReadHalfWord(0x60); foreground != 0x7c1f {
+		t.Errorf("expected the foreground 7c1f back, got %x", foreground)
+	}
+	if background := z.Core.ReadHalfWord(0x62); background != 0x03e0 {
+		t.Errorf("expected the background 03e0 back, got %x", background)
+	}
+	lower := frontend.screen.Windows[0]
+	if hex := lower.Foreground.ToHex(); hex != "#ff00ff" {
+		t.Errorf("expected a magenta foreground, got %s", hex)
+	}
+	if hex := lower.Background.ToHex(); hex != "#00ff00" {
+		t.Errorf("expected a green background, got %s", hex)
+	}
+}
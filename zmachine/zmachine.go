@@ -1,8 +1,11 @@
 package zmachine
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"runtime/debug"
 	"slices"
@@ -10,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/davetcode/goz/blorb"
 	"github.com/davetcode/goz/dictionary"
 	"github.com/davetcode/goz/zcore"
 	"github.com/davetcode/goz/zobject"
@@ -24,24 +28,60 @@ type StatusBar struct {
 	IsTimeBased bool
 }
 
+// Text lays the status bar out as a single line of the given width with the
+// place name on the left and the score/moves or time on the right
+func (s StatusBar) Text(width int) string {
+	rightHandSide := fmt.Sprintf("Score: %d    Moves %d", s.Score, s.Moves)
+
+	if s.IsTimeBased {
+		rightHandSide = fmt.Sprintf("Time: %d:%d", s.Score, s.Moves)
+	}
+
+	// Too narrow to show properly so just show as much of the score/time/moves as we can manage
+	if len(rightHandSide) >= width {
+		return rightHandSide[:width]
+	}
+
+	if len(s.PlaceName)+len(rightHandSide)+1 >= width {
+		return fmt.Sprintf("%s %s", s.PlaceName[:width-len(rightHandSide)-1], rightHandSide)
+	}
+
+	numberSpaces := width - len(s.PlaceName) - len(rightHandSide)
+
+	return fmt.Sprintf("%s%s%s", s.PlaceName, strings.Repeat(" ", numberSpaces), rightHandSide)
+}
+
 type Quit bool
 
 type Restart bool
 
 type RuntimeError string
 
+func (e RuntimeError) Error() string { return string(e) }
+
+// ErrRestart was returned by RunContext when the story asked to be restarted.
+//
+// Deprecated: restarts are now handled in place and RunContext never returns it.
+var ErrRestart = errors.New("story requested restart")
+
 type Warning string
 
 type EraseWindowRequest int
 
 type EraseLineRequest int
 
+// ScrollWindowRequest asks for the contents of a V6 window to move up by
+// Pixels screen units, or down if it's negative, leaving the cursor alone
+type ScrollWindowRequest struct {
+	Window int
+	Pixels int
+}
+
 type StateChangeRequest int
 
 const (
-	WaitForInput     StateChangeRequest = iota
-	WaitForCharacter StateChangeRequest = iota
-	Running          StateChangeRequest = iota
+	WaitForInput StateChangeRequest = iota
+	Running      StateChangeRequest = iota
 )
 
 // InputRequest is sent when the Z-machine needs line input from the user.
@@ -50,12 +90,32 @@ type InputRequest struct {
 	// ValidTerminators contains the Z-character codes that can end input.
 	// Always includes 13 (carriage return/newline). May also include function keys (129-154, 252-254).
 	ValidTerminators []uint8
+
+	// Timeout is how long to wait before returning with TimedOut set, zero
+	// means wait for as long as it takes.
+	Timeout time.Duration
+
+	// InitialText is what the player had typed when the last request timed
+	// out, it should be shown and editable as though they'd just typed it.
+	InitialText string
+}
+
+// CharacterRequest is sent when the Z-machine needs a single keypress
+type CharacterRequest struct {
+	Timeout time.Duration // Zero means wait for as long as it takes
 }
 
 // InputResponse is sent back to the Z-machine with the user's input.
 type InputResponse struct {
 	Text           string
 	TerminatingKey uint8 // The Z-character code of the terminator (13 for Enter, or function key code)
+	TimedOut       bool  // The request's timeout passed first, Text holds whatever had been typed so far
+
+	// Where the mouse was clicked, in screen units from 1, when TerminatingKey
+	// is 254 (single click) or 253 (double click), and the buttons held then
+	MouseY       int
+	MouseX       int
+	MouseButtons uint16
 }
 
 type SoundEffectRequest struct {
@@ -91,34 +151,41 @@ type ZMachine struct {
 	callStack            CallStack
 	Core                 zcore.Core
 	dictionary           *dictionary.Dictionary
+	userDictionaries     dictionary.Cache // Dictionaries passed to TOKENISE
 	screenModel          ScreenModel
 	streams              Streams
+	transcript           io.WriteCloser // Output stream 2, opened by the frontend when first selected
+	commandRecording     io.WriteCloser // Output stream 4, opened by the frontend when first selected
+	commandScript        *commandScript // Input stream 1, nil when reading from the keyboard
 	rng                  rand.Rand
 	Alphabets            *zstring.Alphabets
-	outputChannel        chan<- any
-	inputChannel         <-chan InputResponse
-	saveRestoreChannel   <-chan SaveRestoreResponse
+	frontend             Frontend
+	ctx                  context.Context // Context passed to RunContext, handed to every frontend call
+	stopErr              error           // Why the machine stopped, returned from RunContext
 	UndoStates           InMemorySaveStateCache
-	nextFramePointer     uint16          // Used for catch/throw in V5+
+	SaveFormat           SaveFormat // Format written by ExportSaveState, defaults to Quetzal
+	originalMemory       []uint8    // Dynamic memory as loaded from the story file, used to compress saves
+	interruptResult      uint16     // Value returned by the last interrupt routine, see callInterrupt
+	history              history    // Most recently executed instructions, for crash reports
+	tracer               Tracer
+	tracing              *TraceEvent      // Event for the instruction being executed when tracing
+	symbols              Symbols          // Names for addresses and variables, nil if there aren't any
+	resources            *blorb.Blorb     // Pictures and sounds, nil if the story wasn't in a Blorb
+	soundSystem          SoundSystem      // Plays sampled sounds, nil leaves them to the frontend
+	soundFinished        chan uint16      // Routines of sounds which have finished playing
+	newlineInterrupts    []uint16         // Routines of V6 windows whose newline countdown ran out
+	mouse                InputResponse    // The last mouse click, for read_mouse
+	debugHook            func() error     // Called before every instruction, see SetDebugHook
+	memoryHook           zcore.WriteHook  // Kept here as the core is replaced on restart, see SetMemoryHook
+	memoryStrictness     MemoryStrictness // Kept here as the core is replaced on restart, see SetMemoryStrictness
+	protectedWrite       *protectedWrite  // Refused write of the current instruction, see MemoryStrictnessFatal
+	objectHook           func(ObjectChange)
 	issuedWarnings       map[string]bool // Track warnings to implement "will ignore further occurrences"
 	currentInstructionPC uint32          // PC of the current instruction (for warnings)
 }
 
 func (z *ZMachine) packedAddress(originalAddress uint32, isZString bool) uint32 {
-	switch {
-	case z.Core.Version < 4:
-		return 2 * originalAddress
-	case z.Core.Version < 6:
-		return 4 * originalAddress
-	case z.Core.Version < 8:
-		offset := z.Core.RoutinesOffset
-		if isZString {
-			offset = z.Core.StringOffset
-		}
-		return 4*originalAddress + 8*uint32(offset)
-	case z.Core.Version == 8:
-		return 8 * originalAddress
-	default:
+	if z.Core.Version > 8 {
 		// Invalid/unsupported version detected. This should never happen in practice as LoadCore
 		// would validate the version, but we handle it gracefully. Default to v1-3 behavior as
 		// it's the simplest packed address calculation and gives the best chance of some output
@@ -126,6 +193,7 @@ func (z *ZMachine) packedAddress(originalAddress uint32, isZString bool) uint32
 		z.warnOnce("invalid_version", "Warning: Invalid ROM version %d (PC = %x)", z.Core.Version, z.currentInstructionPC)
 		return 2 * originalAddress
 	}
+	return UnpackAddress(&z.Core, originalAddress, isZString)
 }
 
 func (z *ZMachine) readIncPC(frame *CallStackFrame) uint8 {
@@ -201,48 +269,99 @@ func (z *ZMachine) writeVariable(variable uint8, value uint16, indirect bool) er
 	return nil
 }
 
+// LoadRom creates a machine which talks to its frontend using the channel
+// protocol, see ChannelFrontend.
 func LoadRom(storyFile []uint8, inputChannel <-chan InputResponse, saveRestoreChannel <-chan SaveRestoreResponse, outputChannel chan<- any) *ZMachine {
+	return LoadRomWithFrontend(storyFile, NewChannelFrontend(inputChannel, saveRestoreChannel, outputChannel))
+}
+
+// LoadRomWithFrontend creates a machine which calls directly into the given
+// frontend for all input and output.
+func LoadRomWithFrontend(storyFile []uint8, frontend Frontend) *ZMachine {
+	// Taken before LoadCore writes the interpreter's details into the header
+	staticMemoryBase := binary.BigEndian.Uint16(storyFile[0x0e:0x10])
+	originalMemory := slices.Clone(storyFile[:staticMemoryBase])
+
 	machine := ZMachine{
-		Core:               zcore.LoadCore(storyFile),
-		inputChannel:       inputChannel,
-		saveRestoreChannel: saveRestoreChannel,
-		outputChannel:      outputChannel,
-		issuedWarnings:     make(map[string]bool),
-		streams: Streams{
-			Screen:        true,
-			Transcript:    false,
-			Memory:        false,
-			CommandScript: false,
-		},
-		rng: *rand.New(rand.NewSource(time.Now().UnixNano())),
+		originalMemory: originalMemory,
+		frontend:       frontend,
+		ctx:            context.Background(),
+		issuedWarnings: make(map[string]bool),
+		rng:            *rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	machine.SetHistorySize(DefaultHistorySize)
+	machine.initialise(storyFile)
+
+	return &machine
+}
+
+// initialise sets up the core over the given memory and puts the rest of the
+// machine into the state the story expects to start in
+func (z *ZMachine) initialise(memory []uint8) {
+	z.Core = zcore.LoadCore(memory)
+	z.userDictionaries = dictionary.Cache{}
+	z.claimPictures()
+	z.Core.SetWriteHook(z.memoryHook)
+	z.SetMemoryStrictness(z.memoryStrictness)
+	z.streams = Streams{
+		Screen:        true,
+		Transcript:    false,
+		Memory:        false,
+		CommandScript: false,
 	}
 
 	// Load custom alphabets on v5+
-	machine.Alphabets = zstring.LoadAlphabets(&machine.Core)
+	z.Alphabets = zstring.LoadAlphabets(&z.Core)
 
 	// TODO - Is the dictionary static? If not shouldn't cache like this
-	machine.dictionary = dictionary.ParseDictionary(uint32(machine.Core.DictionaryBase), &machine.Core, machine.Alphabets)
-
-	machine.Core.SetDefaultBackgroundColorNumber(2)
-	machine.Core.SetDefaultForegroundColorNumber(9)
-	machine.screenModel = newScreenModel(Color{0, 0, 0}, Color{255, 255, 255})
+	z.dictionary = dictionary.ParseDictionary(uint32(z.Core.DictionaryBase), &z.Core, z.Alphabets)
+
+	z.Core.SetDefaultBackgroundColorNumber(2)
+	z.Core.SetDefaultForegroundColorNumber(9)
+	z.screenModel = newScreenModel(Color{0, 0, 0}, Color{255, 255, 255})
+	if z.Core.Version == 6 {
+		// The header gives the font height before its width in V6
+		z.screenModel.initialiseWindows(int(z.Core.ScreenWidthUnits), int(z.Core.ScreenHeightUnits), int(z.Core.FontHeight), int(z.Core.FontWidth))
+	}
+	z.newlineInterrupts = nil
 
 	// V6+ uses a packed address and a routine for the initial function
-	if machine.Core.Version == 6 {
-		packedAddress := machine.packedAddress(uint32(machine.Core.FirstInstruction), false)
-
-		machine.callStack.push(CallStackFrame{
-			pc:     packedAddress + 1,
-			locals: make([]uint16, machine.Core.ReadZByte(packedAddress)),
+	z.callStack = CallStack{}
+	if z.Core.Version == 6 {
+		packedAddress := z.packedAddress(uint32(z.Core.FirstInstruction), false)
+
+		z.callStack.push(CallStackFrame{
+			pc:             packedAddress + 1,
+			routineAddress: packedAddress,
+			locals:         make([]uint16, z.Core.ReadZByte(packedAddress)),
 		})
 	} else {
-		machine.callStack.push(CallStackFrame{
-			pc:     uint32(machine.Core.FirstInstruction),
+		z.callStack.push(CallStackFrame{
+			pc:     uint32(z.Core.FirstInstruction),
 			locals: make([]uint16, 0),
 		})
 	}
+}
 
-	return &machine
+// restart reloads dynamic memory from the story file and starts again. The
+// transcript and fixed pitch bits of Flags 2 are kept as the spec requires
+// (6.1.3), so a transcript carries on across the restart.
+func (z *ZMachine) restart() {
+	memory := z.Core.ReadSlice(0, z.Core.MemoryLength())
+	preservedFlags := memory[0x11] & (flags2Transcript | flags2FixedPitch)
+	recording := z.streams.CommandScript
+
+	copy(memory, z.originalMemory)
+	z.initialise(memory)
+	z.Core.WriteZByte(0x11, z.Core.ReadZByte(0x11)&^(flags2Transcript|flags2FixedPitch)|preservedFlags)
+	z.streams.Transcript = preservedFlags&flags2Transcript != 0 && z.transcript != nil
+	z.streams.CommandScript = recording && z.commandRecording != nil
+
+	z.UndoStates = InMemorySaveStateCache{}
+	z.stopSounds()
+
+	z.frontend.Restart(z.ctx)
+	z.updateScreen()
 }
 
 func (z *ZMachine) call(opcode *Opcode, routineType RoutineType) {
@@ -256,12 +375,13 @@ func (z *ZMachine) call(opcode *Opcode, routineType RoutineType) {
 				z.reportError("CallRoutine: %v", err)
 				return
 			}
-			z.writeVariable(z.readIncPC(frame), 0, false) // nolint:errcheck
+			z.storeResult(frame, 0)
 		}
 
 		return
 	}
 
+	routineStart := routineAddress
 	localVariableCount := z.Core.ReadZByte(routineAddress)
 	routineAddress++
 
@@ -285,11 +405,11 @@ func (z *ZMachine) call(opcode *Opcode, routineType RoutineType) {
 
 	z.callStack.push(CallStackFrame{
 		pc:              routineAddress,
+		routineAddress:  routineStart,
 		locals:          locals,
 		routineStack:    make([]uint16, 0),
 		routineType:     routineType, // TODO - Not really sure what this is, v3+ only
 		numValuesPassed: opcode.numOperands - 1,
-		framePointer:    0, // TODO - Only used for try/catch in later versions
 	})
 }
 
@@ -304,6 +424,11 @@ func (z *ZMachine) handleBranch(frame *CallStackFrame, result bool) bool {
 		offset = int32(int16((uint16(branchArg1&0b11_1111)<<8|uint16(z.readIncPC(frame)))<<2) >> 2)
 	}
 
+	if z.tracing != nil {
+		z.tracing.Branched = true
+		z.tracing.BranchTaken = result != branchReversed
+	}
+
 	if result != branchReversed {
 		switch offset {
 		case 0:
@@ -368,7 +493,7 @@ func (z *ZMachine) Tokenise(baddr1 uint32, baddr2 uint32, dictionary *dictionary
 			}
 			startingLocation = currentLocation + 1
 		} else {
-			if slices.Contains(z.dictionary.Header.InputCodes, chr) {
+			if slices.Contains(dictionary.Header.InputCodes, chr) {
 				// Only add accumulated word if we have one
 				if currentLocation > startingLocation {
 					words = append(words, tokeniseSingleWord(z.Core.ReadSlice(startingLocation, currentLocation), startingLocation, dictionary, &z.Core, z.Alphabets))
@@ -392,9 +517,13 @@ func (z *ZMachine) Tokenise(baddr1 uint32, baddr2 uint32, dictionary *dictionary
 	z.Core.WriteZByte(parseBufferPtr, uint8(len(words)))
 	parseBufferPtr += 1
 	for _, word := range words {
-		z.Core.WriteHalfWord(parseBufferPtr, word.dictionaryAddress)
-		z.Core.WriteZByte(parseBufferPtr+2, uint8(len(word.bytes)))
-		z.Core.WriteZByte(parseBufferPtr+3, uint8(word.startingLocation-baddr1))
+		// With the flag set only recognised words are written so that a story
+		// can tokenise against several dictionaries in turn
+		if word.dictionaryAddress != 0 || !leaveWordsBlank {
+			z.Core.WriteHalfWord(parseBufferPtr, word.dictionaryAddress)
+			z.Core.WriteZByte(parseBufferPtr+2, uint8(len(word.bytes)))
+			z.Core.WriteZByte(parseBufferPtr+3, uint8(word.startingLocation-baddr1))
+		}
 
 		parseBufferPtr += 4
 	}
@@ -410,9 +539,12 @@ func (z *ZMachine) retValue(val uint16) error {
 		return fmt.Errorf("retValue: %w", err)
 	}
 
-	if oldFrame.routineType == function {
+	switch oldFrame.routineType {
+	case function:
 		destination := z.readIncPC(newFrame)
 		z.writeVariable(destination, val, false) // nolint:errcheck
+	case interrupt:
+		z.interruptResult = val
 	}
 	return nil
 }
@@ -424,6 +556,14 @@ func (z *ZMachine) RemoveObject(objId uint16) {
 		return
 	}
 
+	oldParent := zobject.GetObject(objId, &z.Core, z.Alphabets).Parent
+	z.detachObject(objId)
+	z.parentChanged(objId, oldParent, 0)
+}
+
+// detachObject takes an object out of the tree, leaving it with no parent
+// or siblings
+func (z *ZMachine) detachObject(objId uint16) {
 	object := zobject.GetObject(objId, &z.Core, z.Alphabets)
 	if object.Parent != 0 {
 		oldParent := zobject.GetObject(object.Parent, &z.Core, z.Alphabets)
@@ -465,9 +605,10 @@ func (z *ZMachine) MoveObject(objId uint16, newParent uint16) {
 	}
 
 	object := zobject.GetObject(objId, &z.Core, z.Alphabets)
+	oldParent := object.Parent
 
 	// Detach it from it's current place in the tree
-	z.RemoveObject(object.Id)
+	z.detachObject(object.Id)
 
 	// Re-read destination's child from memory after removal, as RemoveObject may have
 	// modified it (e.g., if objId was the first child of newParent)
@@ -480,6 +621,8 @@ func (z *ZMachine) MoveObject(objId uint16, newParent uint16) {
 	// Re-read destination object to get correct base address for SetChild
 	destinationObject := zobject.GetObject(newParent, &z.Core, z.Alphabets)
 	destinationObject.SetChild(object.Id, &z.Core)
+
+	z.parentChanged(object.Id, oldParent, newParent)
 }
 
 func (z *ZMachine) appendText(s string) {
@@ -495,8 +638,12 @@ func (z *ZMachine) appendText(s string) {
 		return
 	}
 
-	if z.streams.Screen {
-		z.outputChannel <- s
+	if z.streams.Screen && z.Core.Version == 6 {
+		z.frontend.Print(z.ctx, s)
+		z.advanceCursor(s)
+		z.updateScreen()
+	} else if z.streams.Screen {
+		z.frontend.Print(z.ctx, s)
 
 		// If writing to the upper window we need to update the screen model and
 		// reflect the change in cursor position
@@ -510,31 +657,35 @@ func (z *ZMachine) appendText(s string) {
 				// No newlines - just advance X
 				z.screenModel.UpperWindowCursorX += len(s)
 			}
-			z.outputChannel <- z.screenModel
+			z.updateScreen()
 		}
 	}
 
-	if z.streams.Transcript {
-		panic("TODO - Not implemented transcript")
+	// The upper window is a status area rather than part of the story so isn't transcribed
+	if z.Core.Version == 6 {
+		if z.screenModel.Windows[z.screenModel.CurrentWindow].Attributes&WindowTranscript != 0 {
+			z.writeTranscript(s)
+		}
+	} else if z.screenModel.LowerWindowActive {
+		z.writeTranscript(s)
 	}
 
-	if z.streams.CommandScript {
-		panic("TODO - Not implemented command script stream")
-	}
 }
 
-func (z *ZMachine) read(opcode *Opcode) {
+func (z *ZMachine) read(opcode *Opcode) bool {
+	operands := opcode.values(z)
+
 	if z.Core.Version <= 3 { // TODO - Not really sure if this is true
 		locationVar, _ := z.readVariable(16, false)
 		scoreVar, _ := z.readVariable(17, false)
 		movesVar, _ := z.readVariable(18, false)
 		currentLocation := zobject.GetObject(locationVar, &z.Core, z.Alphabets)
-		z.outputChannel <- StatusBar{
+		z.frontend.UpdateStatusBar(z.ctx, StatusBar{
 			PlaceName:   currentLocation.Name,
 			Score:       int(scoreVar),
 			Moves:       int(movesVar),
 			IsTimeBased: z.Core.StatusBarTimeBased,
-		}
+		})
 	}
 
 	// In V5+ a custom set of terminating characters can be stored in memory
@@ -558,12 +709,30 @@ func (z *ZMachine) read(opcode *Opcode) {
 		}
 	}
 
-	// TODO - Handle timed interrupts of the read function
+	// V4+ can give a time in tenths of a second after which the routine is called
+	var tenths, routine uint16
+	if z.Core.Version >= 4 && opcode.numOperands >= 4 {
+		tenths = operands[2]
+		routine = operands[3]
+	}
+
 	// TODO - Somehow let UI know how many chars to accept
-	z.outputChannel <- InputRequest{ValidTerminators: validTerminators}
-	inputResponse := <-z.inputChannel
-	textBufferPtr := opcode.operands[0].Value(z)
-	parseBufferPtr := opcode.operands[1].Value(z)
+	z.resetLineCounts()
+	inputResponse, ok, err := z.readLineTimed(InputRequest{ValidTerminators: validTerminators, Timeout: timeout(tenths, routine)}, routine)
+	if err != nil {
+		return z.stop(err)
+	}
+	if !ok {
+		return false
+	}
+	// The frontend echoes the command on screen, the transcript needs it too
+	z.writeTranscript(inputResponse.Text + "\n")
+	if z.Core.Version == 6 {
+		z.advanceCursor(inputResponse.Text + "\n")
+	}
+
+	textBufferPtr := operands[0]
+	parseBufferPtr := operands[1]
 
 	rawTextBytes := []byte(strings.ToLower(inputResponse.Text))
 
@@ -594,32 +763,40 @@ func (z *ZMachine) read(opcode *Opcode) {
 
 	// Need to store the number of bytes in total in v5+ as that's used to determine end point of the string
 	if z.Core.Version >= 5 {
-		z.Core.WriteZByte(uint32(opcode.operands[0].Value(z)+1), uint8(ix))
+		z.Core.WriteZByte(uint32(operands[0]+1), uint8(ix))
 	}
 
 	// TODO - Can this ever really be zero?
 	if parseBufferPtr != 0 {
-		z.Tokenise(uint32(opcode.operands[0].Value(z)), uint32(parseBufferPtr), z.dictionary, false)
+		z.Tokenise(uint32(operands[0]), uint32(parseBufferPtr), z.dictionary, false)
 	}
 
 	if z.Core.Version >= 5 {
 		frame, err := z.callStack.peek()
 		if err != nil {
-			z.reportError("READ: %v", err)
-			return
+			return z.reportError("READ: %v", err)
 		}
 		// Store the actual terminating character that ended input
-		z.writeVariable(z.readIncPC(frame), uint16(inputResponse.TerminatingKey), false) // nolint:errcheck
+		z.storeResult(frame, uint16(inputResponse.TerminatingKey))
 	}
+
+	return true
 }
 
-// reportError sends an error to the output channel and returns false to stop execution
+// reportError sends an error to the frontend and returns false to stop execution
 func (z *ZMachine) reportError(format string, args ...any) bool {
-	z.outputChannel <- RuntimeError(fmt.Sprintf(format, args...))
+	runtimeError := RuntimeError(fmt.Sprintf(format, args...) + z.describe(z.currentInstructionPC))
+	z.frontend.RuntimeError(z.ctx, runtimeError)
+	return z.stop(runtimeError)
+}
+
+// stop records why the machine is stopping and returns false to stop execution
+func (z *ZMachine) stop(err error) bool {
+	z.stopErr = err
 	return false
 }
 
-// warnOnce emits a warning to the output channel, but only once per warning key.
+// warnOnce emits a warning to the frontend, but only once per warning key.
 // This matches Frotz behavior: "Warning: ... (will ignore further occurrences)"
 // The warningKey should be the opcode name (e.g., "test_attr")
 func (z *ZMachine) warnOnce(warningKey string, format string, args ...any) {
@@ -627,11 +804,28 @@ func (z *ZMachine) warnOnce(warningKey string, format string, args ...any) {
 		return
 	}
 	z.issuedWarnings[warningKey] = true
-	msg := fmt.Sprintf(format, args...)
-	z.outputChannel <- Warning(msg + " (will ignore further occurrences)")
+	msg := fmt.Sprintf(format, args...) + z.describe(z.currentInstructionPC)
+	z.frontend.Warning(z.ctx, Warning(msg+" (will ignore further occurrences)"))
 }
 
+// Run executes the story until it stops, see RunContext.
 func (z *ZMachine) Run() {
+	z.RunContext(context.Background()) // nolint:errcheck
+}
+
+// RunContext executes the story until it stops or ctx is done, including
+// while waiting on the frontend for input or a save/restore. The returned
+// error says why it stopped: nil if the story quit, a RuntimeError if it
+// failed or the context's error if it was cancelled. Restarts are handled
+// without stopping.
+func (z *ZMachine) RunContext(ctx context.Context) (err error) {
+	z.ctx = ctx
+	z.stopErr = nil
+	defer z.closeTranscript()
+	defer z.closeCommandRecording()
+	defer z.closeCommandScript()
+	defer z.stopSounds()
+
 	// Catch any remaining panics from helper functions and convert to RuntimeError
 	defer func() {
 		if r := recover(); r != nil {
@@ -639,37 +833,52 @@ func (z *ZMachine) Run() {
 			// to get the original panic location
 			stackTrace := debug.Stack()
 
-			// Build debug context from PC history
+			// Build debug context from the instruction history
 			var debugInfo strings.Builder
 			fmt.Fprintf(&debugInfo, "Internal error: %v\n", r)
+			if description := z.describe(z.currentInstructionPC); description != "" {
+				fmt.Fprintf(&debugInfo, "At PC %x%s\n", z.currentInstructionPC, description)
+			}
 			debugInfo.WriteString("Recent opcode history (most recent last):\n")
-			for i := range 10 {
-				idx := (pcHistoryPtr - 10 + i + 100) % 100
-				op := pcHistory[idx]
-				if op.pc != 0 { // Skip uninitialized entries
-					fmt.Fprintf(&debugInfo, "  PC=0x%x opcode=0x%x operands=%v\n", op.pc, op.opcodeByte, op.operands)
-				}
+			for _, op := range z.history.recent() {
+				fmt.Fprintf(&debugInfo, "  %s\n", z.traceEvent(&op))
 			}
 			fmt.Fprintf(&debugInfo, "\nGo stack trace:\n%s", stackTrace)
-			z.outputChannel <- RuntimeError(debugInfo.String())
-			z.outputChannel <- Quit(true)
+			runtimeError := RuntimeError(debugInfo.String())
+			z.frontend.RuntimeError(z.ctx, runtimeError)
+			z.frontend.Quit(z.ctx)
+			err = runtimeError
 		}
 	}()
 
 	// Initialise whatever is listening by sending inital versions of the screen model
-	z.outputChannel <- z.screenModel
+	z.updateScreen()
 
-	for z.StepMachine() {
+	for ctx.Err() == nil && z.StepMachine() {
 	}
 
-	z.outputChannel <- Quit(true)
+	if ctx.Err() != nil {
+		// Whoever cancelled the context doesn't need telling that the machine stopped
+		return ctx.Err()
+	}
+	z.frontend.Quit(z.ctx)
+	return z.stopErr
 }
 
-// Debugging information, show last 100 program counter addresses
-var pcHistory = make([]Opcode, 100)
-var pcHistoryPtr = 0
-
 func (z *ZMachine) StepMachine() bool {
+	if z.soundSystem != nil && !z.runSoundRoutines() {
+		return false
+	}
+	if len(z.newlineInterrupts) > 0 && !z.runNewlineInterrupts() {
+		return false
+	}
+
+	if z.debugHook != nil {
+		if err := z.debugHook(); err != nil {
+			return z.stop(err)
+		}
+	}
+
 	opcode, err := ParseOpcode(z)
 	if err != nil {
 		return z.reportError("ParseOpcode: %v", err)
@@ -680,9 +889,24 @@ func (z *ZMachine) StepMachine() bool {
 		return z.reportError("StepMachine: %v", err)
 	}
 
-	pcHistory[pcHistoryPtr] = opcode
-	pcHistoryPtr = (pcHistoryPtr + 1) % 100
+	z.history.record(opcode)
 
+	if z.tracer == nil {
+		return z.execute(opcode, frame) && z.checkProtectedWrites()
+	}
+
+	// Interrupt routines run instructions in the middle of this one
+	event := z.traceEvent(&opcode)
+	outer := z.tracing
+	z.tracing = &event
+	running := z.execute(opcode, frame)
+	z.tracing = outer
+	z.tracer.Trace(event)
+	return running && z.checkProtectedWrites()
+}
+
+// execute runs a single decoded instruction, frame is the frame it was read from
+func (z *ZMachine) execute(opcode Opcode, frame *CallStackFrame) bool {
 	switch opcode.operandCount {
 	case OP0:
 		switch opcode.opcodeNumber {
@@ -715,14 +939,11 @@ func (z *ZMachine) StepMachine() bool {
 
 		case 5: // SAVE
 			if z.Core.Version >= 1 && z.Core.Version < 5 {
-				z.outputChannel <- Save{Prompt: true}
-
-				response := <-z.saveRestoreChannel
-				if saveResp, ok := response.(SaveResponse); ok {
-					z.handleBranch(frame, saveResp.Success)
-				} else {
-					z.handleBranch(frame, false)
+				saveResp, err := z.frontend.Save(z.ctx, Save{Prompt: true, Data: z.ExportSaveState()})
+				if err != nil {
+					return z.stop(err)
 				}
+				z.handleBranch(frame, saveResp.Success)
 			} else {
 				z.reportError("OP0 save called on unsupported version %d (PC = %x)", z.Core.Version, z.currentInstructionPC)
 				return false
@@ -730,11 +951,12 @@ func (z *ZMachine) StepMachine() bool {
 
 		case 6: // RESTORE
 			if z.Core.Version >= 1 && z.Core.Version < 5 {
-				z.outputChannel <- Restore{Prompt: true}
-
-				response := <-z.saveRestoreChannel
-				restoreResp, ok := response.(RestoreResponse)
-				if ok && restoreResp.Success && len(restoreResp.Data) > 0 {
+				restoreResp, err := z.frontend.Restore(z.ctx, Restore{Prompt: true})
+				if err != nil {
+					return z.stop(err)
+				}
+				ok := true
+				if restoreResp.Success && len(restoreResp.Data) > 0 {
 					if z.ImportSaveState(restoreResp.Data) {
 						// PC is now at the save point, need the restored frame
 						newFrame, err := z.callStack.peek()
@@ -754,8 +976,7 @@ func (z *ZMachine) StepMachine() bool {
 			}
 
 		case 7: // RESTART
-			z.outputChannel <- Restart(true)
-			return false
+			z.restart()
 
 		case 8: // RET_POPPED
 			v := frame.pop(z)
@@ -767,10 +988,8 @@ func (z *ZMachine) StepMachine() bool {
 			if z.Core.Version <= 4 {
 				frame.pop(z)
 			} else {
-				// Tag the current frame with a unique frame pointer and store it
-				z.nextFramePointer++
-				frame.framePointer = uint32(z.nextFramePointer)
-				z.writeVariable(z.readIncPC(frame), z.nextFramePointer, false) // nolint:errcheck
+				// As in Quetzal the frame is identified by how deep the call stack is
+				z.storeResult(frame, uint16(len(z.callStack.frames)))
 			}
 
 		case 10: // QUIT
@@ -784,7 +1003,7 @@ func (z *ZMachine) StepMachine() bool {
 			fileLength := z.Core.FileLength()
 			actualChecksum := uint16(0)
 
-			for ix := uint32(0x40); ix < uint32(fileLength); ix++ {
+			for ix := uint32(0x40); ix < fileLength; ix++ {
 				actualChecksum += uint16(z.Core.ReadZByte(ix))
 			}
 
@@ -814,7 +1033,7 @@ func (z *ZMachine) StepMachine() bool {
 				z.warnOnce("get_sibling", "Warning: @get_sibling called with object 0 (PC = %x)", opcode.pc)
 			}
 			sibling := zobject.GetObjectSafe(objId, &z.Core, z.Alphabets).Sibling
-			z.writeVariable(z.readIncPC(frame), sibling, false) // nolint:errcheck
+			z.storeResult(frame, sibling)
 
 			if !z.handleBranch(frame, sibling != 0) {
 				return false
@@ -826,7 +1045,7 @@ func (z *ZMachine) StepMachine() bool {
 				z.warnOnce("get_child", "Warning: @get_child called with object 0 (PC = %x)", opcode.pc)
 			}
 			child := zobject.GetObjectSafe(objId, &z.Core, z.Alphabets).Child
-			z.writeVariable(z.readIncPC(frame), child, false) // nolint:errcheck
+			z.storeResult(frame, child)
 
 			if !z.handleBranch(frame, child != 0) {
 				return false
@@ -837,11 +1056,11 @@ func (z *ZMachine) StepMachine() bool {
 			if objId == 0 {
 				z.warnOnce("get_parent", "Warning: @get_parent called with object 0 (PC = %x)", opcode.pc)
 			}
-			z.writeVariable(z.readIncPC(frame), zobject.GetObjectSafe(objId, &z.Core, z.Alphabets).Parent, false) // nolint:errcheck
+			z.storeResult(frame, zobject.GetObjectSafe(objId, &z.Core, z.Alphabets).Parent)
 
 		case 4: // GET_PROP_LEN
 			addr := opcode.operands[0].Value(z)
-			z.writeVariable(z.readIncPC(frame), zobject.GetPropertyLength(&z.Core, uint32(addr)), false) // nolint:errcheck
+			z.storeResult(frame, zobject.GetPropertyLength(&z.Core, uint32(addr)))
 		case 5: // INC
 			variable := uint8(opcode.operands[0].Value(z))
 			val, _ := z.readVariable(variable, true)
@@ -890,12 +1109,12 @@ func (z *ZMachine) StepMachine() bool {
 		case 14: // LOAD
 			value := opcode.operands[0].Value(z)
 			val, _ := z.readVariable(uint8(value), true)
-			z.writeVariable(z.readIncPC(frame), val, false) // nolint:errcheck
+			z.storeResult(frame, val)
 
 		case 15: // NOT or CALL_1n
 			if z.Core.Version < 5 {
 				val := opcode.operands[0].Value(z)
-				z.writeVariable(z.readIncPC(frame), ^val, false) // nolint:errcheck
+				z.storeResult(frame, ^val)
 			} else {
 				z.call(&opcode, procedure)
 			}
@@ -976,10 +1195,10 @@ func (z *ZMachine) StepMachine() bool {
 			}
 
 		case 8: // OR
-			z.writeVariable(z.readIncPC(frame), opcode.operands[0].Value(z)|opcode.operands[1].Value(z), false) // nolint:errcheck
+			z.storeResult(frame, opcode.operands[0].Value(z)|opcode.operands[1].Value(z))
 
 		case 9: // AND
-			z.writeVariable(z.readIncPC(frame), opcode.operands[0].Value(z)&opcode.operands[1].Value(z), false) // nolint:errcheck
+			z.storeResult(frame, opcode.operands[0].Value(z)&opcode.operands[1].Value(z))
 
 		case 10: // TEST_ATTR
 			objId := opcode.operands[0].Value(z)
@@ -997,7 +1216,10 @@ func (z *ZMachine) StepMachine() bool {
 				z.warnOnce("set_attr", "Warning: @set_attr called with object 0 (PC = %x)", opcode.pc)
 			} else {
 				obj := zobject.GetObject(objId, &z.Core, z.Alphabets)
-				obj.SetAttribute(opcode.operands[1].Value(z), &z.Core)
+				attribute := opcode.operands[1].Value(z)
+				wasSet := obj.TestAttribute(attribute)
+				obj.SetAttribute(attribute, &z.Core)
+				z.attributeChanged(objId, attribute, wasSet, true)
 			}
 
 		case 12: // CLEAR_ATTR
@@ -1006,7 +1228,10 @@ func (z *ZMachine) StepMachine() bool {
 				z.warnOnce("clear_attr", "Warning: @clear_attr called with object 0 (PC = %x)", opcode.pc)
 			} else {
 				obj := zobject.GetObject(objId, &z.Core, z.Alphabets)
-				obj.ClearAttribute(opcode.operands[1].Value(z), &z.Core)
+				attribute := opcode.operands[1].Value(z)
+				wasSet := obj.TestAttribute(attribute)
+				obj.ClearAttribute(attribute, &z.Core)
+				z.attributeChanged(objId, attribute, wasSet, false)
 			}
 
 		case 13: // STORE
@@ -1016,16 +1241,16 @@ func (z *ZMachine) StepMachine() bool {
 			z.MoveObject(opcode.operands[0].Value(z), opcode.operands[1].Value(z))
 
 		case 15: // LOADW
-			z.writeVariable(z.readIncPC(frame), z.Core.ReadHalfWord(uint32(opcode.operands[0].Value(z)+2*opcode.operands[1].Value(z))), false) // nolint:errcheck
+			z.storeResult(frame, z.Core.ReadHalfWord(uint32(opcode.operands[0].Value(z)+2*opcode.operands[1].Value(z))))
 
 		case 16: // LOADB
-			z.writeVariable(z.readIncPC(frame), uint16(z.Core.ReadZByte(uint32(opcode.operands[0].Value(z)+opcode.operands[1].Value(z)))), false) // nolint:errcheck
+			z.storeResult(frame, uint16(z.Core.ReadZByte(uint32(opcode.operands[0].Value(z)+opcode.operands[1].Value(z)))))
 
 		case 17: // GET_PROP
 			objId := opcode.operands[0].Value(z)
 			if objId == 0 {
 				z.warnOnce("get_prop", "Warning: @get_prop called with object 0 (PC = %x)", opcode.pc)
-				z.writeVariable(z.readIncPC(frame), 0, false) // nolint:errcheck
+				z.storeResult(frame, 0)
 			} else {
 				obj := zobject.GetObject(objId, &z.Core, z.Alphabets)
 				prop := obj.GetProperty(uint8(opcode.operands[1].Value(z)), &z.Core)
@@ -1038,44 +1263,44 @@ func (z *ZMachine) StepMachine() bool {
 					z.warnOnce("get_prop_prop_len", "Warning: @get_prop called with object %d property %d which has length %d (PC = %x); only first two bytes returned", objId, opcode.operands[1].Value(z), len(prop.Data), opcode.pc)
 				}
 
-				z.writeVariable(z.readIncPC(frame), value, false) // nolint:errcheck
+				z.storeResult(frame, value)
 			}
 
 		case 18: // GET_PROP_ADDR
 			objId := opcode.operands[0].Value(z)
 			if objId == 0 {
 				z.warnOnce("get_prop_addr", "Warning: @get_prop_addr called with object 0 (PC = %x)", opcode.pc)
-				z.writeVariable(z.readIncPC(frame), 0, false) // nolint:errcheck
+				z.storeResult(frame, 0)
 			} else {
 				obj := zobject.GetObject(objId, &z.Core, z.Alphabets)
 				prop := obj.GetProperty(uint8(opcode.operands[1].Value(z)), &z.Core)
-				z.writeVariable(z.readIncPC(frame), uint16(prop.DataAddress), false) // nolint:errcheck
+				z.storeResult(frame, uint16(prop.DataAddress))
 			}
 
 		case 19: // GET_NEXT_PROP
 			objId := opcode.operands[0].Value(z)
 			if objId == 0 {
 				z.warnOnce("get_next_prop", "Warning: @get_next_prop called with object 0 (PC = %x)", opcode.pc)
-				z.writeVariable(z.readIncPC(frame), 0, false) // nolint:errcheck
+				z.storeResult(frame, 0)
 			} else {
 				obj := zobject.GetObject(objId, &z.Core, z.Alphabets)
 				nextProp, err := obj.GetNextProperty(uint8(opcode.operands[1].Value(z)), &z.Core)
 				if err != nil {
 					z.warnOnce("get_next_prop_invalid", "Warning: @get_next_prop error: %v (PC = %x)", err, opcode.pc)
-					z.writeVariable(z.readIncPC(frame), 0, false) // nolint:errcheck
+					z.storeResult(frame, 0)
 				} else {
-					z.writeVariable(z.readIncPC(frame), uint16(nextProp), false) // nolint:errcheck
+					z.storeResult(frame, uint16(nextProp))
 				}
 			}
 
 		case 20: // ADD
-			z.writeVariable(z.readIncPC(frame), opcode.operands[0].Value(z)+opcode.operands[1].Value(z), false) // nolint:errcheck
+			z.storeResult(frame, opcode.operands[0].Value(z)+opcode.operands[1].Value(z))
 
 		case 21: // SUB
-			z.writeVariable(z.readIncPC(frame), opcode.operands[0].Value(z)-opcode.operands[1].Value(z), false) // nolint:errcheck
+			z.storeResult(frame, opcode.operands[0].Value(z)-opcode.operands[1].Value(z))
 
 		case 22: // MUL
-			z.writeVariable(z.readIncPC(frame), opcode.operands[0].Value(z)*opcode.operands[1].Value(z), false) // nolint:errcheck
+			z.storeResult(frame, opcode.operands[0].Value(z)*opcode.operands[1].Value(z))
 
 		case 23: // DIV
 			numerator := int16(opcode.operands[0].Value(z))
@@ -1083,7 +1308,7 @@ func (z *ZMachine) StepMachine() bool {
 			if denominator == 0 {
 				return z.reportError("Division by zero")
 			}
-			z.writeVariable(z.readIncPC(frame), uint16(numerator/denominator), false) // nolint:errcheck
+			z.storeResult(frame, uint16(numerator/denominator))
 
 		case 24: // MOD
 			numerator := int16(opcode.operands[0].Value(z))
@@ -1091,7 +1316,7 @@ func (z *ZMachine) StepMachine() bool {
 			if denominator == 0 {
 				return z.reportError("Modulo by zero")
 			}
-			z.writeVariable(z.readIncPC(frame), uint16(numerator%denominator), false) // nolint:errcheck
+			z.storeResult(frame, uint16(numerator%denominator))
 
 		case 25: // call_2s
 			if z.Core.Version < 4 {
@@ -1112,6 +1337,17 @@ func (z *ZMachine) StepMachine() bool {
 				return z.reportError("set_colour not available on v1-4")
 			}
 
+			if z.Core.Version == 6 {
+				foreground, background := opcode.operands[0].Value(z), opcode.operands[1].Value(z)
+				w, _, ok := z.optionalWindow(&opcode, 2)
+				if ok {
+					w.Foreground = z.screenModel.colour(foreground, true, w)
+					w.Background = z.screenModel.colour(background, false, w)
+					z.updateScreen()
+				}
+				break
+			}
+
 			foreground := z.screenModel.NewZMachineColor(opcode.operands[0].Value(z), true)
 			background := z.screenModel.NewZMachineColor(opcode.operands[1].Value(z), false)
 			if z.screenModel.LowerWindowActive {
@@ -1121,28 +1357,20 @@ func (z *ZMachine) StepMachine() bool {
 				z.screenModel.UpperWindowForeground = foreground
 				z.screenModel.UpperWindowBackground = background
 			}
-			z.outputChannel <- z.screenModel
+			z.updateScreen()
 
 		case 28: // throw
 			if z.Core.Version < 5 {
 				return z.reportError("throw not available on v1-4")
 			}
 			returnValue := opcode.operands[0].Value(z)
-			fp := uint32(opcode.operands[1].Value(z))
+			depth := int(opcode.operands[1].Value(z))
 
-			// Pop frames until we find the one with the matching frame pointer
-			for {
-				frame, err := z.callStack.peek()
-				if err != nil {
-					return z.reportError("THROW: %v", err)
-				}
-				if frame.framePointer == fp {
-					break
-				}
-				if _, err := z.callStack.pop(); err != nil {
-					return z.reportError("THROW: %v", err)
-				}
+			// Pop frames until the one which caught is back on top
+			if depth == 0 || depth > len(z.callStack.frames) {
+				return z.reportError("THROW: no frame %d to throw to", depth)
 			}
+			z.callStack.frames = z.callStack.frames[:depth]
 
 			// Return with the given value from the found frame
 			if err := z.retValue(returnValue); err != nil {
@@ -1175,14 +1403,23 @@ func (z *ZMachine) StepMachine() bool {
 					}
 				}
 
-				z.outputChannel <- Save{Prompt: prompt, Filename: filename, Address: address, NumBytes: numBytes}
-
-				response := <-z.saveRestoreChannel
-				if saveResp, ok := response.(SaveResponse); ok {
-					z.writeVariable(z.readIncPC(frame), saveResp.Result, false) // nolint:errcheck
+				var data []byte
+				if numBytes == 0 {
+					data = z.ExportSaveState()
+				} else if address+numBytes > z.Core.MemoryLength() {
+					z.warnOnce("aux_save_range", "Warning: Auxiliary save of %d bytes at %x runs off the end of memory (PC = %x)", numBytes, address, z.currentInstructionPC)
+					z.storeResult(frame, 0)
+					return true
 				} else {
-					z.writeVariable(z.readIncPC(frame), 0, false) // nolint:errcheck
+					// Auxiliary save of a table, the bytes are written to the file verbatim
+					data = slices.Clone(z.Core.ReadSlice(address, address+numBytes))
+				}
+
+				saveResp, err := z.frontend.Save(z.ctx, Save{Prompt: prompt, Filename: filename, Address: address, NumBytes: numBytes, Data: data})
+				if err != nil {
+					return z.stop(err)
 				}
+				z.storeResult(frame, saveResp.Result)
 
 			case 0x01: // EXT_RESTORE
 				var address, numBytes uint32
@@ -1200,11 +1437,28 @@ func (z *ZMachine) StepMachine() bool {
 					}
 				}
 
-				z.outputChannel <- Restore{Prompt: prompt, Filename: filename, Address: address, NumBytes: numBytes}
+				restoreResp, err := z.frontend.Restore(z.ctx, Restore{Prompt: prompt, Filename: filename, Address: address, NumBytes: numBytes})
+				if err != nil {
+					return z.stop(err)
+				}
+				if numBytes != 0 {
+					// Auxiliary restore loads at most numBytes and stores how many were loaded
+					var loaded uint16
+					if address+numBytes > uint32(z.Core.StaticMemoryBase) {
+						z.warnOnce("aux_restore_range", "Warning: Auxiliary restore of %d bytes at %x runs past dynamic memory (PC = %x)", numBytes, address, z.currentInstructionPC)
+					} else if restoreResp.Success {
+						n := min(uint32(len(restoreResp.Data)), numBytes)
+						for i := range n {
+							z.Core.WriteZByte(address+i, restoreResp.Data[i])
+						}
+						loaded = uint16(n)
+					}
+					z.storeResult(frame, loaded)
+					return true
+				}
 
-				response := <-z.saveRestoreChannel
-				restoreResp, ok := response.(RestoreResponse)
-				if ok && restoreResp.Success && numBytes == 0 && len(restoreResp.Data) > 0 {
+				ok := true
+				if restoreResp.Success && len(restoreResp.Data) > 0 {
 					if z.ImportSaveState(restoreResp.Data) {
 						newFrame, err := z.callStack.peek()
 						if err != nil {
@@ -1218,9 +1472,9 @@ func (z *ZMachine) StepMachine() bool {
 				}
 
 				if ok {
-					z.writeVariable(z.readIncPC(frame), restoreResp.Result, false) // nolint:errcheck
+					z.storeResult(frame, restoreResp.Result)
 				} else {
-					z.writeVariable(z.readIncPC(frame), 0, false) // nolint:errcheck
+					z.storeResult(frame, 0)
 				}
 
 			case 0x02: // LOG_SHIFT
@@ -1234,7 +1488,7 @@ func (z *ZMachine) StepMachine() bool {
 					result = num >> (-1 * places)
 				}
 
-				z.writeVariable(z.readIncPC(frame), result, false) // nolint:errcheck
+				z.storeResult(frame, result)
 			case 0x03: // ART_SHIFT
 				num := int16(opcode.operands[0].Value(z))
 				places := int16(opcode.operands[1].Value(z))
@@ -1246,42 +1500,45 @@ func (z *ZMachine) StepMachine() bool {
 					result = uint16(num >> (-1 * places))
 				}
 
-				z.writeVariable(z.readIncPC(frame), result, false) // nolint:errcheck
+				z.storeResult(frame, result)
 
 			case 0x04: // SET_FONT
 				requestFont := Font(opcode.operands[0].Value(z))
 
-				// V6 has optional window parameter - we don't support multiple windows
-				if z.Core.Version == 6 && opcode.numOperands > 1 {
-					window := int16(opcode.operands[1].Value(z))
-					if window != -3 && window != 0 {
-						z.warnOnce("set_font_v6_window", "Warning: SET_FONT with window %d not supported (only -3 and 0)", window)
+				// V6 has an optional window parameter, each window has its own font
+				font := &z.screenModel.CurrentFont
+				if z.Core.Version == 6 {
+					w, _, ok := z.optionalWindow(&opcode, 1)
+					if !ok {
+						z.storeResult(frame, 0)
+						break
 					}
+					font = &w.Font
 				}
 
-				previousFont := z.screenModel.CurrentFont
+				previousFont := *font
 				var result uint16
 
 				switch requestFont {
 				case 0:
 					// Font 0: return current font, don't change
 					result = uint16(previousFont)
-				case FontNormal, FontFixedPitch:
+				case FontNormal, FontCharGraphs, FontFixedPitch:
 					// Available fonts
-					z.screenModel.CurrentFont = requestFont
+					*font = requestFont
 					result = uint16(previousFont)
 				default:
-					// FontPicture, FontCharGraphs, and others: unavailable
+					// FontPicture and others: unavailable
 					result = 0
 				}
 
-				z.writeVariable(z.readIncPC(frame), result, false) // nolint:errcheck
-				z.outputChannel <- z.screenModel
+				z.storeResult(frame, result)
+				z.updateScreen()
 
 			case 0x09: // SAVE_UNDO
 				z.saveUndo()
 				// Save always succeeds
-				z.writeVariable(z.readIncPC(frame), uint16(1), false) // nolint:errcheck
+				z.storeResult(frame, uint16(1))
 
 			case 0x0a: // RESTORE_UNDO
 				response := z.restoreUndo()
@@ -1291,7 +1548,7 @@ func (z *ZMachine) StepMachine() bool {
 					return z.reportError("RESTORE_UNDO: %v", err)
 				}
 				// Restore always says that it's done and continues from previous save
-				z.writeVariable(z.readIncPC(frame), response, false) // nolint:errcheck
+				z.storeResult(frame, response)
 
 			case 0x0b: // PRINT_UNICODE
 				chr := opcode.operands[0].Value(z)
@@ -1301,7 +1558,7 @@ func (z *ZMachine) StepMachine() bool {
 				chr := opcode.operands[0].Value(z)
 				// What unicode characters _can_ i write? TODO
 				if chr != 0 {
-					z.writeVariable(z.readIncPC(frame), 0b11, false) // nolint:errcheck
+					z.storeResult(frame, 0b11)
 				}
 
 			case 0x0d: // SET_TRUE_COLOUR
@@ -1309,6 +1566,16 @@ func (z *ZMachine) StepMachine() bool {
 				background := opcode.operands[1].Value(z)
 				var fgColor, bgColor Color
 
+				if z.Core.Version == 6 {
+					w, _, ok := z.optionalWindow(&opcode, 2)
+					if ok {
+						w.Foreground = trueColour(foreground, w.Foreground, z.screenModel.DefaultLowerWindowForeground)
+						w.Background = trueColour(background, w.Background, z.screenModel.DefaultLowerWindowBackground)
+						z.updateScreen()
+					}
+					break
+				}
+
 				if int16(foreground) == -1 {
 					if z.screenModel.LowerWindowActive {
 						fgColor = z.screenModel.DefaultLowerWindowForeground
@@ -1322,7 +1589,7 @@ func (z *ZMachine) StepMachine() bool {
 						fgColor = z.screenModel.UpperWindowForeground
 					}
 				} else {
-					fgColor = Color{int(foreground&0b11111) * 32, int((foreground>>5)&0b11111) * 32, int((foreground>>10)&0b11111) * 32}
+					fgColor = trueColourValue(foreground)
 				}
 
 				if int16(background) == -1 {
@@ -1338,7 +1605,7 @@ func (z *ZMachine) StepMachine() bool {
 						bgColor = z.screenModel.UpperWindowBackground
 					}
 				} else {
-					bgColor = Color{int(background&0b11111) * 32, int((background>>5)&0b11111) * 32, int((background>>10)&0b11111) * 32}
+					bgColor = trueColourValue(background)
 				}
 
 				if z.screenModel.LowerWindowActive {
@@ -1349,7 +1616,186 @@ func (z *ZMachine) StepMachine() bool {
 					z.screenModel.UpperWindowBackground = bgColor
 				}
 
-				z.outputChannel <- z.screenModel
+				z.updateScreen()
+
+			case 0x05: // DRAW_PICTURE
+				if z.Core.Version != 6 {
+					return z.reportError("draw_picture only available on v6")
+				}
+				z.drawPicture(&opcode)
+
+			case 0x06: // PICTURE_DATA
+				if z.Core.Version != 6 {
+					return z.reportError("picture_data only available on v6")
+				}
+				found := z.pictureData(opcode.operands[0].Value(z), uint32(opcode.operands[1].Value(z)))
+				if !z.handleBranch(frame, found) {
+					return false
+				}
+
+			case 0x07: // ERASE_PICTURE
+				if z.Core.Version != 6 {
+					return z.reportError("erase_picture only available on v6")
+				}
+				z.erasePicture(&opcode)
+
+			case 0x08: // SET_MARGINS
+				if z.Core.Version != 6 {
+					return z.reportError("set_margins only available on v6")
+				}
+				left, right := opcode.operands[0].Value(z), opcode.operands[1].Value(z)
+				if w, _, ok := z.optionalWindow(&opcode, 2); ok {
+					w.LeftMargin = int(left)
+					w.RightMargin = int(right)
+					// The cursor is moved inside the new margins
+					w.CursorX = max(w.CursorX, 1+w.LeftMargin)
+					z.updateScreen()
+				}
+
+			case 0x12: // WINDOW_STYLE
+				if z.Core.Version != 6 {
+					return z.reportError("window_style only available on v6")
+				}
+				// A missing operation is 0, setting the attributes
+				operands := opcode.values(z)
+				if w, _, ok := z.window(operands[0]); ok {
+					z.setWindowStyle(w, WindowAttributes(operands[1]), operands[2])
+					z.updateScreen()
+				}
+
+			case 0x10: // MOVE_WINDOW
+				if z.Core.Version != 6 {
+					return z.reportError("move_window only available on v6")
+				}
+				operands := opcode.values(z)
+				if w, _, ok := z.window(operands[0]); ok {
+					z.moveWindow(w, int(operands[1]), int(operands[2]))
+					z.updateScreen()
+				}
+
+			case 0x11: // WINDOW_SIZE
+				if z.Core.Version != 6 {
+					return z.reportError("window_size only available on v6")
+				}
+				operands := opcode.values(z)
+				if w, _, ok := z.window(operands[0]); ok {
+					z.resizeWindow(w, int(operands[1]), int(operands[2]))
+					z.updateScreen()
+				}
+
+			case 0x13: // GET_WIND_PROP
+				if z.Core.Version != 6 {
+					return z.reportError("get_wind_prop only available on v6")
+				}
+				operands := opcode.values(z)
+				value := uint16(0)
+				if w, _, ok := z.window(operands[0]); ok {
+					property := operands[1]
+					if value, ok = z.screenModel.windowProperty(w, property); !ok {
+						z.warnOnce("get_wind_prop", "Warning: @get_wind_prop property %d doesn't exist (PC = %x)", property, opcode.pc)
+					}
+				}
+				z.storeResult(frame, value)
+
+			case 0x14: // SCROLL_WINDOW
+				if z.Core.Version != 6 {
+					return z.reportError("scroll_window only available on v6")
+				}
+				operands := opcode.values(z)
+				if _, n, ok := z.window(operands[0]); ok {
+					z.frontend.ScrollWindow(z.ctx, ScrollWindowRequest{Window: n, Pixels: int(int16(operands[1]))})
+				}
+
+			case 0x15: // POP_STACK
+				if z.Core.Version != 6 {
+					return z.reportError("pop_stack only available on v6")
+				}
+				items := opcode.operands[0].Value(z)
+				if opcode.numOperands > 1 {
+					// Popping from a user stack just gives the slots back
+					stack := uint32(opcode.operands[1].Value(z))
+					z.Core.WriteHalfWord(stack, z.Core.ReadHalfWord(stack)+items)
+				} else {
+					for range items {
+						frame.pop(z)
+					}
+				}
+
+			case 0x16: // READ_MOUSE
+				if z.Core.Version != 6 {
+					return z.reportError("read_mouse only available on v6")
+				}
+				z.readMouse(uint32(opcode.operands[0].Value(z)))
+
+			case 0x17: // MOUSE_WINDOW
+				if z.Core.Version != 6 {
+					return z.reportError("mouse_window only available on v6")
+				}
+				window := opcode.values(z)[0]
+				if int16(window) == -1 {
+					z.screenModel.MouseWindow = -1
+				} else if _, n, ok := z.window(window); ok {
+					z.screenModel.MouseWindow = n
+				}
+				z.updateScreen()
+
+			case 0x18: // PUSH_STACK
+				if z.Core.Version != 6 {
+					return z.reportError("push_stack only available on v6")
+				}
+				pushed := z.pushUserStack(uint32(opcode.operands[1].Value(z)), opcode.operands[0].Value(z))
+				if !z.handleBranch(frame, pushed) {
+					return false
+				}
+
+			case 0x19: // PUT_WIND_PROP
+				if z.Core.Version != 6 {
+					return z.reportError("put_wind_prop only available on v6")
+				}
+				operands := opcode.values(z)
+				if w, _, ok := z.window(operands[0]); ok {
+					property := operands[1]
+					if !z.screenModel.setWindowProperty(w, property, operands[2]) {
+						z.warnOnce("put_wind_prop", "Warning: @put_wind_prop property %d doesn't exist (PC = %x)", property, opcode.pc)
+					}
+					z.updateScreen()
+				}
+
+			case 0x1a: // PRINT_FORM
+				if z.Core.Version != 6 {
+					return z.reportError("print_form only available on v6")
+				}
+				z.printForm(uint32(opcode.operands[0].Value(z)))
+
+			case 0x1b: // MAKE_MENU
+				if z.Core.Version != 6 {
+					return z.reportError("make_menu only available on v6")
+				}
+				// There's no menu bar to add menus to, which the story is told by the branch failing
+				if !z.handleBranch(frame, false) {
+					return false
+				}
+
+			case 0x1c: // PICTURE_TABLE
+				if z.Core.Version != 6 {
+					return z.reportError("picture_table only available on v6")
+				}
+				// Only a hint that the pictures are about to be drawn, there's no loading them in advance
+
+			case 0x1d: // BUFFER_SCREEN
+				if z.Core.Version != 6 {
+					return z.reportError("buffer_screen only available on v6")
+				}
+				previous := uint16(0)
+				if z.screenModel.ScreenBuffered {
+					previous = 1
+				}
+				// -1 asks for the screen to be brought up to date without changing the mode
+				if mode := int16(opcode.operands[0].Value(z)); mode != -1 {
+					z.screenModel.ScreenBuffered = mode == 1
+				}
+				z.updateScreen()
+				z.storeResult(frame, previous)
 
 			default:
 				return z.reportError("EXT opcode not implemented 0x%x at 0x%x", opcode.opcodeByte, opcode.pc)
@@ -1381,7 +1827,9 @@ func (z *ZMachine) StepMachine() bool {
 				}
 
 			case 4: // SREAD
-				z.read(&opcode)
+				if !z.read(&opcode) {
+					return false
+				}
 
 			case 5: // PRINT_CHAR
 				chr := uint8(opcode.operands[0].Value(z))
@@ -1406,17 +1854,19 @@ func (z *ZMachine) StepMachine() bool {
 					result = uint16(z.rng.Int31n(int32(n))) + 1
 				}
 
-				z.writeVariable(z.readIncPC(frame), result, false) // nolint:errcheck
+				z.storeResult(frame, result)
 			case 8: // PUSH
 				frame.push(opcode.operands[0].Value(z))
 
 			case 9: // PULL
 				if z.Core.Version == 6 {
+					var value uint16
 					if opcode.numOperands > 0 {
-						return z.reportError("V6 PULL with user stack not implemented")
+						value = z.pullUserStack(uint32(opcode.operands[0].Value(z)))
+					} else {
+						value = frame.pop(z)
 					}
-					value := frame.pop(z)
-					z.writeVariable(z.readIncPC(frame), value, false) // nolint:errcheck
+					z.storeResult(frame, value)
 				} else {
 					z.writeVariable(uint8(opcode.operands[0].Value(z)), frame.pop(z), true) // nolint:errcheck
 				}
@@ -1427,14 +1877,27 @@ func (z *ZMachine) StepMachine() bool {
 				}
 
 				lines := opcode.operands[0].Value(z)
-				z.screenModel.UpperWindowHeight = int(lines)
+				if z.Core.Version == 6 {
+					z.splitWindowV6(int(lines))
+				} else {
+					z.screenModel.UpperWindowHeight = int(lines)
+				}
 
-				z.outputChannel <- z.screenModel
+				z.updateScreen()
 
 			case 11: // SET_WINDOW
 				if z.Core.Version < 3 {
 					return z.reportError("SET_WINDOW not available on v1-2")
 				}
+				if z.Core.Version == 6 {
+					// The cursor stays wherever it was left in the window
+					if _, n, ok := z.window(opcode.operands[0].Value(z)); ok {
+						z.screenModel.CurrentWindow = n
+						z.updateScreen()
+					}
+					break
+				}
+
 				window := opcode.operands[0].Value(z)
 				z.screenModel.LowerWindowActive = window == 0
 				// 8.7.2: Whenever the upper window is selected, its cursor position is reset to the top left
@@ -1442,12 +1905,19 @@ func (z *ZMachine) StepMachine() bool {
 					z.screenModel.UpperWindowCursorX = 0
 					z.screenModel.UpperWindowCursorY = 0
 				}
-				z.outputChannel <- z.screenModel
+				z.updateScreen()
 
 			case 12: // CALL_VS2
 				z.call(&opcode, function)
 
 			case 13: // ERASE_WINDOW
+				if z.Core.Version == 6 {
+					request := z.eraseWindowV6(opcode.operands[0].Value(z))
+					z.updateScreen()
+					z.frontend.EraseWindow(z.ctx, request)
+					break
+				}
+
 				window := int16(opcode.operands[0].Value(z))
 
 				switch window {
@@ -1473,8 +1943,8 @@ func (z *ZMachine) StepMachine() bool {
 					z.screenModel.UpperWindowCursorY = 0
 				}
 
-				z.outputChannel <- z.screenModel
-				z.outputChannel <- EraseWindowRequest(window)
+				z.updateScreen()
+				z.frontend.EraseWindow(z.ctx, EraseWindowRequest(window))
 
 			case 14: // ERASE_LINE
 				if z.Core.Version < 4 {
@@ -1485,7 +1955,7 @@ func (z *ZMachine) StepMachine() bool {
 				switch value {
 				case 1:
 					// Erase from cursor to end of line
-					z.outputChannel <- EraseLineRequest(1)
+					z.frontend.EraseLine(z.ctx, EraseLineRequest(1))
 				default:
 					// "If the value is anything other than 1, do nothing." - Spec
 				}
@@ -1495,7 +1965,11 @@ func (z *ZMachine) StepMachine() bool {
 				col := opcode.operands[1].Value(z)
 
 				if z.Core.Version == 6 {
-					return z.reportError("V6 cursor operations not implemented")
+					if w, _, ok := z.optionalWindow(&opcode, 2); ok {
+						z.setCursorV6(int16(line), int16(col), w)
+						z.updateScreen()
+					}
+					break
 				}
 
 				// TODO - Pretty sure you can't set the cursor on lower window v<=5
@@ -1503,15 +1977,18 @@ func (z *ZMachine) StepMachine() bool {
 				if !z.screenModel.LowerWindowActive {
 					z.screenModel.UpperWindowCursorX = int(col) - 1
 					z.screenModel.UpperWindowCursorY = int(line) - 1
-					z.outputChannel <- z.screenModel
+					z.updateScreen()
 				}
 
 			case 16: // GET_CURSOR
+				array := uint32(opcode.operands[0].Value(z))
 				if z.Core.Version == 6 {
-					return z.reportError("V6 cursor operations not implemented")
+					w := &z.screenModel.Windows[z.screenModel.CurrentWindow]
+					z.Core.WriteHalfWord(array, uint16(w.CursorY))
+					z.Core.WriteHalfWord(array+2, uint16(w.CursorX))
+					break
 				}
 
-				array := uint32(opcode.operands[0].Value(z))
 				// Store cursor position as 1-based coordinates (Z-machine convention)
 				// Word 0 = row, Word 1 = column
 				z.Core.WriteHalfWord(array, uint16(z.screenModel.UpperWindowCursorY+1))
@@ -1521,13 +1998,15 @@ func (z *ZMachine) StepMachine() bool {
 				if z.Core.Version >= 4 {
 					mask := uint8(opcode.operands[0].Value(z))
 
-					if z.screenModel.LowerWindowActive {
+					if z.Core.Version == 6 {
+						z.screenModel.Windows[z.screenModel.CurrentWindow].TextStyle = TextStyle(mask)
+					} else if z.screenModel.LowerWindowActive {
 						z.screenModel.LowerWindowTextStyle = TextStyle(mask)
 					} else {
 						z.screenModel.UpperWindowTextStyle = TextStyle(mask)
 					}
 
-					z.outputChannel <- z.screenModel
+					z.updateScreen()
 				} else {
 					return z.reportError("SET_TEXT_STYLE not available on v1-3")
 				}
@@ -1542,7 +2021,7 @@ func (z *ZMachine) StepMachine() bool {
 				case 1, -1:
 					z.streams.Screen = stream > 0
 				case 2, -2:
-					z.streams.Transcript = stream > 0
+					z.setTranscript(stream > 0)
 				case 3:
 					// TODO - Handle width of v6+ formatted memory stream data
 					z.streams.Memory = true
@@ -1565,56 +2044,61 @@ func (z *ZMachine) StepMachine() bool {
 						}
 					}
 				case 4, -4:
-					z.streams.CommandScript = stream > 0
+					z.setCommandRecording(stream > 0)
 				}
 
+			case 20: // INPUT_STREAM
+				z.setInputStream(int16(opcode.operands[0].Value(z)))
+
 			case 21: // SOUND_EFFECT
 				if z.Core.Version < 3 {
 					return z.reportError("SOUND_EFFECT not available on v1-2")
 				}
 
-				soundNumber := opcode.operands[0].Value(z) // Will default to 0 if omitted by compiler
+				operands := opcode.values(z)
+				soundNumber := operands[0] // Will default to 0 if omitted by compiler
 				if soundNumber == 0 {
 					soundNumber = 1 // Per spec, sound effect 0 is treated as sound effect 1
 				}
 
-				effect := uint16(0)
-				volume := byte(0)
-				repeats := byte(0)
-				routine := uint16(0)
-
+				effect := SoundStart // Only the sound number is given for bleeps, Frotz plays anything else
 				if opcode.numOperands > 1 {
-					effect = opcode.operands[1].Value(z) // "The effect can be: 1 (prepare), 2 (start), 3 (stop), 4 (finish with)."
-				}
-				if opcode.numOperands > 2 {
-					volume = byte(opcode.operands[2].Value(z))
-					repeats = byte(opcode.operands[2].Value(z) >> 8)
-				}
-				if opcode.numOperands > 3 {
-					routine = opcode.operands[3].Value(z)
+					effect = operands[1] // "The effect can be: 1 (prepare), 2 (start), 3 (stop), 4 (finish with)."
 				}
 
-				z.outputChannel <- SoundEffectRequest{
+				// Volume is the low byte and repeats the high byte of one operand
+				z.soundEffect(SoundEffectRequest{
 					SoundNumber: soundNumber,
 					Effect:      effect,
-					Volume:      volume,
-					Repeats:     repeats,
-					Routine:     routine,
-				}
+					Volume:      byte(operands[2]),
+					Repeats:     byte(operands[2] >> 8),
+					Routine:     operands[3],
+				})
 
 			case 22: // READ_CHAR
-				z.outputChannel <- WaitForCharacter
-				inputResponse := <-z.inputChannel
+				// The first operand is always 1, it's still read in case it's on the stack
+				operands := opcode.values(z)
+				var tenths, routine uint16
+				if opcode.numOperands >= 3 {
+					tenths = operands[1]
+					routine = operands[2]
+				}
+
+				z.resetLineCounts()
+				charCode, ok, err := z.readCharTimed(CharacterRequest{Timeout: timeout(tenths, routine)}, routine)
+				if err != nil {
+					return z.stop(err)
+				}
+				if !ok {
+					return false
+				}
 
-				// Handle empty input (treat as newline)
-				charCode := uint16(13) // Default to carriage return
-				if len(inputResponse.Text) > 0 {
-					charCode = uint16(inputResponse.Text[0])
-				} else if inputResponse.TerminatingKey != 0 {
-					// Use terminating key if text is empty (e.g., function key was pressed)
-					charCode = uint16(inputResponse.TerminatingKey)
+				// An interrupt routine may have grown the call stack, moving the frame
+				frame, err = z.callStack.peek()
+				if err != nil {
+					return z.reportError("READ_CHAR: %v", err)
 				}
-				z.writeVariable(z.readIncPC(frame), charCode, false) // nolint:errcheck
+				z.storeResult(frame, charCode)
 
 			case 23: // SCAN_TABLE
 				test := opcode.operands[0].Value(z)
@@ -1628,7 +2112,7 @@ func (z *ZMachine) StepMachine() bool {
 
 				result := ztable.ScanTable(&z.Core, test, uint32(tableAddress), length, form)
 
-				z.writeVariable(z.readIncPC(frame), uint16(result), false) // nolint:errcheck
+				z.storeResult(frame, uint16(result))
 
 				if !z.handleBranch(frame, result != 0) {
 					return false
@@ -1636,7 +2120,7 @@ func (z *ZMachine) StepMachine() bool {
 
 			case 24: // NOT
 				val := opcode.operands[0].Value(z)
-				z.writeVariable(z.readIncPC(frame), ^val, false) // nolint:errcheck
+				z.storeResult(frame, ^val)
 
 			case 25: // CALL_VN
 				z.call(&opcode, procedure)
@@ -1653,13 +2137,13 @@ func (z *ZMachine) StepMachine() bool {
 				if opcode.numOperands > 2 {
 					dictionaryAddress := opcode.operands[2].Value(z)
 
-					// TODO - Handle special case custom dictionaries with negative number of entries (unsorted)
-					dictionaryToUse = dictionary.ParseDictionary(uint32(dictionaryAddress), &z.Core, z.Alphabets)
+					// An address of 0 means the standard dictionary
+					if dictionaryAddress != 0 {
+						dictionaryToUse = z.userDictionaries.Get(uint32(dictionaryAddress), &z.Core, z.Alphabets)
+					}
 
 					if opcode.numOperands == 4 {
-						flag = opcode.operands[3].Value(z) != 0 // nolint:ineffassign,staticcheck
-
-						return z.reportError("TOKENISE with 4th operand not implemented")
+						flag = opcode.operands[3].Value(z) != 0
 					}
 				}
 
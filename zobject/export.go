@@ -0,0 +1,132 @@
+package zobject
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/davetcode/goz/zcore"
+	"github.com/davetcode/goz/zstring"
+)
+
+// ExportedProperty is a property's raw data, hex encoded
+type ExportedProperty struct {
+	Id   uint8  `json:"id"`
+	Data string `json:"data"`
+}
+
+// ExportedObject is a snapshot of an object for exporting the object tree
+type ExportedObject struct {
+	Id         uint16             `json:"id"`
+	Name       string             `json:"name"`
+	Parent     uint16             `json:"parent"`
+	Sibling    uint16             `json:"sibling"`
+	Child      uint16             `json:"child"`
+	Attributes []uint16           `json:"attributes"` // Numbers of the attributes which are set
+	Properties []ExportedProperty `json:"properties"`
+}
+
+// Count returns how many objects the story has. The object table has no
+// length of its own so objects are counted up to the first property table,
+// which always comes after the last object.
+func Count(core *zcore.Core) int {
+	maxObjects, entrySize, propertyOffset, firstObject := uint32(255), uint32(9), uint32(7), uint32(core.ObjectTableBase)+31*2
+	if core.Version >= 4 {
+		maxObjects, entrySize, propertyOffset, firstObject = 65535, 14, 12, uint32(core.ObjectTableBase)+63*2
+	}
+
+	lowestProperties := core.MemoryLength()
+	count := 0
+	for id := uint32(1); id <= maxObjects; id++ {
+		objectBase := firstObject + (id-1)*entrySize
+		if objectBase+entrySize > lowestProperties {
+			break
+		}
+		lowestProperties = min(lowestProperties, uint32(core.ReadHalfWord(objectBase+propertyOffset)))
+		count++
+	}
+	return count
+}
+
+// Export snapshots every object in the object table. It works on any core,
+// whether freshly loaded, from a running machine or with a save restored.
+func Export(core *zcore.Core, alphabets *zstring.Alphabets) []ExportedObject {
+	attributeCount := uint16(32)
+	if core.Version >= 4 {
+		attributeCount = 48
+	}
+
+	count := Count(core)
+	objects := make([]ExportedObject, 0, count)
+	for id := uint16(1); int(id) <= count; id++ {
+		obj := GetObject(id, core, alphabets)
+		exported := ExportedObject{
+			Id:         obj.Id,
+			Name:       obj.Name,
+			Parent:     obj.Parent,
+			Sibling:    obj.Sibling,
+			Child:      obj.Child,
+			Attributes: []uint16{},
+			Properties: []ExportedProperty{},
+		}
+
+		for attribute := range attributeCount {
+			if obj.TestAttribute(attribute) {
+				exported.Attributes = append(exported.Attributes, attribute)
+			}
+		}
+
+		propertyId, err := obj.GetNextProperty(0, core)
+		for err == nil && propertyId != 0 {
+			property := obj.GetProperty(propertyId, core)
+			exported.Properties = append(exported.Properties, ExportedProperty{
+				Id:   property.Id,
+				Data: hex.EncodeToString(property.Data),
+			})
+			propertyId, err = obj.GetNextProperty(propertyId, core)
+		}
+
+		objects = append(objects, exported)
+	}
+	return objects
+}
+
+// WriteJSON writes the objects as an indented JSON array
+func WriteJSON(w io.Writer, objects []ExportedObject) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(objects)
+}
+
+// WriteDOT writes the object tree as a Graphviz digraph with an edge from
+// each parent to each of its children
+func WriteDOT(w io.Writer, objects []ExportedObject) error {
+	var s strings.Builder
+	s.WriteString("digraph objects {\n")
+	s.WriteString("  node [shape=box];\n")
+	for _, obj := range objects {
+		attributes := make([]string, len(obj.Attributes))
+		for ix, attribute := range obj.Attributes {
+			attributes[ix] = fmt.Sprint(attribute)
+		}
+		fmt.Fprintf(&s, "  %d [label=%s, tooltip=%s];\n",
+			obj.Id,
+			dotString(fmt.Sprintf("%d: %s", obj.Id, obj.Name)),
+			dotString("attributes: "+strings.Join(attributes, " ")))
+	}
+	for _, obj := range objects {
+		if obj.Parent != 0 {
+			fmt.Fprintf(&s, "  %d -> %d;\n", obj.Parent, obj.Id)
+		}
+	}
+	s.WriteString("}\n")
+
+	_, err := io.WriteString(w, s.String())
+	return err
+}
+
+func dotString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -2,6 +2,7 @@ package zobject_test
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/davetcode/goz/zmachine"
@@ -416,3 +417,32 @@ func TestGetNextPropertyV1(t *testing.T) {
 		t.Fatalf("Object with no property should always return 0 even for first prop")
 	}
 }
+
+func TestExportZork1ObjectTree(t *testing.T) {
+	z := loadZork1()
+
+	objects := zobject.Export(&z.Core, z.Alphabets)
+	if len(objects) != zobject.Count(&z.Core) {
+		t.Fatalf("Expected every object to be exported, got %d", len(objects))
+	}
+
+	westOfHouse := objects[0x23-1]
+	if westOfHouse.Name != "West of House" || westOfHouse.Parent != 117 || westOfHouse.Child != 252 {
+		t.Errorf("Incorrect export of West of House %+v", westOfHouse)
+	}
+
+	dampCave := objects[0]
+	if len(dampCave.Properties) == 0 || dampCave.Properties[0].Id != 30 || dampCave.Properties[0].Data != "67" {
+		t.Errorf("Incorrect properties for damp cave %+v", dampCave.Properties)
+	}
+
+	var dot strings.Builder
+	if err := zobject.WriteDOT(&dot, objects); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`35 [label="35: West of House"`, "117 -> 35;"} {
+		if !strings.Contains(dot.String(), expected) {
+			t.Errorf("Missing %q from DOT output", expected)
+		}
+	}
+}
@@ -123,10 +123,8 @@ func (o *Object) GetPropertyByAddress(propertyAddr uint32, core *zcore.Core) Pro
 
 func (o *Object) GetNextProperty(propertyId uint8, core *zcore.Core) (uint8, error) {
 	if propertyId == 0 { // Special case, means get first property
-		if core.ReadZByte(uint32(o.PropertyPointer)) == 0 {
-			return 0, nil // Special case, no next property means return 0
-		}
-
+		// An object with no properties has the terminating 0 straight after its
+		// name, which reads as property 0 meaning there's no next property
 		objectNameLength := core.ReadZByte(uint32(o.PropertyPointer))
 		currentPtr := uint32(o.PropertyPointer + 1 + uint16(objectNameLength)*2)
 		return o.GetPropertyByAddress(currentPtr, core).Id, nil
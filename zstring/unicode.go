@@ -74,6 +74,16 @@ var DefaultUnicodeTranslationTable = map[rune]uint8{
 	'¿': 223,
 }
 
+// UnicodeToZscii gives the ZSCII code for a character the player typed,
+// ASCII is unchanged and anything else goes through the unicode translation
+// table
+func UnicodeToZscii(r rune, core *zcore.Core) (uint8, bool) {
+	if r < 127 {
+		return uint8(r), true
+	}
+	return unicodeToZscii(r, core)
+}
+
 func unicodeToZscii(r rune, core *zcore.Core) (uint8, bool) {
 	unicodeTranslationTable := DefaultUnicodeTranslationTable
 	if core.UnicodeExtensionTableBaseAddress != 0 {
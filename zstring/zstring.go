@@ -32,10 +32,42 @@ func LoadAlphabets(core *zcore.Core) *Alphabets {
 	} else if core.AlternativeCharSetBaseAddress == 0 {
 		return &defaultAlphabetsV2
 	} else {
-		panic("TODO - Load custom alphabet")
+		return loadCustomAlphabets(core)
 	}
 }
 
+// loadCustomAlphabets reads the 78 ZSCII characters of a V5+ alphabet table,
+// 26 per alphabet. The first two characters of A2 are always escape and
+// newline whatever the table says.
+func loadCustomAlphabets(core *zcore.Core) *Alphabets {
+	base := uint32(core.AlternativeCharSetBaseAddress)
+	row := func(alphabet uint32, skip uint32) []rune {
+		var runes []rune
+		for ix := skip; ix < 26; ix++ {
+			zchr := core.ReadZByte(base + 26*alphabet + ix)
+			r, ok := ZsciiToUnicode(zchr, core)
+			if !ok {
+				r = '?'
+			}
+			runes = append(runes, r)
+		}
+		return runes
+	}
+
+	alphabets := Alphabets{a0: row(0, 0), a1: row(1, 0), a2: append([]rune{'\n'}, row(2, 2)...)}
+	return &alphabets
+}
+
+// Rows returns the characters of A0, A1 and A2 in Z-character order starting
+// from 6, with a space standing in for A2's escape character
+func (a *Alphabets) Rows() [3]string {
+	a2 := string(a.a2)
+	if len(a.a2) < 26 {
+		a2 = " " + a2
+	}
+	return [3]string{string(a.a0), string(a.a1), a2}
+}
+
 var coreUnicodeTranslationTable = map[rune]uint8{
 	'!':  0x21,
 	'"':  0x22,
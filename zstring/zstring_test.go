@@ -82,3 +82,23 @@ func TestV3Abbreviations(t *testing.T) {
 func TestV5PartialConstruction(t *testing.T) {
 	// TODO - Test case where a string has a partial construction which should get ignored
 }
+
+func TestCustomAlphabetTable(t *testing.T) {
+	memory := make([]uint8, 256)
+	memory[0x00] = 5
+	memory[0x35] = 0x80 // Alphabet table at 0x80
+	table := "zyxwvutsrqponmlkjihgfedcba" + "ZYXWVUTSRQPONMLKJIHGFEDCBA" + "  9876543210.,!?_#'\"/\\-:()"
+	copy(memory[0x80:], table)
+	core := zcore.LoadCore(memory)
+	alphabets := LoadAlphabets(&core)
+
+	rows := alphabets.Rows()
+	if rows[0] != table[:26] || rows[1] != table[26:52] || rows[2] != " \n"+table[54:] {
+		t.Errorf("unexpected alphabets %q", rows)
+	}
+
+	// "a" is the last character of the custom A0 so encodes as Z-character 31
+	if encoded := Encode([]rune("a"), &core, alphabets); encoded[0]>>2 != 31 {
+		t.Errorf("expected a to use the custom alphabet, got %v", encoded)
+	}
+}